@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"cms-backend/models"
+	"cms-backend/utils"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type apiKeyInput struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// GetAPIKeys lists the current user's API keys.
+func GetAPIKeys(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	user, ok := keyManager(c)
+	if !ok {
+		return
+	}
+
+	var keys []models.APIKey
+	if err := db.Where("user_id = ?", user.ID).Order("id").Find(&keys).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+// CreateAPIKey issues an API key acting as the current user. The key is
+// only ever returned by this call; afterwards just its prefix is known.
+func CreateAPIKey(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	user, ok := keyManager(c)
+	if !ok {
+		return
+	}
+
+	var input apiKeyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: "expires_at must be in the future",
+		})
+		return
+	}
+
+	seen := map[string]bool{}
+	scopes := models.Scopes{}
+	for _, scope := range input.Scopes {
+		if _, ok := models.APIKeyScopes[scope]; !ok {
+			c.JSON(http.StatusBadRequest, utils.HTTPError{
+				Code:    http.StatusBadRequest,
+				Message: "Invalid scope: " + scope,
+			})
+			return
+		}
+		if !user.CanGrantScope(scope) {
+			forbid(c, "Your role does not allow the "+scope+" scope")
+			return
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	sort.Strings(scopes)
+
+	key, prefix := utils.NewAPIKey()
+	apiKey := models.APIKey{
+		UserID:    user.ID,
+		Name:      input.Name,
+		Prefix:    prefix,
+		KeyHash:   utils.HashToken(key),
+		Scopes:    scopes,
+		ExpiresAt: input.ExpiresAt,
+	}
+
+	tx := db.Begin()
+	if err := tx.Create(&apiKey).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	tx.Commit()
+	c.JSON(http.StatusCreated, models.APIKeyWithSecret{APIKey: apiKey, Key: key})
+}
+
+// DeleteAPIKey revokes one of the current user's API keys.
+func DeleteAPIKey(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	user, ok := keyManager(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid API key ID",
+		})
+		return
+	}
+
+	tx := db.Begin()
+	result := tx.Where("id = ? AND user_id = ?", uint(id), user.ID).Delete(&models.APIKey{})
+	if result.Error != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: result.Error.Error(),
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, utils.HTTPError{
+			Code:    http.StatusNotFound,
+			Message: "API key not found",
+		})
+		return
+	}
+	tx.Commit()
+	c.JSON(http.StatusOK, utils.MessageResponse{
+		Message: "API key deleted successfully",
+	})
+}
+
+// keyManager returns the user managing API keys. Keys are managed with a
+// login, never with another key, so a leaked key cannot mint more.
+func keyManager(c *gin.Context) (models.User, bool) {
+	user, ok := currentUser(c)
+	if !ok {
+		c.Header("WWW-Authenticate", `Bearer realm="api"`)
+		c.JSON(http.StatusUnauthorized, utils.HTTPError{
+			Code:    http.StatusUnauthorized,
+			Message: "Authentication required",
+		})
+		return user, false
+	}
+	if user.Scopes != nil {
+		forbid(c, "API keys cannot manage API keys")
+		return user, false
+	}
+	return user, true
+}
@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"cms-backend/models"
+	"cms-backend/utils"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestCreateAPIKey(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "api_keys" \("site_id","user_id","name","prefix","key_hash","scopes","expires_at","last_used_at","created_at"\)`).
+		WithArgs(1, 1, "Site builder", sqlmock.AnyArg(), sqlmock.AnyArg(), "media:write posts:read", nil, nil, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	router.POST("/api-keys", CreateAPIKey)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/api-keys", strings.NewReader(`{"name":"Site builder","scopes":["posts:read","media:write","posts:read"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response models.APIKeyWithSecret
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+	if !strings.HasPrefix(response.Key, response.Prefix+"_") || !strings.HasPrefix(response.Prefix, models.APIKeyPrefix) {
+		t.Fatalf("Expected a key starting with its prefix, but got %q and %q", response.Key, response.Prefix)
+	}
+	if strings.Contains(w.Body.String(), utils.HashToken(response.Key)) {
+		t.Fatalf("Expected the key hash to stay out of the response")
+	}
+}
+
+func TestCreateAPIKeyRejectsScopes(t *testing.T) {
+	tests := []struct {
+		user *models.User
+		body string
+		code int
+	}{
+		{&models.User{ID: 1, Role: models.UserRoleAdmin}, `{"name":"x","scopes":["users:manage"]}`, http.StatusBadRequest},
+		{&models.User{ID: 1, Role: models.UserRoleAdmin}, `{"name":"x","scopes":[]}`, http.StatusBadRequest},
+		{&models.User{ID: 1, Role: models.UserRoleAuthor}, `{"name":"x","scopes":["pages:write"]}`, http.StatusForbidden},
+		{&models.User{ID: 1, Role: models.UserRoleAdmin, Scopes: []string{"posts:write"}}, `{"name":"x","scopes":["posts:read"]}`, http.StatusForbidden},
+		{nil, `{"name":"x","scopes":["posts:read"]}`, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		router, _, mock := utils.SetupRouterAndMockDBAs(t, tt.user)
+		router.POST("/api-keys", CreateAPIKey)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/api-keys", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.code {
+			t.Fatalf("Expected status %d for %s, but got %d", tt.code, tt.body, w.Code)
+		}
+		mock.ExpectClose()
+	}
+}
+
+func TestDeleteAPIKeyOfOtherUser(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM "api_keys" WHERE id = \$1 AND user_id = \$2`).
+		WithArgs(5, 1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	router.DELETE("/api-keys/:id", DeleteAPIKey)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodDelete, "/api-keys/5", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("Expected status 404, but got %d", w.Code)
+	}
+}
@@ -0,0 +1,202 @@
+package controllers
+
+import (
+	"cms-backend/models"
+	"cms-backend/utils"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var auditCSVHeader = []string{
+	"id", "created_at", "actor_id", "actor_email", "action", "resource_type", "resource_id",
+	"ip", "user_agent", "request_id", "before", "after",
+}
+
+// GetAuditLogs lists audit entries, newest first. It filters by ?actor=
+// (user ID or email), ?resource_type=, ?resource_id=, ?action= and the
+// ?from= / ?to= time range, and exports every match with ?format=csv.
+func GetAuditLogs(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	query, err := auditFilters(db.Model(&models.AuditLog{}), c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if c.Query("format") == "csv" {
+		exportAuditLogs(c, db, query)
+		return
+	}
+
+	page, perPage, err := paginationParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	response := models.AuditLogResponse{Entries: []models.AuditLog{}, Page: page, PerPage: perPage}
+	if err := query.Session(&gorm.Session{}).Count(&response.Total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err := query.Order("id DESC").Limit(perPage).Offset((page - 1) * perPage).Find(&response.Entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func auditFilters(query *gorm.DB, c *gin.Context) (*gorm.DB, error) {
+	if actor := c.Query("actor"); actor != "" {
+		if id, err := strconv.ParseUint(actor, 10, 32); err == nil {
+			query = query.Where("actor_id = ?", uint(id))
+		} else {
+			query = query.Where("actor_email = ?", strings.ToLower(actor))
+		}
+	}
+	if resourceType := c.Query("resource_type"); resourceType != "" {
+		query = query.Where("resource_type = ?", resourceType)
+	}
+	if value := c.Query("resource_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("resource_id must be a positive integer")
+		}
+		query = query.Where("resource_id = ?", uint(id))
+	}
+	if action := c.Query("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+	for _, bound := range []struct{ param, condition string }{
+		{"from", "created_at >= ?"},
+		{"to", "created_at < ?"},
+	} {
+		value := c.Query(bound.param)
+		if value == "" {
+			continue
+		}
+		t, err := parseAuditTime(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be an RFC 3339 time or a YYYY-MM-DD date", bound.param)
+		}
+		query = query.Where(bound.condition, t)
+	}
+	return query, nil
+}
+
+func parseAuditTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// exportAuditLogs streams every matching entry as CSV, oldest first.
+func exportAuditLogs(c *gin.Context, db *gorm.DB, query *gorm.DB) {
+	rows, err := query.Order("id").Rows()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	defer rows.Close()
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="audit.csv"`)
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	w.Write(auditCSVHeader)
+	for rows.Next() {
+		var entry models.AuditLog
+		if err := db.ScanRows(rows, &entry); err != nil {
+			c.Error(err)
+			break
+		}
+		actorID := ""
+		if entry.ActorID != nil {
+			actorID = strconv.FormatUint(uint64(*entry.ActorID), 10)
+		}
+		w.Write([]string{
+			strconv.FormatUint(uint64(entry.ID), 10),
+			entry.CreatedAt.UTC().Format(time.RFC3339),
+			actorID,
+			csvSafe(entry.ActorEmail),
+			entry.Action,
+			entry.ResourceType,
+			strconv.FormatUint(uint64(entry.ResourceID), 10),
+			entry.IP,
+			csvSafe(entry.UserAgent),
+			csvSafe(entry.RequestID),
+			string(entry.Before),
+			string(entry.After),
+		})
+	}
+	w.Flush()
+}
+
+// csvSafe keeps spreadsheets from evaluating client-supplied values as
+// formulas.
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// recordAudit appends an audit entry for a mutation to its transaction.
+// before is nil for creates and after is nil for deletes.
+func recordAudit(c *gin.Context, tx *gorm.DB, action, resourceType string, resourceID uint, before, after interface{}) error {
+	entry := models.AuditLog{
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		IP:           c.ClientIP(),
+		UserAgent:    c.Request.UserAgent(),
+		RequestID:    c.GetString("request_id"),
+	}
+	if user, ok := currentUser(c); ok {
+		entry.ActorID = &user.ID
+		entry.ActorEmail = user.Email
+	}
+
+	var err error
+	if entry.Before, err = auditSnapshot(before); err != nil {
+		return err
+	}
+	if entry.After, err = auditSnapshot(after); err != nil {
+		return err
+	}
+	return tx.Create(&entry).Error
+}
+
+func auditSnapshot(value interface{}) (models.JSON, error) {
+	if value == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(value)
+	return models.JSON(data), err
+}
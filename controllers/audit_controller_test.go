@@ -0,0 +1,173 @@
+package controllers
+
+import (
+	"cms-backend/middleware"
+	"cms-backend/models"
+	"cms-backend/utils"
+	"database/sql/driver"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/gorm"
+)
+
+// expectAudit expects the audit entry a mutation writes before committing.
+func expectAudit(mock sqlmock.Sqlmock, action, resourceType string, resourceID uint) {
+	mock.ExpectQuery(`INSERT INTO "audit_logs" \("site_id","actor_id","actor_email","action","resource_type","resource_id","before","after","ip","user_agent","request_id","created_at"\)`).
+		WithArgs(1, sqlmock.AnyArg(), sqlmock.AnyArg(), action, resourceType, resourceID,
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+}
+
+// jsonArg matches a JSON argument containing the given fragment.
+type jsonArg string
+
+func (a jsonArg) Match(v driver.Value) bool {
+	s, ok := v.(string)
+	return ok && strings.Contains(s, string(a))
+}
+
+func TestDeletePageRecordsAudit(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+	router.Use(middleware.RequestID())
+
+	mock.ExpectQuery(`SELECT \* FROM "pages" WHERE "pages"\."id" = \$1`).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "content"}).AddRow(1, "Gone", "Content"))
+	expectNoLock(mock)
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM "pages"`).
+		WithArgs(sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(`INSERT INTO "audit_logs"`).
+		WithArgs(1, 1, "admin@example.com", models.AuditActionDelete, models.ResourcePage, 1,
+			jsonArg(`"title":"Gone"`), nil, "203.0.113.5", "site-builder/1.0", "req-42", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	expectTranslationsDeleted(mock, models.ResourcePage, 1)
+	expectOutbox(mock, 1, models.EventPageDeleted)
+	mock.ExpectCommit()
+
+	router.DELETE("/pages/:id", DeletePage)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodDelete, "/pages/1", nil)
+	req.RemoteAddr = "203.0.113.5:4000"
+	req.Header.Set("User-Agent", "site-builder/1.0")
+	req.Header.Set("X-Request-ID", "req-42")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("X-Request-ID") != "req-42" {
+		t.Fatalf("Expected the request ID to be echoed, but got %q", w.Header().Get("X-Request-ID"))
+	}
+}
+
+func TestAuditFailureRollsBackMutation(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "media"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(`INSERT INTO "audit_logs"`).
+		WillReturnError(gorm.ErrInvalidDB)
+	mock.ExpectRollback()
+
+	router.POST("/media", CreateMedia)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/media", strings.NewReader(`{"url":"https://example.com/a.jpg","type":"image"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected status 500, but got %d", w.Code)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetAuditLogs(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "audit_logs" WHERE actor_email = \$1 AND resource_type = \$2 AND resource_id = \$3 AND created_at >= \$4`).
+		WithArgs("ann@example.com", models.ResourcePost, 7, from).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery(`SELECT \* FROM "audit_logs" WHERE actor_email = \$1 AND resource_type = \$2 AND resource_id = \$3 AND created_at >= \$4 ORDER BY id DESC LIMIT \$5`).
+		WithArgs("ann@example.com", models.ResourcePost, 7, from, 20).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "actor_email", "action", "resource_type", "resource_id", "after"}).
+			AddRow(3, "ann@example.com", models.AuditActionCreate, models.ResourcePost, 7, `{"id":7}`))
+
+	router.GET("/audit", GetAuditLogs)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/audit?actor=Ann@example.com&resource_type=post&resource_id=7&from=2026-01-01", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response models.AuditLogResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+	if response.Total != 1 || len(response.Entries) != 1 || string(response.Entries[0].After) != `{"id":7}` {
+		t.Fatalf("Expected one entry, but got %+v", response)
+	}
+}
+
+func TestGetAuditLogsInvalidFilter(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	router.GET("/audit", GetAuditLogs)
+	for _, query := range []string{"from=yesterday", "resource_id=abc", "per_page=1000"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/audit?"+query, nil)
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("Expected status 400 for %s, but got %d", query, w.Code)
+		}
+	}
+}
+
+func TestGetAuditLogsCSV(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT \* FROM "audit_logs" WHERE actor_id = \$1 ORDER BY id`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "actor_id", "actor_email", "action", "resource_type", "resource_id", "user_agent", "before"}).
+			AddRow(1, time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC), 2, "ann@example.com", models.AuditActionDelete, models.ResourceMedia, 5, "=HYPERLINK()", `{"id":5}`))
+
+	router.GET("/audit", GetAuditLogs)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/audit?actor=2&format=csv", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/csv") {
+		t.Fatalf("Expected a CSV export, but got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("Error parsing CSV: %v", err)
+	}
+	if len(records) != 2 || records[0][0] != "id" {
+		t.Fatalf("Expected a header and one row, but got %v", records)
+	}
+	row := records[1]
+	if row[1] != "2026-03-01T12:00:00Z" || row[2] != "2" || row[4] != "delete" || row[8] != "'=HYPERLINK()" || row[10] != `{"id":5}` {
+		t.Fatalf("Unexpected CSV row %v", row)
+	}
+}
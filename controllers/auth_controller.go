@@ -0,0 +1,271 @@
+package controllers
+
+import (
+	"cms-backend/models"
+	"cms-backend/utils"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type loginInput struct {
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type refreshInput struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type logoutInput struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// dummyPasswordHash is checked when the email is unknown so a failed login
+// takes as long whether or not the account exists.
+var dummyPasswordHash, _ = utils.HashPassword("not the password")
+
+func Login(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var input loginInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	var user models.User
+	err := db.Where("email = ?", strings.ToLower(strings.TrimSpace(input.Email))).First(&user).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err == gorm.ErrRecordNotFound {
+		utils.CheckPassword(dummyPasswordHash, input.Password)
+	}
+	if err == gorm.ErrRecordNotFound || !utils.CheckPassword(user.PasswordHash, input.Password) {
+		c.JSON(http.StatusUnauthorized, utils.HTTPError{
+			Code:    http.StatusUnauthorized,
+			Message: "Invalid email or password",
+		})
+		return
+	}
+
+	tx := db.Begin()
+	tokens, err := issueTokens(tx, user.ID, utils.RandomToken())
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	tx.Commit()
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Refresh exchanges a refresh token for a new access and refresh token
+// pair. Refresh tokens are single use: presenting one that was already
+// rotated revokes every token in its family.
+func Refresh(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var input refreshInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	var token models.RefreshToken
+	if err := db.Where("token_hash = ?", utils.HashToken(input.RefreshToken)).First(&token).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusUnauthorized, utils.HTTPError{
+				Code:    http.StatusUnauthorized,
+				Message: "Invalid refresh token",
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, utils.HTTPError{
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
+			})
+		}
+		return
+	}
+
+	if token.RevokedAt != nil {
+		if err := revokeRefreshTokens(db.Where("family_id = ?", token.FamilyID)); err != nil {
+			c.JSON(http.StatusInternalServerError, utils.HTTPError{
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusUnauthorized, utils.HTTPError{
+			Code:    http.StatusUnauthorized,
+			Message: "Refresh token has been revoked",
+		})
+		return
+	}
+	if time.Now().After(token.ExpiresAt) {
+		c.JSON(http.StatusUnauthorized, utils.HTTPError{
+			Code:    http.StatusUnauthorized,
+			Message: "Refresh token has expired",
+		})
+		return
+	}
+
+	tx := db.Begin()
+	// The revoked_at guard makes concurrent refreshes with the same token
+	// race for a single winner.
+	result := tx.Model(&models.RefreshToken{}).Where("id = ? AND revoked_at IS NULL", token.ID).Update("revoked_at", time.Now())
+	if result.Error != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: result.Error.Error(),
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		c.JSON(http.StatusUnauthorized, utils.HTTPError{
+			Code:    http.StatusUnauthorized,
+			Message: "Refresh token has been revoked",
+		})
+		return
+	}
+	tokens, err := issueTokens(tx, token.UserID, token.FamilyID)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	tx.Commit()
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Logout revokes the access token it is called with and the given refresh
+// token's family, or every refresh token of the user when none is given.
+func Logout(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	user := c.MustGet("user").(models.User)
+	value, ok := c.Get("claims")
+	if !ok {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: "Logout requires an access token",
+		})
+		return
+	}
+	claims := value.(*utils.AccessClaims)
+
+	// The body is optional.
+	var input logoutInput
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil && err != io.EOF {
+			c.JSON(http.StatusBadRequest, utils.HTTPError{
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+
+	scope, value := "user_id = ?", interface{}(user.ID)
+	if input.RefreshToken != "" {
+		var token models.RefreshToken
+		if err := db.Where("token_hash = ? AND user_id = ?", utils.HashToken(input.RefreshToken), user.ID).First(&token).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				c.JSON(http.StatusBadRequest, utils.HTTPError{
+					Code:    http.StatusBadRequest,
+					Message: "Invalid refresh token",
+				})
+			} else {
+				c.JSON(http.StatusInternalServerError, utils.HTTPError{
+					Code:    http.StatusInternalServerError,
+					Message: err.Error(),
+				})
+			}
+			return
+		}
+		scope, value = "family_id = ?", token.FamilyID
+	}
+
+	tx := db.Begin()
+	if err := revokeRefreshTokens(tx.Where(scope, value)); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	revoked := models.RevokedToken{JTI: claims.ID, ExpiresAt: claims.ExpiresAt.Time}
+	if err := tx.Create(&revoked).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err := tx.Where("expires_at < ?", time.Now()).Delete(&models.RevokedToken{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	tx.Commit()
+	c.JSON(http.StatusOK, utils.MessageResponse{
+		Message: "Logged out successfully",
+	})
+}
+
+// issueTokens signs an access token and stores a new refresh token in the
+// given family.
+func issueTokens(tx *gorm.DB, userID uint, familyID string) (models.TokenResponse, error) {
+	accessToken, _, err := utils.IssueAccessToken(userID)
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+
+	refreshToken := utils.RandomToken()
+	if err := tx.Create(&models.RefreshToken{
+		UserID:    userID,
+		TokenHash: utils.HashToken(refreshToken),
+		FamilyID:  familyID,
+		ExpiresAt: time.Now().Add(utils.RefreshTokenTTL()),
+	}).Error; err != nil {
+		return models.TokenResponse{}, err
+	}
+
+	return models.TokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(utils.AccessTokenTTL().Seconds()),
+	}, nil
+}
+
+func revokeRefreshTokens(query *gorm.DB) error {
+	return query.Model(&models.RefreshToken{}).Where("revoked_at IS NULL").Update("revoked_at", time.Now()).Error
+}
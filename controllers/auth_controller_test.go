@@ -0,0 +1,178 @@
+package controllers
+
+import (
+	"cms-backend/models"
+	"cms-backend/utils"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+)
+
+func TestLogin(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	hash, _ := utils.HashPassword("secret")
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE email = \$1 ORDER BY "users"\."id" LIMIT \$2`).
+		WithArgs("ann@example.com", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "password_hash"}).AddRow(1, "ann@example.com", hash))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "refresh_tokens" \("user_id","token_hash","family_id","expires_at","revoked_at","created_at"\)`).
+		WithArgs(1, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), nil, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	router.POST("/auth/login", Login)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"email":" Ann@example.com","password":"secret"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response models.TokenResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+	if response.TokenType != "Bearer" || response.RefreshToken == "" || response.ExpiresIn != 900 {
+		t.Fatalf("Expected a bearer token pair, but got %+v", response)
+	}
+	claims, err := utils.ParseAccessToken(response.AccessToken)
+	if err != nil {
+		t.Fatalf("Expected a valid access token, but got %v", err)
+	}
+	if id, _ := claims.UserID(); id != 1 {
+		t.Fatalf("Expected subject 1, but got %d", id)
+	}
+}
+
+func TestLoginInvalidCredentials(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	hash, _ := utils.HashPassword("secret")
+	mock.ExpectQuery(`SELECT \* FROM "users"`).
+		WithArgs("ann@example.com", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "password_hash"}).AddRow(1, "ann@example.com", hash))
+	mock.ExpectQuery(`SELECT \* FROM "users"`).
+		WithArgs("nobody@example.com", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	router.POST("/auth/login", Login)
+	for _, body := range []string{
+		`{"email":"ann@example.com","password":"wrong"}`,
+		`{"email":"nobody@example.com","password":"secret"}`,
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected status 401 for %s, but got %d", body, w.Code)
+		}
+	}
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT \* FROM "refresh_tokens" WHERE token_hash = \$1`).
+		WithArgs(utils.HashToken("old-token"), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "family_id", "expires_at", "revoked_at"}).
+			AddRow(5, 1, "family", time.Now().Add(time.Hour), nil))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "refresh_tokens" SET "revoked_at"=\$1 WHERE id = \$2 AND revoked_at IS NULL`).
+		WithArgs(sqlmock.AnyArg(), 5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO "refresh_tokens"`).
+		WithArgs(1, sqlmock.AnyArg(), "family", sqlmock.AnyArg(), nil, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(6))
+	mock.ExpectCommit()
+
+	router.POST("/auth/refresh", Refresh)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/auth/refresh", strings.NewReader(`{"refresh_token":"old-token"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response models.TokenResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+	if response.RefreshToken == "" || response.RefreshToken == "old-token" {
+		t.Fatalf("Expected a new refresh token, but got %q", response.RefreshToken)
+	}
+}
+
+func TestRefreshReuseRevokesFamily(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT \* FROM "refresh_tokens" WHERE token_hash = \$1`).
+		WithArgs(utils.HashToken("old-token"), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "family_id", "expires_at", "revoked_at"}).
+			AddRow(5, 1, "family", time.Now().Add(time.Hour), time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "refresh_tokens" SET "revoked_at"=\$1 WHERE family_id = \$2 AND revoked_at IS NULL`).
+		WithArgs(sqlmock.AnyArg(), "family").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	router.POST("/auth/refresh", Refresh)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/auth/refresh", strings.NewReader(`{"refresh_token":"old-token"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected status 401, but got %d: %s", w.Code, w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("Expected the token family to be revoked: %v", err)
+	}
+}
+
+func TestLogout(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	_, claims, _ := utils.IssueAccessToken(1)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "refresh_tokens" SET "revoked_at"=\$1 WHERE user_id = \$2 AND revoked_at IS NULL`).
+		WithArgs(sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`INSERT INTO "revoked_tokens" \("jti","expires_at"\) VALUES \(\$1,\$2\)`).
+		WithArgs(claims.ID, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM "revoked_tokens" WHERE expires_at < \$1`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	router.Use(func(c *gin.Context) {
+		c.Set("user", models.User{ID: 1})
+		c.Set("claims", &claims)
+	})
+	router.POST("/auth/logout", Logout)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/auth/logout", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+}
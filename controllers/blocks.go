@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"cms-backend/models"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// projectBlocks validates a block document, checks that its image blocks
+// reference existing media and returns the plain-text projection to store
+// in Content. On failure it also returns the HTTP status to respond with.
+func projectBlocks(db *gorm.DB, doc models.JSON) (string, int, error) {
+	blocks, err := models.ParseBlocks(doc)
+	if err != nil {
+		return "", http.StatusBadRequest, err
+	}
+
+	if ids := models.MediaIDs(blocks); len(ids) > 0 {
+		var found []uint
+		if err := db.Model(&models.Media{}).Where("id IN ?", ids).Pluck("id", &found).Error; err != nil {
+			return "", http.StatusInternalServerError, err
+		}
+		if missing := missingIDs(ids, found); len(missing) > 0 {
+			return "", http.StatusBadRequest, fmt.Errorf("blocks reference unknown media: %s", strings.Join(missing, ", "))
+		}
+	}
+	return models.PlainText(blocks), 0, nil
+}
+
+func missingIDs(wanted, found []uint) []string {
+	present := make(map[uint]bool, len(found))
+	for _, id := range found {
+		present[id] = true
+	}
+	var missing []string
+	for _, id := range wanted {
+		if !present[id] {
+			missing = append(missing, fmt.Sprint(id))
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
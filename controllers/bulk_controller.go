@@ -0,0 +1,284 @@
+package controllers
+
+import (
+	"bytes"
+	"cms-backend/models"
+	"cms-backend/utils"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// bulkHandler runs one kind of bulk operation through the single-item
+// handler, which needs permission on top of the route's when it is set.
+type bulkHandler struct {
+	method     string
+	handler    gin.HandlerFunc
+	permission models.Permission
+}
+
+var bulkHandlers = map[string]map[string]bulkHandler{
+	models.ResourcePage: {
+		models.BulkCreate: {method: http.MethodPost, handler: CreatePage},
+		models.BulkUpdate: {method: http.MethodPut, handler: UpdatePage},
+		models.BulkPatch:  {method: http.MethodPatch, handler: PatchPage},
+		models.BulkDelete: {method: http.MethodDelete, handler: DeletePage},
+	},
+	models.ResourcePost: {
+		models.BulkCreate: {method: http.MethodPost, handler: CreatePost},
+		models.BulkUpdate: {method: http.MethodPut, handler: UpdatePost},
+		models.BulkPatch:  {method: http.MethodPatch, handler: PatchPost},
+		models.BulkDelete: {method: http.MethodDelete, handler: DeletePost},
+	},
+	models.ResourceMedia: {
+		models.BulkCreate: {method: http.MethodPost, handler: CreateMedia},
+		models.BulkPatch:  {method: http.MethodPatch, handler: PatchMedia},
+		models.BulkDelete: {method: http.MethodDelete, handler: DeleteMedia, permission: models.PermDeleteMedia},
+	},
+}
+
+func BulkPages(c *gin.Context) { bulk(c, models.ResourcePage) }
+func BulkPosts(c *gin.Context) { bulk(c, models.ResourcePost) }
+func BulkMedia(c *gin.Context) { bulk(c, models.ResourceMedia) }
+
+// bulk runs a list of operations on pages, posts or media, each exactly as
+// its single-item request would run. In atomic mode they share one
+// transaction, each in a savepoint, and the first failure rolls back all of
+// them and answers with its status. In best-effort mode each commits on its
+// own and the response reports every status.
+func bulk(c *gin.Context, resourceType string) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var request models.BulkRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	if request.Mode == "" {
+		request.Mode = models.BulkAtomic
+	}
+	if request.Mode != models.BulkAtomic && request.Mode != models.BulkBestEffort {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: "mode must be atomic or best_effort",
+		})
+		return
+	}
+	if len(request.Operations) == 0 || len(request.Operations) > utils.BulkMaxOperations() {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: "operations must hold between 1 and " + strconv.Itoa(utils.BulkMaxOperations()) + " operations",
+		})
+		return
+	}
+
+	response := models.BulkResponse{Mode: request.Mode, Results: []models.BulkResult{}}
+	if request.Mode == models.BulkBestEffort {
+		for i, operation := range request.Operations {
+			result := runBulkOperation(c, resourceType, i, operation, nil)
+			if result.Status < http.StatusBadRequest {
+				response.Succeeded++
+			} else {
+				response.Failed++
+			}
+			response.Results = append(response.Results, result)
+		}
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	tx := db.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: tx.Error.Error(),
+		})
+		return
+	}
+	pending := &bulkCommit{db: utils.NestTransactions(tx)}
+	if index, ok := c.Get("search"); ok {
+		if _, ok := index.(*utils.PostgresSearchIndex); !ok {
+			pending.index = &deferredIndex{SearchIndex: index.(utils.SearchIndex)}
+		}
+	}
+	for i, operation := range request.Operations {
+		result := runBulkOperation(c, resourceType, i, operation, pending)
+		if result.Status >= http.StatusBadRequest {
+			tx.Rollback()
+			response.Failed = 1
+			response.Results = []models.BulkResult{result}
+			c.JSON(result.Status, response)
+			return
+		}
+		response.Results = append(response.Results, result)
+	}
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	response.Succeeded = len(response.Results)
+	pending.committed(c)
+	c.JSON(http.StatusOK, response)
+}
+
+// runBulkOperation runs one operation through its single-item handler on a
+// copy of the request, and records the response it would have sent. With
+// pending, the operation runs in the bulk request's transaction.
+func runBulkOperation(c *gin.Context, resourceType string, i int, operation models.BulkOperation, pending *bulkCommit) models.BulkResult {
+	result := models.BulkResult{Index: i, Op: operation.Op}
+	run, ok := bulkHandlers[resourceType][operation.Op]
+	if !ok {
+		result.Status = http.StatusBadRequest
+		result.Error = "Unsupported operation " + strconv.Quote(operation.Op)
+		return result
+	}
+	if run.permission != "" && !can(c, run.permission) {
+		result.Status = http.StatusForbidden
+		result.Error = "You do not have permission to " + operation.Op + " " + resourceType
+		return result
+	}
+
+	item := c.Copy()
+	writer := &bulkWriter{header: http.Header{}}
+	item.Writer = writer
+	item.Request = c.Request.Clone(c.Request.Context())
+	item.Request.Method = run.method
+	item.Request.URL.RawQuery = ""
+	item.Request.Body = io.NopCloser(bytes.NewReader(operation.Data))
+	item.Request.ContentLength = int64(len(operation.Data))
+	item.Request.Header.Set("Content-Type", "application/json")
+	if operation.Op == models.BulkPatch {
+		item.Request.Header.Set("Content-Type", mergePatchType)
+	}
+	item.Request.Header.Del("If-Match")
+	if operation.Version != 0 {
+		item.Request.Header.Set("If-Match", utils.VersionETag(operation.Version, nil))
+	}
+	item.Params = gin.Params{{Key: "id", Value: strconv.FormatUint(uint64(operation.ID), 10)}}
+	if pending != nil {
+		item.Set("db", pending.db)
+		item.Set("bulk", pending)
+		if pending.index != nil {
+			item.Set("search", pending.index)
+		}
+	}
+
+	run.handler(item)
+
+	result.Status = writer.Status()
+	if result.Status < http.StatusBadRequest {
+		result.Data = writer.body.Bytes()
+		return result
+	}
+	var failure utils.HTTPError
+	if err := json.Unmarshal(writer.body.Bytes(), &failure); err == nil && failure.Message != "" {
+		result.Error = failure.Message
+	} else {
+		result.Error = http.StatusText(result.Status)
+	}
+	return result
+}
+
+// bulkCommit holds back what the operations of an atomic bulk request do
+// once committed, cache invalidation, events and search indexing, until the
+// request's transaction commits.
+type bulkCommit struct {
+	db            *gorm.DB
+	index         *deferredIndex
+	invalidations []utils.Invalidation
+}
+
+// hold takes over the invalidations an operation left for notifyCommitted.
+func (b *bulkCommit) hold(c *gin.Context) {
+	pending, _ := c.Get("invalidations")
+	invalidations, _ := pending.([]utils.Invalidation)
+	b.invalidations = append(b.invalidations, invalidations...)
+	c.Set("invalidations", []utils.Invalidation(nil))
+}
+
+// committed runs what the operations held back, now that they committed.
+func (b *bulkCommit) committed(c *gin.Context) {
+	c.Set("invalidations", b.invalidations)
+	notifyCommitted(c)
+	if b.index == nil {
+		return
+	}
+	for _, apply := range b.index.pending {
+		if err := apply(); err != nil {
+			log.Printf("Failed to update the search index after a bulk request: %v", err)
+		}
+	}
+}
+
+// deferredIndex queues the search index updates of an atomic bulk request,
+// so a rolled back request leaves the index alone.
+type deferredIndex struct {
+	utils.SearchIndex
+	pending []func() error
+}
+
+func (d *deferredIndex) Index(doc utils.SearchDocument) error {
+	d.pending = append(d.pending, func() error { return d.SearchIndex.Index(doc) })
+	return nil
+}
+
+func (d *deferredIndex) Delete(resourceType string, id uint) error {
+	d.pending = append(d.pending, func() error { return d.SearchIndex.Delete(resourceType, id) })
+	return nil
+}
+
+// bulkWriter captures the response of one bulk operation.
+type bulkWriter struct {
+	gin.ResponseWriter
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bulkWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bulkWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *bulkWriter) WriteHeaderNow() {}
+
+func (w *bulkWriter) Write(data []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(data)
+}
+
+func (w *bulkWriter) WriteString(s string) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.WriteString(s)
+}
+
+func (w *bulkWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+func (w *bulkWriter) Size() int {
+	return w.body.Len()
+}
+
+func (w *bulkWriter) Written() bool {
+	return w.status != 0
+}
@@ -0,0 +1,189 @@
+package controllers
+
+import (
+	"bytes"
+	"cms-backend/models"
+	"cms-backend/utils"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/gorm"
+)
+
+func bulkRequest(t *testing.T, request models.BulkRequest) *http.Request {
+	body, err := json.Marshal(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodPost, "/bulk", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func TestBulkPagesAtomic(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectBegin()
+	for i, title := range []string{"First", "Second"} {
+		savepoint := "sp" + string(rune('1'+i))
+		mock.ExpectExec(`^SAVEPOINT ` + savepoint + `$`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`INSERT INTO "pages"`).
+			WithArgs(1, title, "Content", sqlmock.AnyArg(), sqlmock.AnyArg(), nil, nil, "plain", "<p>Content</p>\n", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(i + 1))
+		expectAudit(mock, models.AuditActionCreate, models.ResourcePage, uint(i+1))
+		expectOutbox(mock, uint(i+1), models.EventPageCreated)
+		mock.ExpectExec(`^RELEASE SAVEPOINT ` + savepoint + `$`).WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+	mock.ExpectCommit()
+
+	router.POST("/bulk", BulkPages)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, bulkRequest(t, models.BulkRequest{Operations: []models.BulkOperation{
+		{Op: models.BulkCreate, Data: json.RawMessage(`{"title":"First","content":"Content"}`)},
+		{Op: models.BulkCreate, Data: json.RawMessage(`{"title":"Second","content":"Content"}`)},
+	}}))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	var response models.BulkResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+	if response.Mode != models.BulkAtomic || response.Succeeded != 2 || response.Failed != 0 {
+		t.Fatalf("Expected 2 atomic successes, but got %+v", response)
+	}
+	var page models.Page
+	if err := json.Unmarshal(response.Results[1].Data, &page); err != nil {
+		t.Fatalf("Error unmarshaling result: %v", err)
+	}
+	if response.Results[1].Status != http.StatusCreated || page.ID != 2 || page.Title != "Second" {
+		t.Fatalf("Expected the second page created, but got %+v", response.Results[1])
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBulkPagesAtomicRollsBack(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`^SAVEPOINT sp1$`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`INSERT INTO "pages"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	expectAudit(mock, models.AuditActionCreate, models.ResourcePage, 1)
+	expectOutbox(mock, 1, models.EventPageCreated)
+	mock.ExpectExec(`^RELEASE SAVEPOINT sp1$`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	router.POST("/bulk", BulkPages)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, bulkRequest(t, models.BulkRequest{Operations: []models.BulkOperation{
+		{Op: models.BulkCreate, Data: json.RawMessage(`{"title":"First","content":"Content"}`)},
+		{Op: models.BulkCreate, Data: json.RawMessage(`{"content":"Content"}`)},
+	}}))
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, but got %d: %s", w.Code, w.Body.String())
+	}
+	var response models.BulkResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+	if response.Failed != 1 || len(response.Results) != 1 || response.Results[0].Index != 1 {
+		t.Fatalf("Expected the second operation reported as failed, but got %+v", response)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBulkMediaBestEffort(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "media"`).
+		WithArgs(1, "https://example.com/new-image.jpg", "image", sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	expectAudit(mock, models.AuditActionCreate, models.ResourceMedia, 1)
+	expectOutbox(mock, 1, models.EventMediaCreated)
+	mock.ExpectCommit()
+	mock.ExpectQuery(`SELECT \* FROM "media" WHERE "media"\."id" = \$1 ORDER BY "media"\."id" LIMIT \$2`).
+		WithArgs(999, 1).
+		WillReturnError(gorm.ErrRecordNotFound)
+
+	router.POST("/bulk", BulkMedia)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, bulkRequest(t, models.BulkRequest{Mode: models.BulkBestEffort, Operations: []models.BulkOperation{
+		{Op: models.BulkCreate, Data: json.RawMessage(`{"url":"https://example.com/new-image.jpg","type":"image"}`)},
+		{Op: models.BulkDelete, ID: 999},
+		{Op: models.BulkUpdate, ID: 1},
+	}}))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	var response models.BulkResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+	if response.Succeeded != 1 || response.Failed != 2 {
+		t.Fatalf("Expected 1 success and 2 failures, but got %+v", response)
+	}
+	for i, want := range []int{http.StatusCreated, http.StatusNotFound, http.StatusBadRequest} {
+		if response.Results[i].Status != want {
+			t.Fatalf("Expected operation %d to answer %d, but got %+v", i, want, response.Results[i])
+		}
+	}
+	if response.Results[1].Error != "Media not found" {
+		t.Fatalf("Expected error 'Media not found', but got '%s'", response.Results[1].Error)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBulkMediaDeleteForbidden(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDBAs(t, &models.User{ID: 2, Role: models.UserRoleEditor})
+	defer mock.ExpectClose()
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	router.POST("/bulk", BulkMedia)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, bulkRequest(t, models.BulkRequest{Operations: []models.BulkOperation{
+		{Op: models.BulkDelete, ID: 1},
+	}}))
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403, but got %d: %s", w.Code, w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBulkTooManyOperations(t *testing.T) {
+	t.Setenv("BULK_MAX_OPERATIONS", "1")
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	router.POST("/bulk", BulkPosts)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, bulkRequest(t, models.BulkRequest{Operations: []models.BulkOperation{
+		{Op: models.BulkDelete, ID: 1},
+		{Op: models.BulkDelete, ID: 2},
+	}}))
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, but got %d", w.Code)
+	}
+}
@@ -0,0 +1,189 @@
+package controllers
+
+import (
+	"cms-backend/models"
+	"cms-backend/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type contentEntryInput struct {
+	Data models.JSON `json:"data" binding:"required"`
+}
+
+func GetContentEntries(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	contentType, ok := findContentType(c, db)
+	if !ok {
+		return
+	}
+
+	var entries []models.ContentEntry
+	if err := db.Where("content_type_id = ?", contentType.ID).Order("id").Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
+
+func GetContentEntry(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	contentType, ok := findContentType(c, db)
+	if !ok {
+		return
+	}
+	entry, ok := findContentEntry(c, db, contentType)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, entry)
+}
+
+func CreateContentEntry(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	contentType, ok := findContentType(c, db)
+	if !ok {
+		return
+	}
+
+	var input contentEntryInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err := utils.ValidateJSON(contentType.Schema, input.Data); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, utils.HTTPError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	entry := models.ContentEntry{
+		ContentTypeID: contentType.ID,
+		Data:          input.Data,
+	}
+
+	tx := db.Begin()
+	if err := tx.Create(&entry).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	tx.Commit()
+	c.JSON(http.StatusCreated, entry)
+}
+
+func UpdateContentEntry(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	contentType, ok := findContentType(c, db)
+	if !ok {
+		return
+	}
+	entry, ok := findContentEntry(c, db, contentType)
+	if !ok {
+		return
+	}
+
+	var input contentEntryInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err := utils.ValidateJSON(contentType.Schema, input.Data); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, utils.HTTPError{
+			Code:    http.StatusUnprocessableEntity,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	entry.Data = input.Data
+
+	tx := db.Begin()
+	if err := tx.Save(&entry).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	tx.Commit()
+	c.JSON(http.StatusOK, entry)
+}
+
+func DeleteContentEntry(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	contentType, ok := findContentType(c, db)
+	if !ok {
+		return
+	}
+	entry, ok := findContentEntry(c, db, contentType)
+	if !ok {
+		return
+	}
+
+	tx := db.Begin()
+	if err := tx.Delete(&entry).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	tx.Commit()
+	c.JSON(http.StatusOK, utils.MessageResponse{
+		Message: "Entry deleted successfully",
+	})
+}
+
+func findContentEntry(c *gin.Context, db *gorm.DB, contentType models.ContentType) (models.ContentEntry, bool) {
+	var entry models.ContentEntry
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid entry ID",
+		})
+		return entry, false
+	}
+
+	if err := db.Where("content_type_id = ?", contentType.ID).First(&entry, uint(id)).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, utils.HTTPError{
+				Code:    http.StatusNotFound,
+				Message: "Entry not found",
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, utils.HTTPError{
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
+			})
+		}
+		return entry, false
+	}
+	return entry, true
+}
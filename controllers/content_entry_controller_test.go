@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"bytes"
+	"cms-backend/models"
+	"cms-backend/utils"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/gorm"
+)
+
+func expectEventContentType(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(`SELECT \* FROM "content_types" WHERE slug = \$1 ORDER BY "content_types"\."id" LIMIT \$2`).
+		WithArgs("events", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "slug", "name", "schema"}).AddRow(7, "events", "Events", eventSchema))
+}
+
+func TestGetContentEntries(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	expectEventContentType(mock)
+	mock.ExpectQuery(`SELECT \* FROM "content_entries" WHERE content_type_id = \$1 ORDER BY id`).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "content_type_id", "data", "created_at", "updated_at"}).
+			AddRow(1, 7, `{"name":"Launch","starts_at":"2026-01-01T10:00:00Z"}`, time.Now(), time.Now()))
+
+	router.GET("/content/:type", GetContentEntries)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/content/events", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d", w.Code)
+	}
+
+	var response []struct {
+		ID   uint `json:"id"`
+		Data struct {
+			Name string `json:"name"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+	if len(response) != 1 || response[0].Data.Name != "Launch" {
+		t.Fatalf("Expected the Launch entry, but got %+v", response)
+	}
+}
+
+func TestGetContentEntriesUnknownType(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT \* FROM "content_types" WHERE slug = \$1`).
+		WithArgs("products", 1).
+		WillReturnError(gorm.ErrRecordNotFound)
+
+	router.GET("/content/:type", GetContentEntries)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/content/products", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("Expected status 404, but got %d", w.Code)
+	}
+}
+
+func TestCreateContentEntry(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	data := `{"name":"Launch","starts_at":"2026-01-01T10:00:00Z","capacity":50}`
+
+	expectEventContentType(mock)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "content_entries"`).
+		WithArgs(1, 7, data, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	router.POST("/content/:type", CreateContentEntry)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/content/events", bytes.NewBufferString(`{"data":`+data+`}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response models.ContentEntry
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+	if response.ContentTypeID != 7 {
+		t.Fatalf("Expected content type 7, but got %d", response.ContentTypeID)
+	}
+}
+
+func TestCreateContentEntrySchemaViolation(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	expectEventContentType(mock)
+
+	router.POST("/content/:type", CreateContentEntry)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/content/events", bytes.NewBufferString(`{"data":{"name":"Launch","capacity":0}}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status 422, but got %d", w.Code)
+	}
+
+	var response utils.HTTPError
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+	if !strings.Contains(response.Message, "starts_at") || !strings.Contains(response.Message, "/capacity") {
+		t.Fatalf("Expected message to name the failing fields, but got '%s'", response.Message)
+	}
+}
+
+func TestUpdateContentEntry(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	data := `{"name":"Launch party","starts_at":"2026-01-01T18:00:00Z"}`
+
+	expectEventContentType(mock)
+	mock.ExpectQuery(`SELECT \* FROM "content_entries" WHERE content_type_id = \$1 AND "content_entries"\."id" = \$2 ORDER BY "content_entries"\."id" LIMIT \$3`).
+		WithArgs(7, 1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "content_type_id", "data", "created_at", "updated_at"}).
+			AddRow(1, 7, `{"name":"Launch","starts_at":"2026-01-01T10:00:00Z"}`, time.Now(), time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "content_entries" SET "site_id"=\$1,"content_type_id"=\$2,"data"=\$3,"created_at"=\$4,"updated_at"=\$5 WHERE "id" = \$6`).
+		WithArgs(sqlmock.AnyArg(), 7, data, sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	router.PUT("/content/:type/:id", UpdateContentEntry)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPut, "/content/events/1", bytes.NewBufferString(`{"data":`+data+`}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestDeleteContentEntryInvalidID(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	expectEventContentType(mock)
+
+	router.DELETE("/content/:type/:id", DeleteContentEntry)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodDelete, "/content/events/abc", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, but got %d", w.Code)
+	}
+}
@@ -0,0 +1,196 @@
+package controllers
+
+import (
+	"cms-backend/models"
+	"cms-backend/utils"
+	"net/http"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var contentTypeSlug = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,99}$`)
+
+func GetContentTypes(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	var contentTypes []models.ContentType
+
+	if err := db.Order("slug").Find(&contentTypes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, contentTypes)
+}
+
+func GetContentType(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	contentType, ok := findContentType(c, db)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, contentType)
+}
+
+func CreateContentType(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var contentType models.ContentType
+	if err := c.ShouldBindJSON(&contentType); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if !contentTypeSlug.MatchString(contentType.Slug) {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: "Slug must start with a letter and contain only lowercase letters, digits, '-' or '_'",
+		})
+		return
+	}
+	if _, err := utils.CompileSchema(contentType.Schema); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	var count int64
+	if err := db.Model(&models.ContentType{}).Where("slug = ?", contentType.Slug).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, utils.HTTPError{
+			Code:    http.StatusConflict,
+			Message: "Content type already exists",
+		})
+		return
+	}
+
+	tx := db.Begin()
+	if err := tx.Create(&contentType).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	tx.Commit()
+	c.JSON(http.StatusCreated, contentType)
+}
+
+func UpdateContentType(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	contentType, ok := findContentType(c, db)
+	if !ok {
+		return
+	}
+
+	var updateData struct {
+		Name        string      `json:"name"`
+		Description *string     `json:"description"`
+		Schema      models.JSON `json:"schema"`
+	}
+	if err := c.ShouldBindJSON(&updateData); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if updateData.Name != "" {
+		contentType.Name = updateData.Name
+	}
+	if updateData.Description != nil {
+		contentType.Description = *updateData.Description
+	}
+	if !updateData.Schema.IsNull() {
+		if _, err := utils.CompileSchema(updateData.Schema); err != nil {
+			c.JSON(http.StatusBadRequest, utils.HTTPError{
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			})
+			return
+		}
+		contentType.Schema = updateData.Schema
+	}
+
+	tx := db.Begin()
+	if err := tx.Save(&contentType).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	tx.Commit()
+	c.JSON(http.StatusOK, contentType)
+}
+
+func DeleteContentType(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	contentType, ok := findContentType(c, db)
+	if !ok {
+		return
+	}
+
+	tx := db.Begin()
+	if err := tx.Where("content_type_id = ?", contentType.ID).Delete(&models.ContentEntry{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err := tx.Delete(&contentType).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	tx.Commit()
+	c.JSON(http.StatusOK, utils.MessageResponse{
+		Message: "Content type deleted successfully",
+	})
+}
+
+// findContentType loads the content type named by the :type route
+// parameter, writing the error response itself when it cannot.
+func findContentType(c *gin.Context, db *gorm.DB) (models.ContentType, bool) {
+	var contentType models.ContentType
+	if err := db.Where("slug = ?", c.Param("type")).First(&contentType).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, utils.HTTPError{
+				Code:    http.StatusNotFound,
+				Message: "Content type not found",
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, utils.HTTPError{
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
+			})
+		}
+		return contentType, false
+	}
+	return contentType, true
+}
@@ -0,0 +1,171 @@
+package controllers
+
+import (
+	"bytes"
+	"cms-backend/models"
+	"cms-backend/utils"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/gorm"
+)
+
+const eventSchema = `{"type":"object","required":["name","starts_at"],"properties":{"name":{"type":"string"},"starts_at":{"type":"string","format":"date-time"},"capacity":{"type":"integer","minimum":1}},"additionalProperties":false}`
+
+func TestGetContentTypes(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	rows := sqlmock.NewRows([]string{"id", "slug", "name", "description", "schema", "created_at", "updated_at"}).
+		AddRow(1, "events", "Events", "", eventSchema, time.Now(), time.Now())
+	mock.ExpectQuery(`SELECT \* FROM "content_types" ORDER BY slug`).WillReturnRows(rows)
+
+	router.GET("/content-types", GetContentTypes)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/content-types", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d", w.Code)
+	}
+
+	var response []models.ContentType
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+	if len(response) != 1 || response[0].Slug != "events" {
+		t.Fatalf("Expected the events type, but got %+v", response)
+	}
+	if string(response[0].Schema) != eventSchema {
+		t.Fatalf("Expected schema to round-trip, but got %s", response[0].Schema)
+	}
+}
+
+func TestGetContentTypeNotFound(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT \* FROM "content_types" WHERE slug = \$1 ORDER BY "content_types"\."id" LIMIT \$2`).
+		WithArgs("jobs", 1).
+		WillReturnError(gorm.ErrRecordNotFound)
+
+	router.GET("/content-types/:type", GetContentType)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/content-types/jobs", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("Expected status 404, but got %d", w.Code)
+	}
+}
+
+func TestCreateContentType(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "content_types" WHERE slug = \$1`).
+		WithArgs("events").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "content_types"`).
+		WithArgs(1, "events", "Events", "", eventSchema, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	body := `{"slug":"events","name":"Events","schema":` + eventSchema + `}`
+
+	router.POST("/content-types", CreateContentType)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/content-types", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, but got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestCreateContentTypeInvalidSchema(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	body := `{"slug":"events","name":"Events","schema":{"type":"no-such-type"}}`
+
+	router.POST("/content-types", CreateContentType)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/content-types", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, but got %d", w.Code)
+	}
+}
+
+func TestCreateContentTypeInvalidSlug(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	body := `{"slug":"Job Listings","name":"Jobs","schema":{"type":"object"}}`
+
+	router.POST("/content-types", CreateContentType)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/content-types", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, but got %d", w.Code)
+	}
+}
+
+func TestCreateContentTypeConflict(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "content_types" WHERE slug = \$1`).
+		WithArgs("events").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	body := `{"slug":"events","name":"Events","schema":{"type":"object"}}`
+
+	router.POST("/content-types", CreateContentType)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/content-types", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("Expected status 409, but got %d", w.Code)
+	}
+}
+
+func TestDeleteContentType(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT \* FROM "content_types" WHERE slug = \$1`).
+		WithArgs("events", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "slug", "name", "schema"}).AddRow(1, "events", "Events", eventSchema))
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM "content_entries" WHERE content_type_id = \$1`).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(`DELETE FROM "content_types" WHERE "content_types"\."id" = \$1`).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	router.DELETE("/content-types/:type", DeleteContentType)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodDelete, "/content-types/events", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d", w.Code)
+	}
+}
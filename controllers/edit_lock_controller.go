@@ -0,0 +1,247 @@
+package controllers
+
+import (
+	"cms-backend/models"
+	"cms-backend/utils"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// lockedError answers a request on a page or post another user holds the
+// edit lock on, naming the holder.
+type lockedError struct {
+	utils.HTTPError
+	Lock models.EditLock `json:"lock"`
+}
+
+func LockPage(c *gin.Context)   { acquireLock(c, models.ResourcePage) }
+func UnlockPage(c *gin.Context) { releaseLock(c, models.ResourcePage) }
+
+func LockPost(c *gin.Context)   { acquireLock(c, models.ResourcePost) }
+func UnlockPost(c *gin.Context) { releaseLock(c, models.ResourcePost) }
+
+// acquireLock takes the edit lock on a page or post, or renews it when the
+// user already holds it; clients heartbeat by calling it again before the
+// lease expires. A lock held by someone else answers 423 until it lapses.
+func acquireLock(c *gin.Context, resourceType string) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	id, ok := translatableID(c, resourceType)
+	if !ok {
+		return
+	}
+	if !findLockable(c, db, resourceType, id) {
+		return
+	}
+	user, _ := currentUser(c)
+	// The lock is shown to other editors, who need not know the email.
+	name := user.Name
+	if name == "" {
+		name = "User " + strconv.FormatUint(uint64(user.ID), 10)
+	}
+
+	now := time.Now()
+	lock := models.EditLock{
+		ResourceType: resourceType,
+		ResourceID:   id,
+		UserID:       user.ID,
+		UserName:     name,
+		ExpiresAt:    now.Add(utils.EditLockTTL()),
+	}
+
+	// The lease moves to the user only when they hold it already or it
+	// lapsed, so two editors racing for it cannot both win.
+	tx := db.Begin()
+	result := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "resource_type"}, {Name: "resource_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"user_id", "user_name", "expires_at", "updated_at"}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			gorm.Expr("edit_locks.user_id = excluded.user_id OR edit_locks.expires_at <= ?", now),
+		}},
+	}).Create(&lock)
+	if result.Error != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: result.Error.Error(),
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		held, err := activeLock(db, resourceType, id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, utils.HTTPError{
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
+			})
+			return
+		}
+		if held == nil {
+			// The holder let go in the meantime; the client may retry.
+			c.JSON(http.StatusConflict, utils.HTTPError{
+				Code:    http.StatusConflict,
+				Message: "Lock changed hands, try again",
+			})
+			return
+		}
+		respondLocked(c, *held)
+		return
+	}
+	if err := invalidateCaches(c, tx, resourceType, id); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	tx.Commit()
+	notifyCommitted(c)
+	c.JSON(http.StatusOK, lock)
+}
+
+// releaseLock gives up the edit lock on a page or post. Only its holder may
+// release it, unless the user may break locks.
+func releaseLock(c *gin.Context, resourceType string) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	id, ok := translatableID(c, resourceType)
+	if !ok {
+		return
+	}
+
+	var lock models.EditLock
+	if err := db.Where("resource_type = ? AND resource_id = ?", resourceType, id).Take(&lock).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, utils.HTTPError{
+				Code:    http.StatusNotFound,
+				Message: "Lock not found",
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, utils.HTTPError{
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
+			})
+		}
+		return
+	}
+	user, _ := currentUser(c)
+	if lock.UserID != user.ID && lock.Active(time.Now()) && !can(c, models.PermBreakLocks) {
+		respondLocked(c, lock)
+		return
+	}
+
+	tx := db.Begin()
+	if err := tx.Where("user_id = ?", lock.UserID).Delete(&lock).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err := invalidateCaches(c, tx, resourceType, id); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	tx.Commit()
+	notifyCommitted(c)
+	c.JSON(http.StatusOK, utils.MessageResponse{
+		Message: "Lock released successfully",
+	})
+}
+
+// findLockable checks the page or post exists and that the user may edit
+// it, writing the error response itself when not.
+func findLockable(c *gin.Context, db *gorm.DB, resourceType string, id uint) bool {
+	if resourceType == models.ResourcePage {
+		_, ok := findTranslatable(c, db, resourceType, id)
+		return ok
+	}
+	var post models.Post
+	if err := db.Select("id", "owner_id").First(&post, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, utils.HTTPError{
+				Code:    http.StatusNotFound,
+				Message: "Post not found",
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, utils.HTTPError{
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
+			})
+		}
+		return false
+	}
+	if !canWritePost(c, post) {
+		forbid(c, "You can only edit your own posts")
+		return false
+	}
+	return true
+}
+
+// activeLock is the unexpired edit lock on a page or post, nil when there
+// is none.
+func activeLock(db *gorm.DB, resourceType string, id uint) (*models.EditLock, error) {
+	var lock models.EditLock
+	err := db.Where("resource_type = ? AND resource_id = ? AND expires_at > ?", resourceType, id, time.Now()).Take(&lock).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &lock, nil
+}
+
+// shownLock is the active lock on a page or post for a reader who may
+// edit it; nobody else is shown locks. A response showing a lock is not
+// stored in caches, since its lease runs out without an invalidation.
+func shownLock(c *gin.Context, db *gorm.DB, resourceType string, id uint, canEdit bool) (*models.EditLock, error) {
+	if !canEdit {
+		return nil, nil
+	}
+	lock, err := activeLock(db, resourceType, id)
+	if lock != nil {
+		c.Header("Cache-Control", "no-store")
+	}
+	return lock, err
+}
+
+// checkEditLock lets a write to a page or post through unless another user
+// holds its edit lock, which answers 423.
+func checkEditLock(c *gin.Context, db *gorm.DB, resourceType string, id uint) bool {
+	lock, err := activeLock(db, resourceType, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return false
+	}
+	if user, _ := currentUser(c); lock != nil && lock.UserID != user.ID {
+		respondLocked(c, *lock)
+		return false
+	}
+	return true
+}
+
+func respondLocked(c *gin.Context, lock models.EditLock) {
+	c.JSON(http.StatusLocked, lockedError{
+		HTTPError: utils.HTTPError{
+			Code:    http.StatusLocked,
+			Message: lock.UserName + " is editing this " + lock.ResourceType,
+		},
+		Lock: lock,
+	})
+}
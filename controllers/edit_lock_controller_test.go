@@ -0,0 +1,244 @@
+package controllers
+
+import (
+	"cms-backend/models"
+	"cms-backend/utils"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// expectNoLock expects the lookup of a page's or post's edit lock, finding
+// none.
+func expectNoLock(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(`SELECT \* FROM "edit_locks" WHERE resource_type = \$1 AND resource_id = \$2 AND expires_at > \$3`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+}
+
+// expectLock expects the lookup of a page's or post's edit lock, finding
+// the one userID holds.
+func expectLock(mock sqlmock.Sqlmock, resourceType string, userID uint, userName string) {
+	mock.ExpectQuery(`SELECT \* FROM "edit_locks" WHERE resource_type = \$1 AND resource_id = \$2`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "resource_type", "resource_id", "user_id", "user_name", "expires_at"}).
+			AddRow(1, resourceType, 1, userID, userName, time.Now().Add(time.Minute)))
+}
+
+func TestLockPage(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDBAs(t, &models.User{ID: 2, Name: "Anna", Role: models.UserRoleEditor})
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT "id","updated_at","content_format" FROM "pages" WHERE id = \$1`).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "updated_at"}).AddRow(1, time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "edit_locks" .* ON CONFLICT \("resource_type","resource_id"\) DO UPDATE SET .* WHERE edit_locks\.user_id = excluded\.user_id OR edit_locks\.expires_at <= \$\d+ RETURNING "id"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	expectInvalidation(mock)
+	mock.ExpectCommit()
+
+	router.POST("/pages/:id/lock", LockPage)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/pages/1/lock", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	var lock models.EditLock
+	if err := json.Unmarshal(w.Body.Bytes(), &lock); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+	if lock.UserID != 2 || lock.UserName != "Anna" || !lock.ExpiresAt.After(time.Now()) {
+		t.Fatalf("Expected an active lock held by Anna, but got %+v", lock)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestLockPageHeldByAnother(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDBAs(t, &models.User{ID: 3, Name: "Ben", Role: models.UserRoleEditor})
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT "id","updated_at","content_format" FROM "pages" WHERE id = \$1`).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "updated_at"}).AddRow(1, time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "edit_locks"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+	expectLock(mock, models.ResourcePage, 2, "Anna")
+
+	router.POST("/pages/:id/lock", LockPage)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/pages/1/lock", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusLocked {
+		t.Fatalf("Expected status 423, but got %d: %s", w.Code, w.Body.String())
+	}
+	var response lockedError
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+	if response.Lock.UserName != "Anna" {
+		t.Fatalf("Expected the lock held by Anna, but got %+v", response.Lock)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetPageShowsLock(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT \* FROM "pages" WHERE "pages"\."id" = \$1 ORDER BY "pages"\."id" LIMIT \$2`).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "content"}).AddRow(1, "Test Page", "Test Content"))
+	expectLock(mock, models.ResourcePage, 2, "Anna")
+
+	router.GET("/pages/:id", GetPage)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/pages/1", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	var page models.Page
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+	if page.Lock == nil || page.Lock.UserName != "Anna" || page.Lock.ExpiresAt.IsZero() {
+		t.Fatalf("Expected the page to show Anna's lock, but got %+v", page.Lock)
+	}
+	if w.Header().Get("Cache-Control") != "no-store" {
+		t.Fatalf("Expected a response showing a lock not to be stored, but got Cache-Control '%s'", w.Header().Get("Cache-Control"))
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetPageHidesLockFromReaders(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDBAs(t, nil)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT \* FROM "pages" WHERE "pages"\."id" = \$1 ORDER BY "pages"\."id" LIMIT \$2`).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "content"}).AddRow(1, "Test Page", "Test Content"))
+
+	router.GET("/pages/:id", GetPage)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/pages/1", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), `"lock"`) {
+		t.Fatalf("Expected no lock for an anonymous reader, but got %s", w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestLockPageWithoutNameHidesEmail(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDBAs(t, &models.User{ID: 2, Email: "anna@example.com", Role: models.UserRoleEditor})
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT "id","updated_at","content_format" FROM "pages" WHERE id = \$1`).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "updated_at"}).AddRow(1, time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "edit_locks"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	expectInvalidation(mock)
+	mock.ExpectCommit()
+
+	router.POST("/pages/:id/lock", LockPage)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/pages/1/lock", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "anna@example.com") {
+		t.Fatalf("Expected the lock not to show the email, but got %s", w.Body.String())
+	}
+}
+
+func TestUpdatePostLockedByAnother(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDBAs(t, &models.User{ID: 3, Name: "Ben", Role: models.UserRoleEditor})
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE "posts"\."id" = \$1`).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "content"}).AddRow(1, "Post", "Content"))
+	expectLock(mock, models.ResourcePost, 2, "Anna")
+
+	router.PUT("/posts/:id", UpdatePost)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPut, "/posts/1", strings.NewReader(`{"title":"Mine","content":"Overwritten"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusLocked {
+		t.Fatalf("Expected status 423, but got %d: %s", w.Code, w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUnlockPageHeldByAnother(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDBAs(t, &models.User{ID: 3, Name: "Ben", Role: models.UserRoleEditor})
+	defer mock.ExpectClose()
+
+	expectLock(mock, models.ResourcePage, 2, "Anna")
+
+	router.DELETE("/pages/:id/lock", UnlockPage)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodDelete, "/pages/1/lock", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusLocked {
+		t.Fatalf("Expected status 423, but got %d: %s", w.Code, w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestAdminBreaksPageLock(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	expectLock(mock, models.ResourcePage, 2, "Anna")
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM "edit_locks" WHERE user_id = \$1 AND "edit_locks"\."id" = \$2`).
+		WithArgs(2, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectInvalidation(mock)
+	mock.ExpectCommit()
+
+	router.DELETE("/pages/:id/lock", UnlockPage)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodDelete, "/pages/1/lock", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("There were unfulfilled expectations: %s", err)
+	}
+}
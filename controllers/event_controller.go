@@ -0,0 +1,273 @@
+package controllers
+
+import (
+	"cms-backend/models"
+	"cms-backend/utils"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	eventStreamPollInterval = time.Second
+	eventStreamHeartbeat    = 15 * time.Second
+	eventStreamBatchSize    = 100
+)
+
+// eventResourceScopes are the resource types events are streamed for and
+// the API key scope each needs.
+var eventResourceScopes = map[string]string{
+	models.ResourcePage:  models.ScopePagesRead,
+	models.ResourcePost:  models.ScopePostsRead,
+	models.ResourceMedia: models.ScopeMediaRead,
+}
+
+// StreamEvents streams the site's content events as Server-Sent Events.
+// Each event's id is its ID in the event log, so a client resuming
+// with Last-Event-ID receives every event it missed that is still kept.
+// Without it the stream starts with the next event.
+func StreamEvents(c *gin.Context) {
+	// The stream holds no connection between polls, so each read confines
+	// its own transaction to the site.
+	db := c.MustGet("db").(*gorm.DB)
+	siteID := currentSiteID(c)
+
+	resourceTypes, ok := streamResourceTypes(c)
+	if !ok {
+		return
+	}
+
+	// Browsers send Last-Event-ID when they reconnect; ?last_event_id= lets
+	// a client resume a stream it opened itself.
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	// The stream's position is the last event sent; a resumed stream picks
+	// up after the event the client names, or replays the events still kept
+	// when that one is gone.
+	var last models.OutboxEvent
+	position := utils.OutboxLatest
+	if lastEventID != "" {
+		id, err := strconv.ParseUint(lastEventID, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, utils.HTTPError{
+				Code:    http.StatusBadRequest,
+				Message: "Invalid Last-Event-ID",
+			})
+			return
+		}
+		last.ID = uint(id)
+		position = func(tx *gorm.DB) *gorm.DB {
+			return tx.Where("id = ?", last.ID).Limit(1)
+		}
+	}
+	if err := utils.InSite(db, siteID, func(tx *gorm.DB) error {
+		var events []models.OutboxEvent
+		if err := tx.Scopes(position).Find(&events).Error; err != nil {
+			return err
+		}
+		if len(events) > 0 {
+			last = events[0]
+		}
+		return nil
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	var wake <-chan struct{}
+	if value, ok := c.Get("events"); ok {
+		var unsubscribe func()
+		wake, unsubscribe = value.(*utils.EventNotifier).Subscribe()
+		defer unsubscribe()
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.WriteHeaderNow()
+	c.Writer.Flush()
+
+	poll := time.NewTicker(eventStreamPollInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(eventStreamHeartbeat)
+	defer heartbeat.Stop()
+	ctx := c.Request.Context()
+	for {
+		var events []models.OutboxEvent
+		if err := utils.InSite(db, siteID, func(tx *gorm.DB) error {
+			return tx.Scopes(utils.OutboxAfter(last.TxID, last.ID)).Where("resource_type IN ?", resourceTypes).
+				Limit(eventStreamBatchSize).Find(&events).Error
+		}); err != nil {
+			// The client reconnects with the last ID it received.
+			log.Printf("Failed to read event log: %v", err)
+			return
+		}
+		for _, event := range events {
+			last = event
+			if !canSeeEvent(c, event) {
+				continue
+			}
+			c.Render(-1, sse.Event{
+				Id:    strconv.FormatUint(uint64(event.ID), 10),
+				Event: event.Type,
+				Data:  event.Event(),
+			})
+		}
+		c.Writer.Flush()
+		if len(events) == eventStreamBatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-wake:
+		case <-poll.C:
+		case <-heartbeat.C:
+			c.Writer.WriteString(": keep-alive\n\n")
+			c.Writer.Flush()
+		}
+	}
+}
+
+// streamResourceTypes is the ?resource_type= filter of an event stream,
+// by default every resource type the request may read. API keys need the
+// read scope of each resource type they stream.
+func streamResourceTypes(c *gin.Context) ([]string, bool) {
+	user, _ := currentUser(c)
+	var requested []string
+	for _, value := range c.QueryArray("resource_type") {
+		for _, resourceType := range strings.Split(value, ",") {
+			if resourceType = strings.TrimSpace(resourceType); resourceType != "" {
+				requested = append(requested, resourceType)
+			}
+		}
+	}
+
+	var resourceTypes []string
+	if len(requested) == 0 {
+		for _, resourceType := range []string{models.ResourcePage, models.ResourcePost, models.ResourceMedia} {
+			if user.HasScope(eventResourceScopes[resourceType]) {
+				resourceTypes = append(resourceTypes, resourceType)
+			}
+		}
+	}
+	for _, resourceType := range requested {
+		scope, ok := eventResourceScopes[resourceType]
+		if !ok {
+			c.JSON(http.StatusBadRequest, utils.HTTPError{
+				Code:    http.StatusBadRequest,
+				Message: "resource_type must be page, post or media",
+			})
+			return nil, false
+		}
+		if !user.HasScope(scope) {
+			forbid(c, "API key is missing the "+scope+" scope")
+			return nil, false
+		}
+		resourceTypes = append(resourceTypes, resourceType)
+	}
+	if len(resourceTypes) == 0 {
+		forbid(c, "API key has no read scope for pages, posts or media")
+		return nil, false
+	}
+	return resourceTypes, true
+}
+
+// canSeeEvent hides events of unpublished posts from requests that cannot
+// view drafts.
+func canSeeEvent(c *gin.Context, event models.OutboxEvent) bool {
+	if event.ResourceType != models.ResourcePost || can(c, models.PermViewDrafts) {
+		return true
+	}
+	var post struct {
+		Status string `json:"status"`
+	}
+	return json.Unmarshal(event.Data, &post) == nil && post.Status == models.PostStatusPublished
+}
+
+// recordEvents writes content events to the outbox in tx, the transaction
+// of the change itself, so they are published if and only if it commits,
+// and invalidates cached copies of the resource on every replica.
+// data is the resource after the change, or before it for deletes.
+func recordEvents(c *gin.Context, tx *gorm.DB, resourceType string, resourceID uint, data interface{}, eventTypes ...string) error {
+	snapshot, err := auditSnapshot(data)
+	if err != nil {
+		return err
+	}
+	events := make([]models.OutboxEvent, 0, len(eventTypes))
+	for _, eventType := range eventTypes {
+		events = append(events, models.OutboxEvent{
+			EventID:      utils.RandomToken()[:22],
+			Type:         eventType,
+			ResourceType: resourceType,
+			ResourceID:   resourceID,
+			Data:         snapshot,
+		})
+	}
+	if err := utils.AppendOutbox(tx, events); err != nil {
+		return err
+	}
+	return invalidateCaches(c, tx, resourceType, resourceID)
+}
+
+// invalidateCaches tells every replica, once tx commits, that a resource of
+// the request's site changed. This replica's response cache is invalidated
+// by notifyCommitted, so the writer reads its own write.
+func invalidateCaches(c *gin.Context, tx *gorm.DB, resourceType string, resourceID uint) error {
+	invalidation := utils.Invalidation{
+		SiteID:   currentSiteID(c),
+		Resource: resourceType,
+		ID:       resourceID,
+	}
+	if err := utils.NotifyInvalidation(tx, invalidation); err != nil {
+		return err
+	}
+	pending, _ := c.Get("invalidations")
+	invalidations, _ := pending.([]utils.Invalidation)
+	c.Set("invalidations", append(invalidations, invalidation))
+	return nil
+}
+
+// notifyCommitted is called once a change is committed. It invalidates the
+// cached responses the change made stale and tells the outbox relay and
+// this process's event streams about its events; without it they are
+// picked up at their next poll. Within an atomic bulk request the change
+// only committed to a savepoint, so this waits for the request to commit.
+func notifyCommitted(c *gin.Context) {
+	if pending, ok := c.Get("bulk"); ok {
+		pending.(*bulkCommit).hold(c)
+		return
+	}
+	if value, ok := c.Get("cache"); ok {
+		cache := value.(*utils.ResponseCache)
+		pending, _ := c.Get("invalidations")
+		invalidations, _ := pending.([]utils.Invalidation)
+		for _, invalidation := range invalidations {
+			if err := cache.Invalidate(c.Request.Context(), invalidation); err != nil {
+				log.Printf("Failed to invalidate response cache: %v", err)
+			}
+		}
+	}
+	c.Set("invalidations", []utils.Invalidation(nil))
+
+	if relay, ok := c.Get("outbox"); ok {
+		relay.(*utils.OutboxRelay).Wake()
+	}
+	if notifier, ok := c.Get("events"); ok {
+		notifier.(*utils.EventNotifier).Notify()
+	}
+}
@@ -0,0 +1,286 @@
+package controllers
+
+import (
+	"bytes"
+	"cms-backend/models"
+	"cms-backend/utils"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+)
+
+// expectOutbox expects the events of a change to be appended to the outbox
+// in its transaction.
+func expectOutbox(mock sqlmock.Sqlmock, resourceID uint, eventTypes ...string) {
+	var args []driver.Value
+	rows := sqlmock.NewRows([]string{"id"})
+	for i, eventType := range eventTypes {
+		resourceType, _, _ := strings.Cut(eventType, ".")
+		args = append(args, 1, sqlmock.AnyArg(), eventType, resourceType, resourceID, sqlmock.AnyArg(), sqlmock.AnyArg())
+		rows.AddRow(i + 1)
+	}
+	mock.ExpectQuery(`INSERT INTO "outbox" \("site_id","event_id","type","resource_type","resource_id","data","created_at"\)`).
+		WithArgs(args...).
+		WillReturnRows(rows)
+	expectInvalidation(mock)
+}
+
+// expectInvalidation expects a cache invalidation to be sent to the other
+// replicas.
+func expectInvalidation(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(`SELECT pg_notify\(\$1, \$2\)`).
+		WithArgs(utils.InvalidationChannel, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+// expectRelay expects a relay pass to the sink starting after the event at
+// (lastTxID, lastEventID) and returning the given outbox rows.
+func expectRelay(mock sqlmock.Sqlmock, sink string, lastTxID int64, lastEventID uint, rows *sqlmock.Rows) {
+	mock.ExpectExec(`INSERT INTO outbox_cursors \(sink, last_event_id, updated_at\) VALUES \(\$1, 0, \$2\) ON CONFLICT \(sink\) DO NOTHING`).
+		WithArgs(sink, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM outbox_cursors WHERE sink = \$1 FOR UPDATE SKIP LOCKED`).
+		WithArgs(sink).
+		WillReturnRows(sqlmock.NewRows([]string{"sink", "last_txid", "last_event_id"}).AddRow(sink, lastTxID, lastEventID))
+	mock.ExpectQuery(`SELECT \* FROM "outbox" WHERE txid < pg_snapshot_xmin\(pg_current_snapshot\(\)\)::text::bigint AND \(txid, id\) > \(\$1, \$2\) ORDER BY txid, id LIMIT \$3`).
+		WithArgs(lastTxID, lastEventID, 100).
+		WillReturnRows(rows)
+}
+
+func outboxRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "txid", "site_id", "event_id", "type", "resource_type", "resource_id", "data", "created_at"}).
+		AddRow(13, 700, 1, "evt-11", models.EventPostCreated, models.ResourcePost, 4, `{"id":4}`, time.Now()).
+		AddRow(14, 700, 1, "evt-12", models.EventPostPublished, models.ResourcePost, 4, `{"id":4}`, time.Now()).
+		AddRow(12, 702, 2, "evt-13", models.EventMediaDeleted, models.ResourceMedia, 9, `{"id":9}`, time.Now())
+}
+
+func TestRelayPublishesInOrder(t *testing.T) {
+	_, db, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	expectRelay(mock, "stdout", 650, 11, outboxRows())
+	mock.ExpectExec(`UPDATE "outbox_cursors" SET "last_event_id"=\$1,"last_txid"=\$2,"updated_at"=\$3 WHERE "sink" = \$4`).
+		WithArgs(12, 702, sqlmock.AnyArg(), "stdout").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	var out bytes.Buffer
+	sink := utils.NewWriterSink("stdout", &out)
+	sent, err := utils.NewOutboxRelay(db, sink).Relay(context.Background(), sink)
+	if err != nil || sent != 3 {
+		t.Fatalf("Expected 3 events relayed, but got %d (%v)", sent, err)
+	}
+
+	var ids []string
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var event models.Event
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("Expected a JSON event per line, but got %q", line)
+		}
+		ids = append(ids, event.ID)
+	}
+	if strings.Join(ids, ",") != "evt-11,evt-12,evt-13" {
+		t.Fatalf("Expected events in outbox order, but got %v", ids)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// flakySink rejects every event after the first accepted ones.
+type flakySink struct {
+	accept    int
+	published []string
+}
+
+func (s *flakySink) Name() string {
+	return "flaky"
+}
+
+func (s *flakySink) Publish(ctx context.Context, event models.Event) error {
+	if len(s.published) == s.accept {
+		return errors.New("broker unavailable")
+	}
+	s.published = append(s.published, event.ID)
+	return nil
+}
+
+func TestRelayStopsAtRejectedEvent(t *testing.T) {
+	_, db, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	expectRelay(mock, "flaky", 650, 11, outboxRows())
+	mock.ExpectExec(`UPDATE "outbox_cursors" SET "last_event_id"=\$1,"last_txid"=\$2`).
+		WithArgs(13, 700, sqlmock.AnyArg(), "flaky").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	sink := &flakySink{accept: 1}
+	sent, err := utils.NewOutboxRelay(db, sink).Relay(context.Background(), sink)
+	if err == nil || sent != 1 {
+		t.Fatalf("Expected 1 event relayed and the sink's error, but got %d (%v)", sent, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("Expected the cursor to stop before the rejected event: %v", err)
+	}
+}
+
+func TestRelayToNATS(t *testing.T) {
+	broker, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: -1, JetStream: true, StoreDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go broker.Start()
+	defer broker.Shutdown()
+	if !broker.ReadyForConnections(5 * time.Second) {
+		t.Fatal("Embedded NATS server did not start")
+	}
+
+	sink, err := utils.OpenNATSSink(utils.NATSConfig{URL: broker.ClientURL()})
+	if err != nil {
+		t.Fatalf("Failed to open NATS sink: %v", err)
+	}
+	defer sink.Close()
+
+	_, db, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+	expectRelay(mock, "nats", 650, 11, outboxRows())
+	mock.ExpectExec(`UPDATE "outbox_cursors" SET "last_event_id"=\$1,"last_txid"=\$2`).
+		WithArgs(12, 702, sqlmock.AnyArg(), "nats").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	relay := utils.NewOutboxRelay(db, sink)
+	if sent, err := relay.Relay(context.Background(), sink); err != nil || sent != 3 {
+		t.Fatalf("Expected 3 events relayed, but got %d (%v)", sent, err)
+	}
+	// An event sent again after a crash is dropped by the stream.
+	if err := sink.Publish(context.Background(), models.Event{ID: "evt-12", Type: models.EventPostPublished, SiteID: 1}); err != nil {
+		t.Fatalf("Expected the duplicate to be acknowledged, but got %v", err)
+	}
+
+	conn, err := nats.Connect(broker.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	js, _ := conn.JetStream()
+	sub, err := js.SubscribeSync("cms.events.>", nats.OrderedConsumer())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var subjects []string
+	for range 3 {
+		msg, err := sub.NextMsg(2 * time.Second)
+		if err != nil {
+			t.Fatalf("Expected 3 messages, but got %v after %v", err, subjects)
+		}
+		subjects = append(subjects, msg.Subject+"#"+msg.Header.Get(nats.MsgIdHdr))
+	}
+	expected := "cms.events.1.post.created#evt-11,cms.events.1.post.published#evt-12,cms.events.2.media.deleted#evt-13"
+	if strings.Join(subjects, ",") != expected {
+		t.Fatalf("Expected %s, but got %v", expected, subjects)
+	}
+	if msg, err := sub.NextMsg(200 * time.Millisecond); err == nil {
+		t.Fatalf("Expected the duplicate to be dropped, but got %s", msg.Subject)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStreamEventsResumesAfterLastEventID(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDBAs(t, nil)
+	defer mock.ExpectClose()
+
+	expectInSite := func() {
+		mock.ExpectBegin()
+		mock.ExpectExec(`SELECT set_config\('app\.site_id', \$1, true\)`).
+			WithArgs("2").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+	expectInSite()
+	mock.ExpectQuery(`SELECT \* FROM "outbox" WHERE id = \$1 LIMIT \$2`).
+		WithArgs(10, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "txid", "site_id"}).AddRow(10, 650, 2))
+	mock.ExpectCommit()
+	expectInSite()
+	mock.ExpectQuery(`SELECT \* FROM "outbox" WHERE resource_type IN \(\$1\) AND txid < pg_snapshot_xmin\(pg_current_snapshot\(\)\)::text::bigint AND \(txid, id\) > \(\$2, \$3\) ORDER BY txid, id LIMIT \$4`).
+		WithArgs(models.ResourcePost, 650, 10, 100).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "txid", "site_id", "event_id", "type", "resource_type", "resource_id", "data", "created_at"}).
+			AddRow(11, 650, 2, "evt-11", models.EventPostPublished, models.ResourcePost, 4, `{"id":4,"status":"published"}`, time.Now()).
+			AddRow(9, 651, 2, "evt-12", models.EventPostCreated, models.ResourcePost, 5, `{"id":5,"status":"draft"}`, time.Now()))
+	mock.ExpectCommit()
+
+	router.Use(func(c *gin.Context) { c.Set("site", models.Site{ID: 2, Slug: "brand"}) })
+	router.GET("/events", StreamEvents)
+	ctx, cancel := context.WithCancel(context.Background())
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "/events?resource_type=post", nil)
+	req.Header.Set("Last-Event-ID", "10")
+	done := make(chan struct{})
+	go func() {
+		router.ServeHTTP(w, req)
+		close(done)
+	}()
+	for deadline := time.Now().Add(2 * time.Second); mock.ExpectationsWereMet() != nil; {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the event log to be read")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected an event stream, but got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, "id:11\nevent:post.published\ndata:{\"id\":\"evt-11\"") {
+		t.Fatalf("Expected the published post's event, but got %q", body)
+	}
+	if strings.Contains(body, "evt-12") {
+		t.Fatalf("Expected the draft's event to be hidden from anonymous clients, but got %q", body)
+	}
+}
+
+func TestStreamEventsInvalidResourceType(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	router.GET("/events", StreamEvents)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/events?resource_type=post,comment", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, but got %d", w.Code)
+	}
+}
+
+func TestStreamEventsRequiresReadScope(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDBAs(t, &models.User{ID: 1, Role: models.UserRoleEditor, Scopes: models.Scopes{models.ScopePagesRead}})
+	defer mock.ExpectClose()
+
+	router.GET("/events", StreamEvents)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/events?resource_type=page&resource_type=post", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403, but got %d", w.Code)
+	}
+}
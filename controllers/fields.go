@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"cms-backend/models"
+	"cms-backend/utils"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// fieldFilterParam matches list query parameters such as
+// fields.event_date[gte] or fields.venue.city.
+var fieldFilterParam = regexp.MustCompile(`^fields\.([A-Za-z0-9_]+(?:\.[A-Za-z0-9_]+)*)(?:\[([a-z]+)\])?$`)
+
+// jsonNumber matches the JSON number grammar, which jsonpath numeric
+// literals share. ParseFloat also takes NaN, Inf, 1_000 and hex floats.
+var jsonNumber = regexp.MustCompile(`^-?(?:0|[1-9][0-9]*)(?:\.[0-9]+)?(?:[eE][+-]?[0-9]+)?$`)
+
+var fieldFilterOperators = map[string]string{
+	"eq":  "==",
+	"ne":  "!=",
+	"gt":  ">",
+	"gte": ">=",
+	"lt":  "<",
+	"lte": "<=",
+}
+
+// applyFieldFilters turns fields.* query parameters into jsonpath predicates
+// on the fields column, matched with @@ so the GIN index on it can be used.
+func applyFieldFilters(query *gorm.DB, column string, params url.Values) (*gorm.DB, error) {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		if strings.HasPrefix(key, "fields.") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		match := fieldFilterParam.FindStringSubmatch(key)
+		if match == nil {
+			return nil, fmt.Errorf("invalid field filter %q", key)
+		}
+		path := jsonPath(strings.Split(match[1], "."))
+		op := match[2]
+		if op == "" {
+			op = "eq"
+		}
+
+		for _, value := range params[key] {
+			var predicate string
+			if op == "exists" {
+				predicate = "exists(" + path + ")"
+				if value == "false" {
+					predicate = "!(" + predicate + ")"
+				}
+			} else {
+				operator, ok := fieldFilterOperators[op]
+				if !ok {
+					return nil, fmt.Errorf("invalid field filter operator %q", op)
+				}
+				predicate = path + " " + operator + " " + jsonPathLiteral(value)
+			}
+			query = query.Where(column+" @@ ?::jsonpath", predicate)
+		}
+	}
+	return query, nil
+}
+
+func jsonPath(segments []string) string {
+	var b strings.Builder
+	b.WriteString("$")
+	for _, segment := range segments {
+		b.WriteString(".")
+		b.WriteString(strconv.Quote(segment))
+	}
+	return b.String()
+}
+
+// jsonPathLiteral types a query string value: numbers, booleans and null
+// compare as JSON scalars, a quoted value ("42") forces a string and
+// anything else is compared as a string.
+func jsonPathLiteral(value string) string {
+	switch value {
+	case "true", "false", "null":
+		return value
+	}
+	if jsonNumber.MatchString(value) {
+		return value
+	}
+	if len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+	}
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
+
+// validateFields checks custom fields are a JSON object and, when a schema
+// is configured for the resource, that they satisfy it.
+func validateFields(resourceType string, fields models.JSON) error {
+	document := []byte(fields)
+	if fields.IsNull() {
+		document = []byte("{}")
+	} else {
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(document, &object); err != nil {
+			return fmt.Errorf("fields must be a JSON object")
+		}
+	}
+
+	schema, err := utils.FieldSchema(resourceType)
+	if err != nil {
+		return err
+	}
+	if schema == nil {
+		return nil
+	}
+	if err := utils.ValidateJSON(schema, document); err != nil {
+		return fmt.Errorf("fields %w", err)
+	}
+	return nil
+}
@@ -52,6 +52,8 @@ func GetMediaByID(c *gin.Context) {
         }
         return
     }
+    setLastModified(c, media.UpdatedAt)
+    c.Header("ETag", utils.VersionETag(media.Version, nil))
     c.JSON(http.StatusOK, media)
 }
 
@@ -75,6 +77,7 @@ func CreateMedia(c *gin.Context) {
         return
     }
 
+    media.Version = 1
     tx := db.Begin()
     if err := tx.Create(&media).Error; err != nil {
         tx.Rollback()
@@ -84,10 +87,112 @@ func CreateMedia(c *gin.Context) {
         })
         return
     }
+    if err := recordAudit(c, tx, models.AuditActionCreate, models.ResourceMedia, media.ID, nil, media); err != nil {
+        tx.Rollback()
+        c.JSON(http.StatusInternalServerError, utils.HTTPError{
+            Code:    http.StatusInternalServerError,
+            Message: err.Error(),
+        })
+        return
+    }
+    if err := recordEvents(c, tx, models.ResourceMedia, media.ID, media, models.EventMediaCreated); err != nil {
+        tx.Rollback()
+        c.JSON(http.StatusInternalServerError, utils.HTTPError{
+            Code:    http.StatusInternalServerError,
+            Message: err.Error(),
+        })
+        return
+    }
     tx.Commit()
+    notifyCommitted(c)
     c.JSON(http.StatusCreated, media)
 }
 
+// PatchMedia applies a JSON Merge Patch or a JSON Patch to a media item and
+// checks the result as a create would.
+func PatchMedia(c *gin.Context) {
+    db := c.MustGet("db").(*gorm.DB)
+
+    idParam := c.Param("id")
+    id, err := strconv.ParseUint(idParam, 10, 32)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, utils.HTTPError{
+            Code:    http.StatusBadRequest,
+            Message: "Invalid media ID",
+        })
+        return
+    }
+
+    var media models.Media
+    if err := db.First(&media, uint(id)).Error; err != nil {
+        if err == gorm.ErrRecordNotFound {
+            c.JSON(http.StatusNotFound, utils.HTTPError{
+                Code:    http.StatusNotFound,
+                Message: "Media not found",
+            })
+        } else {
+            c.JSON(http.StatusInternalServerError, utils.HTTPError{
+                Code:    http.StatusInternalServerError,
+                Message: err.Error(),
+            })
+        }
+        return
+    }
+    if !checkPrecondition(c, media.Version, 0, media) {
+        return
+    }
+
+    before := media
+    var patched models.Media
+    if !applyPatch(c, media, &patched) {
+        return
+    }
+    if patched.URL == "" || patched.Type == "" {
+        unprocessable(c, "URL and type are required")
+        return
+    }
+    media.URL = patched.URL
+    media.Type = patched.Type
+
+    // The update only applies to the version read above.
+    media.Version = before.Version + 1
+    tx := db.Begin()
+    result := tx.Select("*").Where("version = ?", before.Version).Save(&media)
+    if result.Error != nil {
+        tx.Rollback()
+        c.JSON(http.StatusInternalServerError, utils.HTTPError{
+            Code:    http.StatusInternalServerError,
+            Message: result.Error.Error(),
+        })
+        return
+    }
+    if result.RowsAffected == 0 {
+        tx.Rollback()
+        var current models.Media
+        preconditionFailed(c, db, media.ID, &current, func() uint { return current.Version }, "Media not found")
+        return
+    }
+    if err := recordAudit(c, tx, models.AuditActionUpdate, models.ResourceMedia, media.ID, before, media); err != nil {
+        tx.Rollback()
+        c.JSON(http.StatusInternalServerError, utils.HTTPError{
+            Code:    http.StatusInternalServerError,
+            Message: err.Error(),
+        })
+        return
+    }
+    if err := recordEvents(c, tx, models.ResourceMedia, media.ID, media, models.EventMediaUpdated); err != nil {
+        tx.Rollback()
+        c.JSON(http.StatusInternalServerError, utils.HTTPError{
+            Code:    http.StatusInternalServerError,
+            Message: err.Error(),
+        })
+        return
+    }
+    tx.Commit()
+    notifyCommitted(c)
+    respondVersioned(c, http.StatusOK, media.Version, media)
+}
+
 func DeleteMedia(c *gin.Context) {
     db := c.MustGet("db").(*gorm.DB)
     
@@ -116,9 +221,36 @@ func DeleteMedia(c *gin.Context) {
         }
         return
     }
+    version, ok := queryVersion(c)
+    if !ok || !checkPrecondition(c, media.Version, version, media) {
+        return
+    }
 
     tx := db.Begin()
-    if err := tx.Delete(&media).Error; err != nil {
+    result := tx.Where("version = ?", media.Version).Delete(&media)
+    if result.Error != nil {
+        tx.Rollback()
+        c.JSON(http.StatusInternalServerError, utils.HTTPError{
+            Code:    http.StatusInternalServerError,
+            Message: result.Error.Error(),
+        })
+        return
+    }
+    if result.RowsAffected == 0 {
+        tx.Rollback()
+        var current models.Media
+        preconditionFailed(c, db, media.ID, &current, func() uint { return current.Version }, "Media not found")
+        return
+    }
+    if err := recordAudit(c, tx, models.AuditActionDelete, models.ResourceMedia, media.ID, media, nil); err != nil {
+        tx.Rollback()
+        c.JSON(http.StatusInternalServerError, utils.HTTPError{
+            Code:    http.StatusInternalServerError,
+            Message: err.Error(),
+        })
+        return
+    }
+    if err := recordEvents(c, tx, models.ResourceMedia, media.ID, media, models.EventMediaDeleted); err != nil {
         tx.Rollback()
         c.JSON(http.StatusInternalServerError, utils.HTTPError{
             Code:    http.StatusInternalServerError,
@@ -127,6 +259,7 @@ func DeleteMedia(c *gin.Context) {
         return
     }
     tx.Commit()
+    notifyCommitted(c)
     c.JSON(http.StatusOK, utils.MessageResponse{
         Message: "Media deleted successfully",
     })
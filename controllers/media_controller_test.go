@@ -205,8 +205,10 @@ func TestCreateMedia(t *testing.T) {
 
 	mock.ExpectBegin()
 	mock.ExpectQuery(`INSERT INTO "media"`).
-		WithArgs("https://example.com/new-image.jpg", "image", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WithArgs(1, "https://example.com/new-image.jpg", "image", sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	expectAudit(mock, models.AuditActionCreate, models.ResourceMedia, 1)
+	expectOutbox(mock, 1, models.EventMediaCreated)
 	mock.ExpectCommit()
 
 	media := models.Media{
@@ -402,7 +404,7 @@ func TestCreateMediaDatabaseError(t *testing.T) {
 
 	mock.ExpectBegin()
 	mock.ExpectQuery(`INSERT INTO "media"`).
-		WithArgs("https://example.com/new-image.jpg", "image", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WithArgs(1, "https://example.com/new-image.jpg", "image", sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
 		WillReturnError(gorm.ErrInvalidDB)
 	mock.ExpectRollback()
 
@@ -446,9 +448,11 @@ func TestDeleteMedia(t *testing.T) {
 
 	// Mock delete transaction
 	mock.ExpectBegin()
-	mock.ExpectExec(`DELETE FROM "media" WHERE "media"\."id" = \$1`).
-		WithArgs(1).
+	mock.ExpectExec(`DELETE FROM "media" WHERE version = \$1 AND "media"\."id" = \$2`).
+		WithArgs(sqlmock.AnyArg(), 1).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	expectAudit(mock, models.AuditActionDelete, models.ResourceMedia, 1)
+	expectOutbox(mock, 1, models.EventMediaDeleted)
 	mock.ExpectCommit()
 
 	router.DELETE("/media/:id", DeleteMedia)
@@ -540,8 +544,8 @@ func TestDeleteMediaDatabaseError(t *testing.T) {
 
 	// Mock delete transaction error
 	mock.ExpectBegin()
-	mock.ExpectExec(`DELETE FROM "media" WHERE "media"\."id" = \$1`).
-		WithArgs(1).
+	mock.ExpectExec(`DELETE FROM "media" WHERE version = \$1 AND "media"\."id" = \$2`).
+		WithArgs(sqlmock.AnyArg(), 1).
 		WillReturnError(gorm.ErrInvalidDB)
 	mock.ExpectRollback()
 
@@ -562,4 +566,41 @@ func TestDeleteMediaDatabaseError(t *testing.T) {
 	if response.Code != http.StatusInternalServerError {
 		t.Fatalf("Expected error code 500, but got %d", response.Code)
 	}
-}
\ No newline at end of file
+}
+
+func TestPatchMedia(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT \* FROM "media" WHERE "media"\."id" = \$1 ORDER BY "media"\."id" LIMIT \$2`).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "url", "type", "version"}).
+			AddRow(1, "https://example.com/old.jpg", "image", 1))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "media" SET "site_id"=\$1,"url"=\$2,"type"=\$3,"created_at"=\$4,"updated_at"=\$5,"version"=\$6 WHERE version = \$7 AND "id" = \$8`).
+		WithArgs(sqlmock.AnyArg(), "https://example.com/new.jpg", "image", sqlmock.AnyArg(), sqlmock.AnyArg(), 2, 1, 1).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	expectAudit(mock, models.AuditActionUpdate, models.ResourceMedia, 1)
+	expectOutbox(mock, 1, models.EventMediaUpdated)
+	mock.ExpectCommit()
+
+	router.PATCH("/media/:id", PatchMedia)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPatch, "/media/1", bytes.NewBufferString(`{"url":"https://example.com/new.jpg"}`))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	var response models.Media
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+	if response.URL != "https://example.com/new.jpg" || response.Version != 2 {
+		t.Fatalf("Expected the patched media at version 2, but got %+v", response)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("There were unfulfilled expectations: %s", err)
+	}
+}
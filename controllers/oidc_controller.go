@@ -0,0 +1,201 @@
+package controllers
+
+import (
+	"cms-backend/models"
+	"cms-backend/utils"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// oidcStateTTL is how long a user has to finish signing in at the provider.
+const oidcStateTTL = 10 * time.Minute
+
+// OIDCLogin starts a single sign-on login: it stores a fresh state, nonce
+// and PKCE verifier and redirects to the identity provider.
+func OIDCLogin(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	sso, ok := oidcClient(c)
+	if !ok {
+		return
+	}
+
+	state, nonce, verifier := utils.RandomToken(), utils.RandomToken(), utils.RandomToken()
+
+	tx := db.Begin()
+	if err := tx.Where("expires_at < ?", time.Now()).Delete(&models.OIDCState{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err := tx.Create(&models.OIDCState{
+		StateHash:    utils.HashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(oidcStateTTL),
+	}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	tx.Commit()
+	c.Redirect(http.StatusFound, sso.AuthCodeURL(state, nonce, verifier))
+}
+
+// OIDCCallback finishes a single sign-on login. It redeems the code, maps
+// the user's IdP groups to a role, provisions or links the user on first
+// login and answers with the same token pair as Login.
+func OIDCCallback(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	sso, ok := oidcClient(c)
+	if !ok {
+		return
+	}
+
+	if errorCode := c.Query("error"); errorCode != "" {
+		message := c.Query("error_description")
+		if message == "" {
+			message = errorCode
+		}
+		c.JSON(http.StatusUnauthorized, utils.HTTPError{
+			Code:    http.StatusUnauthorized,
+			Message: "Single sign-on failed: " + message,
+		})
+		return
+	}
+	code, state := c.Query("code"), c.Query("state")
+	if code == "" || state == "" {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: "code and state are required",
+		})
+		return
+	}
+
+	// The state is single use: it is deleted before the code is redeemed.
+	var pending models.OIDCState
+	if err := db.Where("state_hash = ?", utils.HashToken(state)).First(&pending).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			invalidOIDCState(c)
+		} else {
+			c.JSON(http.StatusInternalServerError, utils.HTTPError{
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
+			})
+		}
+		return
+	}
+	result := db.Where("state_hash = ?", pending.StateHash).Delete(&models.OIDCState{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: result.Error.Error(),
+		})
+		return
+	}
+	if result.RowsAffected == 0 || time.Now().After(pending.ExpiresAt) {
+		invalidOIDCState(c)
+		return
+	}
+
+	identity, err := sso.Exchange(c.Request.Context(), code, pending.CodeVerifier, pending.Nonce)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, utils.HTTPError{
+			Code:    http.StatusUnauthorized,
+			Message: "Single sign-on failed: " + err.Error(),
+		})
+		return
+	}
+
+	tx := db.Begin()
+	user, status, err := provisionOIDCUser(tx, identity, sso.Role(identity.Groups))
+	if err != nil {
+		tx.Rollback()
+		c.JSON(status, utils.HTTPError{
+			Code:    status,
+			Message: err.Error(),
+		})
+		return
+	}
+	tokens, err := issueTokens(tx, user.ID, utils.RandomToken())
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	tx.Commit()
+	c.JSON(http.StatusOK, tokens)
+}
+
+// provisionOIDCUser finds the user for an identity, linking an existing
+// account by verified email or creating one just in time. The role follows
+// the IdP groups on every login.
+func provisionOIDCUser(tx *gorm.DB, identity utils.OIDCIdentity, role string) (models.User, int, error) {
+	email := strings.ToLower(strings.TrimSpace(identity.Email))
+
+	var user models.User
+	err := tx.Where("oidc_subject = ?", identity.Subject).First(&user).Error
+	if err == gorm.ErrRecordNotFound {
+		err = tx.Where("email = ?", email).First(&user).Error
+		if err == nil && !identity.EmailVerified {
+			return user, http.StatusConflict, errors.New("An account with this email already exists")
+		}
+	}
+
+	switch err {
+	case nil:
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"role":         role,
+			"oidc_subject": identity.Subject,
+		}).Error; err != nil {
+			return user, http.StatusInternalServerError, err
+		}
+	case gorm.ErrRecordNotFound:
+		subject := identity.Subject
+		user = models.User{
+			Email:       email,
+			Name:        identity.Name,
+			Role:        role,
+			OIDCSubject: &subject,
+		}
+		if err := tx.Create(&user).Error; err != nil {
+			return user, http.StatusInternalServerError, err
+		}
+	default:
+		return user, http.StatusInternalServerError, err
+	}
+	return user, http.StatusOK, nil
+}
+
+func invalidOIDCState(c *gin.Context) {
+	c.JSON(http.StatusBadRequest, utils.HTTPError{
+		Code:    http.StatusBadRequest,
+		Message: "Invalid or expired login state",
+	})
+}
+
+// oidcClient returns the single sign-on client, answering 404 when single
+// sign-on is not configured.
+func oidcClient(c *gin.Context) (*utils.OIDCClient, bool) {
+	if value, ok := c.Get("oidc"); ok {
+		return value.(*utils.OIDCClient), true
+	}
+	c.JSON(http.StatusNotFound, utils.HTTPError{
+		Code:    http.StatusNotFound,
+		Message: "Single sign-on is not configured",
+	})
+	return nil, false
+}
@@ -0,0 +1,240 @@
+package controllers
+
+import (
+	"cms-backend/models"
+	"cms-backend/utils"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+)
+
+const oidcCallbackURL = "http://cms.test/api/v1/auth/oidc/callback"
+
+// captureArg matches any argument and remembers it.
+type captureArg struct{ value *string }
+
+func (a captureArg) Match(v driver.Value) bool {
+	s, ok := v.(string)
+	*a.value = s
+	return ok
+}
+
+func setupOIDC(t *testing.T, mapping map[string]string) (*gin.Engine, sqlmock.Sqlmock, *utils.MockOIDCProvider) {
+	provider := utils.NewMockOIDCProvider(t)
+	config := provider.Config(oidcCallbackURL)
+	config.RoleMapping = mapping
+	sso, err := utils.NewOIDCClient(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router, _, mock := utils.SetupRouterAndMockDBAs(t, nil)
+	router.Use(func(c *gin.Context) { c.Set("oidc", sso) })
+	router.GET("/auth/oidc/login", OIDCLogin)
+	router.GET("/auth/oidc/callback", OIDCCallback)
+	return router, mock, provider
+}
+
+// startOIDCLogin runs OIDCLogin and returns the stored nonce and verifier
+// and the provider's redirect back to the callback.
+func startOIDCLogin(t *testing.T, router *gin.Engine, mock sqlmock.Sqlmock, provider *utils.MockOIDCProvider) (nonce, verifier string, callback url.Values) {
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM "oidc_states" WHERE expires_at < \$1`).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO "oidc_states" \("state_hash","nonce","code_verifier","expires_at"\)`).
+		WithArgs(sqlmock.AnyArg(), captureArg{&nonce}, captureArg{&verifier}, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/auth/oidc/login", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusFound {
+		t.Fatalf("Expected status 302, but got %d: %s", w.Code, w.Body.String())
+	}
+	callback, err := provider.Authorize(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return nonce, verifier, callback
+}
+
+func expectOIDCState(mock sqlmock.Sqlmock, state, nonce, verifier string) {
+	hash := utils.HashToken(state)
+	mock.ExpectQuery(`SELECT \* FROM "oidc_states" WHERE state_hash = \$1`).
+		WithArgs(hash, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"state_hash", "nonce", "code_verifier", "expires_at"}).
+			AddRow(hash, nonce, verifier, time.Now().Add(time.Minute)))
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM "oidc_states" WHERE state_hash = \$1`).
+		WithArgs(hash).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+}
+
+func TestOIDCLoginRedirectsWithPKCE(t *testing.T) {
+	router, mock, provider := setupOIDC(t, nil)
+	defer mock.ExpectClose()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM "oidc_states"`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO "oidc_states"`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/auth/oidc/login", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusFound {
+		t.Fatalf("Expected status 302, but got %d", w.Code)
+	}
+	location, _ := url.Parse(w.Header().Get("Location"))
+	q := location.Query()
+	if !strings.HasPrefix(location.String(), provider.Server.URL+"/authorize") {
+		t.Fatalf("Expected a redirect to the provider, but got %s", location)
+	}
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" || q.Get("state") == "" || q.Get("nonce") == "" {
+		t.Fatalf("Expected state, nonce and an S256 challenge, but got %s", q.Encode())
+	}
+	if q.Get("redirect_uri") != oidcCallbackURL || !strings.Contains(q.Get("scope"), "openid") {
+		t.Fatalf("Expected the callback and openid scope, but got %s", q.Encode())
+	}
+}
+
+func TestOIDCCallbackProvisionsUser(t *testing.T) {
+	router, mock, provider := setupOIDC(t, map[string]string{"cms-editors": models.UserRoleEditor, "staff": models.UserRoleAuthor})
+	defer mock.ExpectClose()
+	provider.Claims["groups"] = []string{"staff", "cms-editors"}
+
+	nonce, verifier, callback := startOIDCLogin(t, router, mock, provider)
+	expectOIDCState(mock, callback.Get("state"), nonce, verifier)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE oidc_subject = \$1`).
+		WithArgs("mock-user", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE email = \$1`).
+		WithArgs("sso@example.com", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`INSERT INTO "users" \("email","name","password_hash","role","oidc_subject","created_at","updated_at"\)`).
+		WithArgs("sso@example.com", "SSO User", "", models.UserRoleEditor, "mock-user", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+	mock.ExpectQuery(`INSERT INTO "refresh_tokens"`).
+		WithArgs(9, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), nil, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/auth/oidc/callback?"+callback.Encode(), nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	var response models.TokenResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+	claims, err := utils.ParseAccessToken(response.AccessToken)
+	if err != nil {
+		t.Fatalf("Expected a valid access token, but got %v", err)
+	}
+	if id, _ := claims.UserID(); id != 9 {
+		t.Fatalf("Expected subject 9, but got %d", id)
+	}
+}
+
+func TestOIDCCallbackSyncsRoleOfLinkedUser(t *testing.T) {
+	router, mock, provider := setupOIDC(t, map[string]string{"cms-admins": models.UserRoleAdmin})
+	defer mock.ExpectClose()
+
+	nonce, verifier, callback := startOIDCLogin(t, router, mock, provider)
+	expectOIDCState(mock, callback.Get("state"), nonce, verifier)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE oidc_subject = \$1`).
+		WithArgs("mock-user", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "role", "oidc_subject"}).AddRow(4, "sso@example.com", models.UserRoleAdmin, "mock-user"))
+	mock.ExpectExec(`UPDATE "users" SET "oidc_subject"=\$1,"role"=\$2,"updated_at"=\$3 WHERE "id" = \$4`).
+		WithArgs("mock-user", models.UserRoleViewer, sqlmock.AnyArg(), 4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO "refresh_tokens"`).
+		WithArgs(4, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), nil, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/auth/oidc/callback?"+callback.Encode(), nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestOIDCCallbackRejectsUnverifiedEmailOfExistingUser(t *testing.T) {
+	router, mock, provider := setupOIDC(t, nil)
+	defer mock.ExpectClose()
+	provider.Claims["email_verified"] = false
+
+	nonce, verifier, callback := startOIDCLogin(t, router, mock, provider)
+	expectOIDCState(mock, callback.Get("state"), nonce, verifier)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE oidc_subject = \$1`).
+		WithArgs("mock-user", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE email = \$1`).
+		WithArgs("sso@example.com", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(1, "sso@example.com"))
+	mock.ExpectRollback()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/auth/oidc/callback?"+callback.Encode(), nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("Expected status 409, but got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestOIDCCallbackRejectsInvalidState(t *testing.T) {
+	router, mock, _ := setupOIDC(t, nil)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT \* FROM "oidc_states"`).
+		WithArgs(utils.HashToken("forged"), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"state_hash"}))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/auth/oidc/callback?code=abc&state=forged", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, but got %d", w.Code)
+	}
+}
+
+func TestOIDCCallbackRejectsWrongVerifier(t *testing.T) {
+	router, mock, provider := setupOIDC(t, nil)
+	defer mock.ExpectClose()
+
+	nonce, _, callback := startOIDCLogin(t, router, mock, provider)
+	expectOIDCState(mock, callback.Get("state"), nonce, utils.RandomToken())
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/auth/oidc/callback?"+callback.Encode(), nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected status 401, but got %d: %s", w.Code, w.Body.String())
+	}
+}
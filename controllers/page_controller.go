@@ -14,13 +14,31 @@ func GetPages(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
 	var pages []models.Page
 
-	if err := db.Find(&pages).Error; err != nil {
+	query, err := applyFieldFilters(db, "pages.fields", c.Request.URL.Query())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := query.Find(&pages).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, utils.HTTPError{
 			Code:    http.StatusInternalServerError,
 			Message: err.Error(),
 		})
 		return
 	}
+	for i := range pages {
+		if err := presentHTML(c, false, pages[i].ContentFormat, &pages[i].Content, &pages[i].ContentHTML); err != nil {
+			c.JSON(http.StatusInternalServerError, utils.HTTPError{
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
+			})
+			return
+		}
+	}
 	c.JSON(http.StatusOK, pages)
 }
 
@@ -52,6 +70,32 @@ func GetPage(c *gin.Context) {
 		}
 		return
 	}
+
+	setLastModified(c, page.UpdatedAt)
+	c.Header("ETag", utils.VersionETag(page.Version, nil))
+	if page.Lock, err = shownLock(c, db, models.ResourcePage, page.ID, can(c, models.PermWritePages)); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	locale, err := localize(c, db, models.ResourcePage, page.ID, &page.Title, &page.Content)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	page.Locale = locale
+	if err := presentHTML(c, locale != "" && locale != utils.SourceLocale(), page.ContentFormat, &page.Content, &page.ContentHTML); err != nil {
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
 	c.JSON(http.StatusOK, page)
 }
 func CreatePage(c *gin.Context) {
@@ -66,6 +110,44 @@ func CreatePage(c *gin.Context) {
 		return
 	}
 
+	if page.Blocks.IsNull() {
+		page.Blocks = nil
+	} else {
+		content, status, err := projectBlocks(db, page.Blocks)
+		if err != nil {
+			c.JSON(status, utils.HTTPError{
+				Code:    status,
+				Message: err.Error(),
+			})
+			return
+		}
+		page.Content = content
+	}
+	if page.Content == "" {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: "Content or blocks are required",
+		})
+		return
+	}
+
+	if err := validateFields(models.ResourcePage, page.Fields); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := prepareContentFormat(&page.ContentFormat, page.Blocks != nil, page.Content, &page.ContentHTML); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	page.Version = 1
 	tx := db.Begin()
 	if err := tx.Create(&page).Error; err != nil {
 		tx.Rollback()
@@ -75,7 +157,25 @@ func CreatePage(c *gin.Context) {
 		})
 		return
 	}
+	if err := recordAudit(c, tx, models.AuditActionCreate, models.ResourcePage, page.ID, nil, page); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err := recordEvents(c, tx, models.ResourcePage, page.ID, page, models.EventPageCreated); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
 	tx.Commit()
+	notifyCommitted(c)
+	indexPage(c, page)
 	c.JSON(http.StatusCreated, page)
 }
 
@@ -107,7 +207,11 @@ func UpdatePage(c *gin.Context) {
 		}
 		return
 	}
+	if !checkEditLock(c, db, models.ResourcePage, page.ID) {
+		return
+	}
 
+	before := page
 	var updateData models.Page
 	if err := c.ShouldBindJSON(&updateData); err != nil {
 		c.JSON(http.StatusBadRequest, utils.HTTPError{
@@ -116,12 +220,157 @@ func UpdatePage(c *gin.Context) {
 		})
 		return
 	}
+	if !checkPrecondition(c, page.Version, updateData.Version, before) {
+		return
+	}
+
+	if updateData.Blocks.IsNull() {
+		updateData.Blocks = nil
+	} else {
+		content, status, err := projectBlocks(db, updateData.Blocks)
+		if err != nil {
+			c.JSON(status, utils.HTTPError{
+				Code:    status,
+				Message: err.Error(),
+			})
+			return
+		}
+		updateData.Content = content
+	}
+	if updateData.Content == "" {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: "Content or blocks are required",
+		})
+		return
+	}
 
 	page.Title = updateData.Title
 	page.Content = updateData.Content
+	page.Blocks = updateData.Blocks
+	if updateData.ContentFormat != "" {
+		page.ContentFormat = updateData.ContentFormat
+	}
+	if err := prepareContentFormat(&page.ContentFormat, page.Blocks != nil, page.Content, &page.ContentHTML); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	if updateData.Fields != nil {
+		if err := validateFields(models.ResourcePage, updateData.Fields); err != nil {
+			c.JSON(http.StatusBadRequest, utils.HTTPError{
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			})
+			return
+		}
+		page.Fields = updateData.Fields
+	}
+
+	savePage(c, db, before, page)
+}
+
+// PatchPage applies a JSON Merge Patch or a JSON Patch to a page and checks
+// the result as an update would.
+func PatchPage(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	idParam := c.Param("id")
+	id, err := strconv.ParseUint(idParam, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid page ID",
+		})
+		return
+	}
+
+	var page models.Page
+	if err := db.First(&page, uint(id)).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, utils.HTTPError{
+				Code:    http.StatusNotFound,
+				Message: "Page not found",
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, utils.HTTPError{
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
+			})
+		}
+		return
+	}
+	if !checkEditLock(c, db, models.ResourcePage, page.ID) {
+		return
+	}
+	if !checkPrecondition(c, page.Version, 0, page) {
+		return
+	}
+
+	before := page
+	var patched models.Page
+	if !applyPatch(c, page, &patched) {
+		return
+	}
+	if patched.Title == "" {
+		unprocessable(c, "Title is required")
+		return
+	}
+	if !patchBlocks(c, db, before.Blocks, before.Content, &patched.Blocks, &patched.Content) {
+		return
+	}
+	if err := validateFields(models.ResourcePage, patched.Fields); err != nil {
+		unprocessable(c, err.Error())
+		return
+	}
+	if patched.Fields.IsNull() {
+		patched.Fields = nil
+	}
+
+	page.Title = patched.Title
+	page.Content = patched.Content
+	page.Blocks = patched.Blocks
+	page.Fields = patched.Fields
+	page.ContentFormat = patched.ContentFormat
+	if err := prepareContentFormat(&page.ContentFormat, page.Blocks != nil, page.Content, &page.ContentHTML); err != nil {
+		unprocessable(c, err.Error())
+		return
+	}
+	savePage(c, db, before, page)
+}
 
+// savePage writes an update of a page, as long as it is still at the version
+// before was read at.
+func savePage(c *gin.Context, db *gorm.DB, before, page models.Page) {
+	// The update only applies to the version read above.
+	page.Version = before.Version + 1
 	tx := db.Begin()
-	if err := tx.Save(&page).Error; err != nil {
+	result := tx.Select("*").Where("version = ?", before.Version).Save(&page)
+	if result.Error != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: result.Error.Error(),
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		var current models.Page
+		preconditionFailed(c, db, page.ID, &current, func() uint { return current.Version }, "Page not found")
+		return
+	}
+	if err := recordAudit(c, tx, models.AuditActionUpdate, models.ResourcePage, page.ID, before, page); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err := recordEvents(c, tx, models.ResourcePage, page.ID, page, models.EventPageUpdated); err != nil {
 		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, utils.HTTPError{
 			Code:    http.StatusInternalServerError,
@@ -130,7 +379,9 @@ func UpdatePage(c *gin.Context) {
 		return
 	}
 	tx.Commit()
-	c.JSON(http.StatusOK, page)
+	notifyCommitted(c)
+	indexPage(c, page)
+	respondVersioned(c, http.StatusOK, page.Version, page)
 }
 
 func DeletePage(c *gin.Context) {
@@ -161,9 +412,47 @@ func DeletePage(c *gin.Context) {
 		}
 		return
 	}
+	if !checkEditLock(c, db, models.ResourcePage, page.ID) {
+		return
+	}
+	version, ok := queryVersion(c)
+	if !ok || !checkPrecondition(c, page.Version, version, page) {
+		return
+	}
 
 	tx := db.Begin()
-	if err := tx.Delete(&page).Error; err != nil {
+	result := tx.Where("version = ?", page.Version).Delete(&page)
+	if result.Error != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: result.Error.Error(),
+		})
+		return
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		var current models.Page
+		preconditionFailed(c, db, page.ID, &current, func() uint { return current.Version }, "Page not found")
+		return
+	}
+	if err := recordAudit(c, tx, models.AuditActionDelete, models.ResourcePage, page.ID, page, nil); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err := deleteTranslations(c, tx, models.ResourcePage, page.ID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err := recordEvents(c, tx, models.ResourcePage, page.ID, page, models.EventPageDeleted); err != nil {
 		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, utils.HTTPError{
 			Code:    http.StatusInternalServerError,
@@ -172,6 +461,8 @@ func DeletePage(c *gin.Context) {
 		return
 	}
 	tx.Commit()
+	notifyCommitted(c)
+	unindex(c, models.ResourcePage, page.ID)
 	c.JSON(http.StatusOK, utils.MessageResponse{
 		Message: "Page deleted successfully",
 	})
@@ -4,13 +4,16 @@ import (
 	"bytes"
 	"cms-backend/models"
 	"cms-backend/utils"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
@@ -52,6 +55,7 @@ func TestGetPage(t *testing.T) {
 	mock.ExpectQuery(`SELECT \* FROM "pages" WHERE "pages"\."id" = \$1 ORDER BY "pages"\."id" LIMIT \$2`).
 		WithArgs(1, 1).
 		WillReturnRows(rows)
+	expectNoLock(mock)
 
 	router.GET("/pages/:id", GetPage)
 	w := httptest.NewRecorder()
@@ -74,14 +78,54 @@ func TestGetPage(t *testing.T) {
 	}
 }
 
+func TestGetPageSanitizesHTMLContent(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDBAs(t, nil)
+	defer mock.ExpectClose()
+
+	content := `<p>Hello</p><script>alert(1)</script>`
+	for range 2 {
+		mock.ExpectQuery(`SELECT \* FROM "pages" WHERE "pages"\."id" = \$1`).
+			WithArgs(1, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "title", "content", "content_format", "content_html"}).
+				AddRow(1, "Test Page", content, "html", "<p>Hello</p>"))
+	}
+	mock.ExpectQuery(`SELECT "id","updated_at","content_format" FROM "pages" WHERE id = \$1`).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "updated_at", "content_format"}).AddRow(1, time.Now(), "html"))
+	mock.ExpectQuery(`SELECT \* FROM "translations" WHERE resource_type = \$1 AND resource_id = \$2 ORDER BY locale`).
+		WithArgs("page", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "resource_type", "resource_id", "locale", "title", "content"}).
+			AddRow(1, "page", 1, "fr", "Page", `<p>Bonjour</p><script>alert(1)</script>`))
+
+	router.GET("/pages/:id", GetPage)
+	router.GET("/pages/:id/translations", GetPageTranslations)
+	for _, path := range []string{"/pages/1", "/pages/1?render=html", "/pages/1/translations"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 for %s, but got %d: %s", path, w.Code, w.Body.String())
+		}
+		if strings.Contains(w.Body.String(), "script") {
+			t.Fatalf("Expected the script stripped from %s, but got %s", path, w.Body.String())
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestCreatePage(t *testing.T) {
 	router, _, mock := utils.SetupRouterAndMockDB(t)
 	defer mock.ExpectClose()
 
 	mock.ExpectBegin()
 	mock.ExpectQuery(`INSERT INTO "pages"`).
-		WithArgs("New Page", "New Content", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WithArgs(1, "New Page", "New Content", sqlmock.AnyArg(), sqlmock.AnyArg(), nil, nil, "plain", "<p>New Content</p>\n", 1).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	expectAudit(mock, models.AuditActionCreate, models.ResourcePage, 1)
+	expectOutbox(mock, 1, models.EventPageCreated)
 	mock.ExpectCommit()
 
 	page := models.Page{
@@ -114,18 +158,21 @@ func TestUpdatePage(t *testing.T) {
 	defer mock.ExpectClose()
 
 	// Mock finding existing page
-	rows := sqlmock.NewRows([]string{"id", "title", "content", "created_at", "updated_at"}).
-		AddRow(1, "Old Title", "Old Content", time.Now(), time.Now())
+	rows := sqlmock.NewRows([]string{"id", "title", "content", "created_at", "updated_at", "version"}).
+		AddRow(1, "Old Title", "Old Content", time.Now(), time.Now(), 3)
 
 	mock.ExpectQuery(`SELECT \* FROM "pages" WHERE "pages"\."id" = \$1 ORDER BY "pages"\."id" LIMIT \$2`).
 		WithArgs(1, 1).
 		WillReturnRows(rows)
+	expectNoLock(mock)
 
 	// Mock update transaction
 	mock.ExpectBegin()
-	mock.ExpectExec(`UPDATE "pages" SET "title"=\$1,"content"=\$2,"created_at"=\$3,"updated_at"=\$4 WHERE "id" = \$5`).
-		WithArgs("Updated Title", "Updated Content", sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
+	mock.ExpectExec(`UPDATE "pages" SET "site_id"=\$1,"title"=\$2,"content"=\$3,"created_at"=\$4,"updated_at"=\$5,"fields"=\$6,"blocks"=\$7,"content_format"=\$8,"content_html"=\$9,"version"=\$10 WHERE version = \$11 AND "id" = \$12`).
+		WithArgs(sqlmock.AnyArg(), "Updated Title", "Updated Content", sqlmock.AnyArg(), sqlmock.AnyArg(), nil, nil, "plain", "<p>Updated Content</p>\n", 4, 3, 1).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	expectAudit(mock, models.AuditActionUpdate, models.ResourcePage, 1)
+	expectOutbox(mock, 1, models.EventPageUpdated)
 	mock.ExpectCommit()
 
 	updateData := models.Page{
@@ -151,6 +198,12 @@ func TestUpdatePage(t *testing.T) {
 	if response.Title != "Updated Title" {
 		t.Fatalf("Expected title 'Updated Title', but got '%s'", response.Title)
 	}
+	if response.Version != 4 {
+		t.Fatalf("Expected version 4, but got %d", response.Version)
+	}
+	if version, ok := utils.ETagVersion(w.Header().Get("ETag")); !ok || version != 4 {
+		t.Fatalf("Expected an ETag for version 4, but got %q", w.Header().Get("ETag"))
+	}
 }
 
 func TestDeletePage(t *testing.T) {
@@ -164,12 +217,16 @@ func TestDeletePage(t *testing.T) {
 	mock.ExpectQuery(`SELECT \* FROM "pages" WHERE "pages"\."id" = \$1 ORDER BY "pages"\."id" LIMIT \$2`).
 		WithArgs(1, 1).
 		WillReturnRows(rows)
+	expectNoLock(mock)
 
 	// Mock delete transaction
 	mock.ExpectBegin()
-	mock.ExpectExec(`DELETE FROM "pages" WHERE "pages"\."id" = \$1`).
-		WithArgs(1).
+	mock.ExpectExec(`DELETE FROM "pages" WHERE version = \$1 AND "pages"\."id" = \$2`).
+		WithArgs(sqlmock.AnyArg(), 1).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	expectAudit(mock, models.AuditActionDelete, models.ResourcePage, 1)
+	expectTranslationsDeleted(mock, models.ResourcePage, 1)
+	expectOutbox(mock, 1, models.EventPageDeleted)
 	mock.ExpectCommit()
 
 	router.DELETE("/pages/:id", DeletePage)
@@ -190,6 +247,58 @@ func TestDeletePage(t *testing.T) {
 	}
 }
 
+func TestDeletePageInvalidatesCache(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	cache := utils.NewResponseCache(utils.NewMemoryCacheStore(1<<20), func() time.Duration { return time.Minute })
+	router.Use(func(c *gin.Context) {
+		c.Set("cache", cache)
+		c.Set("site", models.Site{ID: 1})
+	})
+	ctx := context.Background()
+	cached := func(id uint) bool {
+		key, response, err := cache.Lookup(ctx, "page", []string{utils.CacheScope(1, models.ResourcePage, id)})
+		if err != nil {
+			t.Fatalf("Failed to read cache: %v", err)
+		}
+		if response == nil {
+			cache.Store(ctx, key, utils.CachedResponse{Status: http.StatusOK})
+		}
+		return response != nil
+	}
+	cached(1)
+	cached(2)
+
+	mock.ExpectQuery(`SELECT \* FROM "pages" WHERE "pages"\."id" = \$1`).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title"}).AddRow(1, "Test Page"))
+	expectNoLock(mock)
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM "pages" WHERE version = \$1 AND "pages"\."id" = \$2`).
+		WithArgs(sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	expectAudit(mock, models.AuditActionDelete, models.ResourcePage, 1)
+	expectTranslationsDeleted(mock, models.ResourcePage, 1)
+	expectOutbox(mock, 1, models.EventPageDeleted)
+	mock.ExpectCommit()
+
+	router.DELETE("/pages/:id", DeletePage)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodDelete, "/pages/1", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	if cached(1) {
+		t.Fatalf("Expected the deleted page's cached responses to be dropped")
+	}
+	if !cached(2) {
+		t.Fatalf("Expected other pages to stay cached")
+	}
+}
+
 // Error condition tests
 
 func TestGetPagesDatabaseError(t *testing.T) {
@@ -330,7 +439,7 @@ func TestCreatePageDatabaseError(t *testing.T) {
 
 	mock.ExpectBegin()
 	mock.ExpectQuery(`INSERT INTO "pages"`).
-		WithArgs("New Page", "New Content", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WithArgs(1, "New Page", "New Content", sqlmock.AnyArg(), sqlmock.AnyArg(), nil, nil, "plain", "<p>New Content</p>\n", 1).
 		WillReturnError(gorm.ErrInvalidDB)
 	mock.ExpectRollback()
 
@@ -441,6 +550,7 @@ func TestUpdatePageInvalidJSON(t *testing.T) {
 	mock.ExpectQuery(`SELECT \* FROM "pages" WHERE "pages"\."id" = \$1 ORDER BY "pages"\."id" LIMIT \$2`).
 		WithArgs(1, 1).
 		WillReturnRows(rows)
+	expectNoLock(mock)
 
 	router.PUT("/pages/:id", UpdatePage)
 	w := httptest.NewRecorder()
@@ -476,8 +586,8 @@ func TestUpdatePageDatabaseError(t *testing.T) {
 
 	// Mock update transaction error
 	mock.ExpectBegin()
-	mock.ExpectExec(`UPDATE "pages" SET "title"=\$1,"content"=\$2,"created_at"=\$3,"updated_at"=\$4 WHERE "id" = \$5`).
-		WithArgs("Updated Title", "Updated Content", sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
+	mock.ExpectExec(`UPDATE "pages" SET "site_id"=\$1,"title"=\$2,"content"=\$3,"created_at"=\$4,"updated_at"=\$5,"fields"=\$6,"blocks"=\$7,"content_format"=\$8,"content_html"=\$9,"version"=\$10 WHERE version = \$11 AND "id" = \$12`).
+		WithArgs(sqlmock.AnyArg(), "Updated Title", "Updated Content", sqlmock.AnyArg(), sqlmock.AnyArg(), nil, nil, "plain", "<p>Updated Content</p>\n", 1, sqlmock.AnyArg(), 1).
 		WillReturnError(gorm.ErrInvalidDB)
 	mock.ExpectRollback()
 
@@ -577,8 +687,8 @@ func TestDeletePageDatabaseError(t *testing.T) {
 
 	// Mock delete transaction error
 	mock.ExpectBegin()
-	mock.ExpectExec(`DELETE FROM "pages" WHERE "pages"\."id" = \$1`).
-		WithArgs(1).
+	mock.ExpectExec(`DELETE FROM "pages" WHERE version = \$1 AND "pages"\."id" = \$2`).
+		WithArgs(sqlmock.AnyArg(), 1).
 		WillReturnError(gorm.ErrInvalidDB)
 	mock.ExpectRollback()
 
@@ -600,3 +710,267 @@ func TestDeletePageDatabaseError(t *testing.T) {
 		t.Fatalf("Expected error code 500, but got %d", response.Code)
 	}
 }
+
+func TestGetPagesWithFieldFilters(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT \* FROM "pages" WHERE pages\.fields @@ \$1::jsonpath AND pages\.fields @@ \$2::jsonpath`).
+		WithArgs(`exists($."cta_link")`, `$."featured" == true`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "content", "created_at", "updated_at", "fields"}).
+			AddRow(1, "Landing", "Content", time.Now(), time.Now(), `{"cta_link":"/signup","featured":true}`))
+
+	router.GET("/pages", GetPages)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/pages?fields.cta_link[exists]=true&fields.featured=true", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d", w.Code)
+	}
+
+	var response []models.Page
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+	if len(response) != 1 || string(response[0].Fields) != `{"cta_link":"/signup","featured":true}` {
+		t.Fatalf("Expected the landing page with its fields, but got %+v", response)
+	}
+}
+
+func TestCreatePageFieldsNotObject(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	router.POST("/pages", CreatePage)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/pages", bytes.NewBufferString(`{"title":"Page","content":"Content","fields":[1,2]}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, but got %d", w.Code)
+	}
+}
+
+func TestCreatePageInvalidBlocks(t *testing.T) {
+	cases := map[string]string{
+		"unknown type":     `[{"type":"carousel"}]`,
+		"unknown property": `[{"type":"paragraph","text":"Hi","color":"red"}]`,
+		"bad heading":      `[{"type":"heading","text":"Hi","level":9}]`,
+		"bad embed":        `[{"type":"embed","url":"javascript:alert(1)"}]`,
+		"not an array":     `{"type":"paragraph","text":"Hi"}`,
+	}
+	for name, blocks := range cases {
+		t.Run(name, func(t *testing.T) {
+			router, _, mock := utils.SetupRouterAndMockDB(t)
+			defer mock.ExpectClose()
+
+			router.POST("/pages", CreatePage)
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodPost, "/pages", bytes.NewBufferString(`{"title":"Page","blocks":`+blocks+`}`))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("Expected status 400, but got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestCreatePageMissingContent(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	router.POST("/pages", CreatePage)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/pages", bytes.NewBufferString(`{"title":"Page"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, but got %d", w.Code)
+	}
+}
+
+func TestUpdatePageStaleIfMatch(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT \* FROM "pages" WHERE "pages"\."id" = \$1 ORDER BY "pages"\."id" LIMIT \$2`).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "content", "version"}).
+			AddRow(1, "Current Title", "Current Content", 3))
+	expectNoLock(mock)
+
+	router.PUT("/pages/:id", UpdatePage)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPut, "/pages/1", bytes.NewBufferString(`{"title":"Updated Title","content":"Updated Content"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", `"v2"`)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("Expected status 412, but got %d: %s", w.Code, w.Body.String())
+	}
+	var response models.Page
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+	if response.Title != "Current Title" || response.Version != 3 {
+		t.Fatalf("Expected the current page at version 3, but got %+v", response)
+	}
+	if version, ok := utils.ETagVersion(w.Header().Get("ETag")); !ok || version != 3 {
+		t.Fatalf("Expected an ETag for version 3, but got %q", w.Header().Get("ETag"))
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUpdatePageLostRace(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT \* FROM "pages" WHERE "pages"\."id" = \$1 ORDER BY "pages"\."id" LIMIT \$2`).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "content", "version"}).
+			AddRow(1, "Old Title", "Old Content", 3))
+	expectNoLock(mock)
+
+	// Another writer updates the page between the read and the write.
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "pages" SET .* WHERE version = \$11 AND "id" = \$12`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+	mock.ExpectQuery(`SELECT \* FROM "pages" WHERE "pages"\."id" = \$1 ORDER BY "pages"\."id" LIMIT \$2`).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "content", "version"}).
+			AddRow(1, "Their Title", "Their Content", 4))
+
+	router.PUT("/pages/:id", UpdatePage)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPut, "/pages/1", bytes.NewBufferString(`{"title":"Updated Title","content":"Updated Content","version":3}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("Expected status 412, but got %d: %s", w.Code, w.Body.String())
+	}
+	var response models.Page
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+	if response.Title != "Their Title" || response.Version != 4 {
+		t.Fatalf("Expected the current page at version 4, but got %+v", response)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDeletePageRequiresIfMatch(t *testing.T) {
+	t.Setenv("REQUIRE_IF_MATCH", "true")
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT \* FROM "pages" WHERE "pages"\."id" = \$1 ORDER BY "pages"\."id" LIMIT \$2`).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "content", "version"}).
+			AddRow(1, "Test Page", "Test Content", 3))
+	expectNoLock(mock)
+
+	router.DELETE("/pages/:id", DeletePage)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodDelete, "/pages/1", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusPreconditionRequired {
+		t.Fatalf("Expected status 428, but got %d: %s", w.Code, w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestPatchPageMergePatch(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT \* FROM "pages" WHERE "pages"\."id" = \$1 ORDER BY "pages"\."id" LIMIT \$2`).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "content", "content_format", "fields", "version"}).
+			AddRow(1, "Old Title", "Old Content", "plain", `{"color":"red","size":3}`, 2))
+	expectNoLock(mock)
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "pages" SET "site_id"=\$1,"title"=\$2,"content"=\$3,"created_at"=\$4,"updated_at"=\$5,"fields"=\$6,"blocks"=\$7,"content_format"=\$8,"content_html"=\$9,"version"=\$10 WHERE version = \$11 AND "id" = \$12`).
+		WithArgs(sqlmock.AnyArg(), "Patched Title", "Old Content", sqlmock.AnyArg(), sqlmock.AnyArg(), `{"size":3}`, nil, "plain", "<p>Old Content</p>\n", 3, 2, 1).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	expectAudit(mock, models.AuditActionUpdate, models.ResourcePage, 1)
+	expectOutbox(mock, 1, models.EventPageUpdated)
+	mock.ExpectCommit()
+
+	router.PATCH("/pages/:id", PatchPage)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPatch, "/pages/1", bytes.NewBufferString(`{"title":"Patched Title","fields":{"color":null}}`))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	var response models.Page
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+	if response.Title != "Patched Title" || response.Content != "Old Content" || response.Version != 3 {
+		t.Fatalf("Expected the patched page at version 3, but got %+v", response)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestPatchPageRejected(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+	}{
+		{"unsupported content type", "application/json", `{"title":"Patched"}`, http.StatusUnsupportedMediaType},
+		{"malformed JSON patch", "application/json-patch+json", `{"op":"replace"}`, http.StatusBadRequest},
+		{"failed test", "application/json-patch+json", `[{"op":"test","path":"/title","value":"Other"},{"op":"replace","path":"/title","value":"Patched"}]`, http.StatusConflict},
+		{"missing path", "application/json-patch+json", `[{"op":"remove","path":"/missing"}]`, http.StatusUnprocessableEntity},
+		{"title removed", "application/merge-patch+json", `{"title":null}`, http.StatusUnprocessableEntity},
+		{"content cleared", "application/merge-patch+json", `{"content":""}`, http.StatusUnprocessableEntity},
+		{"wrong type", "application/merge-patch+json", `{"title":5}`, http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, _, mock := utils.SetupRouterAndMockDB(t)
+			defer mock.ExpectClose()
+
+			mock.ExpectQuery(`SELECT \* FROM "pages" WHERE "pages"\."id" = \$1 ORDER BY "pages"\."id" LIMIT \$2`).
+				WithArgs(1, 1).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "title", "content", "version"}).
+					AddRow(1, "Old Title", "Old Content", 2))
+			expectNoLock(mock)
+
+			router.PATCH("/pages/:id", PatchPage)
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodPatch, "/pages/1", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, but got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatalf("There were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"cms-backend/models"
+	"cms-backend/utils"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	mergePatchType = "application/merge-patch+json"
+	jsonPatchType  = "application/json-patch+json"
+)
+
+// applyPatch applies the request body to the JSON representation of current
+// and decodes the result into patched. The body is a JSON Merge Patch
+// (RFC 7396) or a JSON Patch (RFC 6902), told apart by its Content-Type.
+// On failure it writes the response itself: 415 for any other Content-Type,
+// 400 for a malformed patch, 409 when a JSON Patch test fails and 422 when
+// the patch cannot be applied or does not yield a valid resource.
+func applyPatch(c *gin.Context, current interface{}, patched interface{}) bool {
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if mediaType != mergePatchType && mediaType != jsonPatchType {
+		c.JSON(http.StatusUnsupportedMediaType, utils.HTTPError{
+			Code:    http.StatusUnsupportedMediaType,
+			Message: "Content-Type must be " + mergePatchType + " or " + jsonPatchType,
+		})
+		return false
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return false
+	}
+	doc, err := json.Marshal(current)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return false
+	}
+
+	var result []byte
+	if mediaType == mergePatchType {
+		if !json.Valid(body) {
+			c.JSON(http.StatusBadRequest, utils.HTTPError{
+				Code:    http.StatusBadRequest,
+				Message: "Invalid merge patch",
+			})
+			return false
+		}
+		result, err = jsonpatch.MergePatch(doc, body)
+	} else {
+		var patch jsonpatch.Patch
+		patch, err = jsonpatch.DecodePatch(body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, utils.HTTPError{
+				Code:    http.StatusBadRequest,
+				Message: "Invalid JSON patch: " + err.Error(),
+			})
+			return false
+		}
+		result, err = patch.Apply(doc)
+	}
+	if errors.Is(err, jsonpatch.ErrTestFailed) {
+		c.JSON(http.StatusConflict, utils.HTTPError{
+			Code:    http.StatusConflict,
+			Message: err.Error(),
+		})
+		return false
+	}
+	if err == nil {
+		err = json.Unmarshal(result, patched)
+	}
+	if err != nil {
+		unprocessable(c, err.Error())
+		return false
+	}
+	return true
+}
+
+// unprocessable answers a patch whose result is not a valid resource.
+func unprocessable(c *gin.Context, message string) {
+	c.JSON(http.StatusUnprocessableEntity, utils.HTTPError{
+		Code:    http.StatusUnprocessableEntity,
+		Message: message,
+	})
+}
+
+// sameJSON reports whether two documents hold the same JSON value, however
+// they are formatted.
+func sameJSON(a, b models.JSON) bool {
+	if a.IsNull() || b.IsNull() {
+		return a.IsNull() == b.IsNull()
+	}
+	var x, y interface{}
+	if json.Unmarshal(a, &x) != nil || json.Unmarshal(b, &y) != nil {
+		return false
+	}
+	return reflect.DeepEqual(x, y)
+}
+
+// patchBlocks reconciles the content and blocks of a patched page or post
+// the way a full update does: changed blocks are projected into the
+// content, and content changed on its own replaces the blocks.
+func patchBlocks(c *gin.Context, db *gorm.DB, beforeBlocks models.JSON, beforeContent string, blocks *models.JSON, content *string) bool {
+	switch {
+	case blocks.IsNull():
+		*blocks = nil
+	case !sameJSON(*blocks, beforeBlocks):
+		projected, status, err := projectBlocks(db, *blocks)
+		if err != nil {
+			if status == http.StatusBadRequest {
+				status = http.StatusUnprocessableEntity
+			}
+			c.JSON(status, utils.HTTPError{
+				Code:    status,
+				Message: err.Error(),
+			})
+			return false
+		}
+		*content = projected
+	case *content != beforeContent:
+		*blocks = nil
+	}
+	if *content == "" {
+		unprocessable(c, "Content or blocks are required")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"cms-backend/models"
+	"cms-backend/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// currentUser is the user Authenticate stored for the request, if any.
+func currentUser(c *gin.Context) (models.User, bool) {
+	value, ok := c.Get("user")
+	if !ok {
+		return models.User{}, false
+	}
+	return value.(models.User), true
+}
+
+// can reports whether the request's user holds the permission; anonymous
+// requests hold none.
+func can(c *gin.Context, permission models.Permission) bool {
+	user, ok := currentUser(c)
+	return ok && user.Can(permission)
+}
+
+// canWritePost applies the ownership rule: posts:write covers every post,
+// posts:write:own only posts the user owns.
+func canWritePost(c *gin.Context, post models.Post) bool {
+	user, ok := currentUser(c)
+	if !ok {
+		return false
+	}
+	if user.Can(models.PermWritePosts) {
+		return true
+	}
+	return user.Can(models.PermWriteOwnPosts) && post.OwnerID != nil && *post.OwnerID == user.ID
+}
+
+func forbid(c *gin.Context, message string) {
+	c.JSON(http.StatusForbidden, utils.HTTPError{
+		Code:    http.StatusForbidden,
+		Message: message,
+	})
+}
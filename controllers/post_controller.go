@@ -3,6 +3,7 @@ package controllers
 import (
 	"cms-backend/models"
 	"cms-backend/utils"
+	"fmt"
 	"net/http"
 	"strconv"
 
@@ -16,22 +17,71 @@ func GetPosts(c *gin.Context) {
 
 	title := c.Query("title")
 	author := c.Query("author")
+	contributor := c.Query("contributor")
+	role := c.Query("role")
+	status := c.Query("status")
+
+	if role != "" && !models.IsValidContributorRole(role) {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid contributor role",
+		})
+		return
+	}
+	if status != "" && !isValidPostStatus(status) {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid post status",
+		})
+		return
+	}
 
 	query := db
+	if !can(c, models.PermViewDrafts) {
+		query = query.Where("status = ?", models.PostStatusPublished)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
 	if title != "" {
 		query = query.Where("title ILIKE ?", "%"+title+"%")
 	}
 	if author != "" {
-		query = query.Where("author = ?", author)
+		query = query.Where("EXISTS (SELECT 1 FROM post_contributors pc WHERE pc.post_id = posts.id AND pc.name = ? AND pc.role = ?)", author, models.RoleAuthor)
+	}
+	if contributor != "" {
+		if role != "" {
+			query = query.Where("EXISTS (SELECT 1 FROM post_contributors pc WHERE pc.post_id = posts.id AND pc.name = ? AND pc.role = ?)", contributor, role)
+		} else {
+			query = query.Where("EXISTS (SELECT 1 FROM post_contributors pc WHERE pc.post_id = posts.id AND pc.name = ?)", contributor)
+		}
+	}
+
+	query, err := applyFieldFilters(query, "posts.fields", c.Request.URL.Query())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
 	}
 
-	if err := query.Preload("Media").Find(&posts).Error; err != nil {
+	if err := query.Preload("Media").Preload("Contributors", orderContributors).Find(&posts).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, utils.HTTPError{
 			Code:    http.StatusInternalServerError,
 			Message: err.Error(),
 		})
 		return
 	}
+	for i := range posts {
+		if err := presentHTML(c, false, posts[i].ContentFormat, &posts[i].Content, &posts[i].ContentHTML); err != nil {
+			c.JSON(http.StatusInternalServerError, utils.HTTPError{
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
+			})
+			return
+		}
+	}
 	c.JSON(http.StatusOK, posts)
 }
 
@@ -49,7 +99,7 @@ func GetPost(c *gin.Context) {
     }
 
     var post models.Post
-    if err := db.Preload("Media").First(&post, uint(id)).Error; err != nil {
+    if err := db.Preload("Media").Preload("Contributors", orderContributors).First(&post, uint(id)).Error; err != nil {
         if err == gorm.ErrRecordNotFound {
             c.JSON(http.StatusNotFound, utils.HTTPError{
                 Code:    http.StatusNotFound,
@@ -63,6 +113,42 @@ func GetPost(c *gin.Context) {
         }
         return
     }
+    if post.Status == models.PostStatusDraft && !can(c, models.PermViewDrafts) {
+        c.JSON(http.StatusNotFound, utils.HTTPError{
+            Code:    http.StatusNotFound,
+            Message: "Post not found",
+        })
+        return
+    }
+
+    setLastModified(c, post.UpdatedAt)
+    c.Header("ETag", utils.VersionETag(post.Version, nil))
+    if post.Lock, err = shownLock(c, db, models.ResourcePost, post.ID, canWritePost(c, post)); err != nil {
+        c.JSON(http.StatusInternalServerError, utils.HTTPError{
+            Code:    http.StatusInternalServerError,
+            Message: err.Error(),
+        })
+        return
+    }
+    for _, media := range post.Media {
+        setLastModified(c, media.UpdatedAt)
+    }
+    locale, err := localize(c, db, models.ResourcePost, post.ID, &post.Title, &post.Content)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, utils.HTTPError{
+            Code:    http.StatusInternalServerError,
+            Message: err.Error(),
+        })
+        return
+    }
+    post.Locale = locale
+    if err := presentHTML(c, locale != "" && locale != utils.SourceLocale(), post.ContentFormat, &post.Content, &post.ContentHTML); err != nil {
+        c.JSON(http.StatusInternalServerError, utils.HTTPError{
+            Code:    http.StatusInternalServerError,
+            Message: err.Error(),
+        })
+        return
+    }
     c.JSON(http.StatusOK, post)
 }
 
@@ -78,6 +164,20 @@ func CreatePost(c *gin.Context) {
         return
     }
 
+    if post.Blocks.IsNull() {
+        post.Blocks = nil
+    } else {
+        content, status, err := projectBlocks(db, post.Blocks)
+        if err != nil {
+            c.JSON(status, utils.HTTPError{
+                Code:    status,
+                Message: err.Error(),
+            })
+            return
+        }
+        post.Content = content
+    }
+
     if post.Title == "" || post.Content == "" {
         c.JSON(http.StatusBadRequest, utils.HTTPError{
             Code:    http.StatusBadRequest,
@@ -86,6 +186,55 @@ func CreatePost(c *gin.Context) {
         return
     }
 
+    if err := validateContributors(post.Contributors); err != nil {
+        c.JSON(http.StatusBadRequest, utils.HTTPError{
+            Code:    http.StatusBadRequest,
+            Message: err.Error(),
+        })
+        return
+    }
+
+    if err := validateFields(models.ResourcePost, post.Fields); err != nil {
+        c.JSON(http.StatusBadRequest, utils.HTTPError{
+            Code:    http.StatusBadRequest,
+            Message: err.Error(),
+        })
+        return
+    }
+
+    if err := prepareContentFormat(&post.ContentFormat, post.Blocks != nil, post.Content, &post.ContentHTML); err != nil {
+        c.JSON(http.StatusBadRequest, utils.HTTPError{
+            Code:    http.StatusBadRequest,
+            Message: err.Error(),
+        })
+        return
+    }
+
+    // Posts default to published for those allowed to publish and to
+    // draft for everyone else.
+    if post.Status == "" {
+        post.Status = models.PostStatusDraft
+        if can(c, models.PermPublishPosts) {
+            post.Status = models.PostStatusPublished
+        }
+    }
+    if !isValidPostStatus(post.Status) {
+        c.JSON(http.StatusBadRequest, utils.HTTPError{
+            Code:    http.StatusBadRequest,
+            Message: "Invalid post status",
+        })
+        return
+    }
+    if post.Status == models.PostStatusPublished && !can(c, models.PermPublishPosts) {
+        forbid(c, "You do not have permission to publish posts")
+        return
+    }
+    post.OwnerID = nil
+    if user, ok := currentUser(c); ok {
+        post.OwnerID = &user.ID
+    }
+
+    post.Version = 1
     tx := db.Begin()
     if err := tx.Create(&post).Error; err != nil {
         tx.Rollback()
@@ -95,7 +244,29 @@ func CreatePost(c *gin.Context) {
         })
         return
     }
+    if err := recordAudit(c, tx, models.AuditActionCreate, models.ResourcePost, post.ID, nil, post); err != nil {
+        tx.Rollback()
+        c.JSON(http.StatusInternalServerError, utils.HTTPError{
+            Code:    http.StatusInternalServerError,
+            Message: err.Error(),
+        })
+        return
+    }
+    events := []string{models.EventPostCreated}
+    if post.Status == models.PostStatusPublished {
+        events = append(events, models.EventPostPublished)
+    }
+    if err := recordEvents(c, tx, models.ResourcePost, post.ID, post, events...); err != nil {
+        tx.Rollback()
+        c.JSON(http.StatusInternalServerError, utils.HTTPError{
+            Code:    http.StatusInternalServerError,
+            Message: err.Error(),
+        })
+        return
+    }
     tx.Commit()
+    notifyCommitted(c)
+    indexPost(c, db, post)
     c.JSON(http.StatusCreated, post)
 }
 
@@ -128,6 +299,15 @@ func UpdatePost(c *gin.Context) {
         return
     }
 
+    if !canWritePost(c, post) {
+        forbid(c, "You can only edit your own posts")
+        return
+    }
+    if !checkEditLock(c, db, models.ResourcePost, post.ID) {
+        return
+    }
+
+    before := post
     var updateData models.Post
     if err := c.ShouldBindJSON(&updateData); err != nil {
         c.JSON(http.StatusBadRequest, utils.HTTPError{
@@ -136,19 +316,250 @@ func UpdatePost(c *gin.Context) {
         })
         return
     }
+    if !checkPrecondition(c, post.Version, updateData.Version, before) {
+        return
+    }
+
+    if updateData.Status != "" && updateData.Status != post.Status {
+        if !isValidPostStatus(updateData.Status) {
+            c.JSON(http.StatusBadRequest, utils.HTTPError{
+                Code:    http.StatusBadRequest,
+                Message: "Invalid post status",
+            })
+            return
+        }
+        if !can(c, models.PermPublishPosts) {
+            forbid(c, "You do not have permission to publish posts")
+            return
+        }
+        post.Status = updateData.Status
+    }
 
     if updateData.Title != "" {
         post.Title = updateData.Title
     }
-    if updateData.Content != "" {
-        post.Content = updateData.Content
+    if updateData.Blocks != nil && !updateData.Blocks.IsNull() {
+        content, status, err := projectBlocks(db, updateData.Blocks)
+        if err != nil {
+            c.JSON(status, utils.HTTPError{
+                Code:    status,
+                Message: err.Error(),
+            })
+            return
+        }
+        post.Blocks = updateData.Blocks
+        post.Content = content
+    } else if updateData.Blocks != nil || updateData.Content != "" {
+        // Raw content replaces the block document it was projected from.
+        post.Blocks = nil
+        if updateData.Content != "" {
+            post.Content = updateData.Content
+        }
+    }
+    if updateData.ContentFormat != "" {
+        post.ContentFormat = updateData.ContentFormat
+    }
+    if err := prepareContentFormat(&post.ContentFormat, post.Blocks != nil, post.Content, &post.ContentHTML); err != nil {
+        c.JSON(http.StatusBadRequest, utils.HTTPError{
+            Code:    http.StatusBadRequest,
+            Message: err.Error(),
+        })
+        return
+    }
+    if updateData.Fields != nil {
+        if err := validateFields(models.ResourcePost, updateData.Fields); err != nil {
+            c.JSON(http.StatusBadRequest, utils.HTTPError{
+                Code:    http.StatusBadRequest,
+                Message: err.Error(),
+            })
+            return
+        }
+        post.Fields = updateData.Fields
+    }
+
+    var contributors []models.PostContributor
+    replaceContributors := false
+    if updateData.Contributors != nil {
+        if err := validateContributors(updateData.Contributors); err != nil {
+            c.JSON(http.StatusBadRequest, utils.HTTPError{
+                Code:    http.StatusBadRequest,
+                Message: err.Error(),
+            })
+            return
+        }
+        contributors = updateData.Contributors
+        replaceContributors = true
+    } else if updateData.Author != "" && updateData.Author != post.Author {
+        if err := orderContributors(db.Where("post_id = ?", post.ID)).Find(&contributors).Error; err != nil {
+            c.JSON(http.StatusInternalServerError, utils.HTTPError{
+                Code:    http.StatusInternalServerError,
+                Message: err.Error(),
+            })
+            return
+        }
+        contributors = withPrimaryAuthor(contributors, updateData.Author)
+        replaceContributors = true
     }
-    if updateData.Author != "" {
-        post.Author = updateData.Author
+    if replaceContributors {
+        post.Author = models.PrimaryAuthor(contributors)
     }
 
+    savePost(c, db, before, post, contributors, replaceContributors)
+}
+
+// PatchPost applies a JSON Merge Patch or a JSON Patch to a post, its
+// contributors included, and checks the result as an update would. Unlike
+// an update, a patch can clear the author.
+func PatchPost(c *gin.Context) {
+    db := c.MustGet("db").(*gorm.DB)
+
+    idParam := c.Param("id")
+    id, err := strconv.ParseUint(idParam, 10, 32)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, utils.HTTPError{
+            Code:    http.StatusBadRequest,
+            Message: "Invalid post ID",
+        })
+        return
+    }
+
+    var post models.Post
+    if err := db.Preload("Contributors", orderContributors).First(&post, uint(id)).Error; err != nil {
+        if err == gorm.ErrRecordNotFound {
+            c.JSON(http.StatusNotFound, utils.HTTPError{
+                Code:    http.StatusNotFound,
+                Message: "Post not found",
+            })
+        } else {
+            c.JSON(http.StatusInternalServerError, utils.HTTPError{
+                Code:    http.StatusInternalServerError,
+                Message: err.Error(),
+            })
+        }
+        return
+    }
+
+    if !canWritePost(c, post) {
+        forbid(c, "You can only edit your own posts")
+        return
+    }
+    if !checkEditLock(c, db, models.ResourcePost, post.ID) {
+        return
+    }
+    if !checkPrecondition(c, post.Version, 0, post) {
+        return
+    }
+
+    before := post
+    var patched models.Post
+    if !applyPatch(c, post, &patched) {
+        return
+    }
+    if patched.Title == "" {
+        unprocessable(c, "Title is required")
+        return
+    }
+    if patched.Status != post.Status {
+        if !isValidPostStatus(patched.Status) {
+            unprocessable(c, "Invalid post status")
+            return
+        }
+        if !can(c, models.PermPublishPosts) {
+            forbid(c, "You do not have permission to publish posts")
+            return
+        }
+    }
+    if !patchBlocks(c, db, before.Blocks, before.Content, &patched.Blocks, &patched.Content) {
+        return
+    }
+    if err := validateFields(models.ResourcePost, patched.Fields); err != nil {
+        unprocessable(c, err.Error())
+        return
+    }
+    if patched.Fields.IsNull() {
+        patched.Fields = nil
+    }
+
+    var contributors []models.PostContributor
+    replaceContributors := false
+    if !sameCredits(patched.Contributors, before.Contributors) {
+        if err := validateContributors(patched.Contributors); err != nil {
+            unprocessable(c, err.Error())
+            return
+        }
+        contributors = patched.Contributors
+        replaceContributors = true
+    } else if patched.Author != before.Author {
+        contributors = withPrimaryAuthor(append([]models.PostContributor(nil), before.Contributors...), patched.Author)
+        replaceContributors = true
+    }
+
+    post.Title = patched.Title
+    post.Content = patched.Content
+    post.Blocks = patched.Blocks
+    post.Fields = patched.Fields
+    post.Status = patched.Status
+    post.ContentFormat = patched.ContentFormat
+    if err := prepareContentFormat(&post.ContentFormat, post.Blocks != nil, post.Content, &post.ContentHTML); err != nil {
+        unprocessable(c, err.Error())
+        return
+    }
+    if replaceContributors {
+        post.Author = models.PrimaryAuthor(contributors)
+    } else {
+        contributors = before.Contributors
+    }
+    // Contributors are written by savePost, not along with the post.
+    post.Contributors = nil
+    savePost(c, db, before, post, contributors, replaceContributors)
+}
+
+// savePost writes an update of a post, as long as it is still at the version
+// before was read at. Its credits are replaced by contributors when
+// replaceContributors is set, and reported as contributors either way.
+func savePost(c *gin.Context, db *gorm.DB, before, post models.Post, contributors []models.PostContributor, replaceContributors bool) {
+    // The update only applies to the version read above.
+    post.Version = before.Version + 1
     tx := db.Begin()
-    if err := tx.Save(&post).Error; err != nil {
+    result := tx.Select("*").Where("version = ?", before.Version).Save(&post)
+    if result.Error != nil {
+        tx.Rollback()
+        c.JSON(http.StatusInternalServerError, utils.HTTPError{
+            Code:    http.StatusInternalServerError,
+            Message: result.Error.Error(),
+        })
+        return
+    }
+    if result.RowsAffected == 0 {
+        tx.Rollback()
+        var current models.Post
+        preconditionFailed(c, db, post.ID, &current, func() uint { return current.Version }, "Post not found")
+        return
+    }
+    if replaceContributors {
+        if err := replacePostContributors(tx, post.ID, contributors); err != nil {
+            tx.Rollback()
+            c.JSON(http.StatusInternalServerError, utils.HTTPError{
+                Code:    http.StatusInternalServerError,
+                Message: err.Error(),
+            })
+            return
+        }
+    }
+    post.Contributors = contributors
+    if err := recordAudit(c, tx, models.AuditActionUpdate, models.ResourcePost, post.ID, before, post); err != nil {
+        tx.Rollback()
+        c.JSON(http.StatusInternalServerError, utils.HTTPError{
+            Code:    http.StatusInternalServerError,
+            Message: err.Error(),
+        })
+        return
+    }
+    events := []string{models.EventPostUpdated}
+    if post.Status == models.PostStatusPublished && before.Status != models.PostStatusPublished {
+        events = append(events, models.EventPostPublished)
+    }
+    if err := recordEvents(c, tx, models.ResourcePost, post.ID, post, events...); err != nil {
         tx.Rollback()
         c.JSON(http.StatusInternalServerError, utils.HTTPError{
             Code:    http.StatusInternalServerError,
@@ -157,7 +568,9 @@ func UpdatePost(c *gin.Context) {
         return
     }
     tx.Commit()
-    c.JSON(http.StatusOK, post)
+    notifyCommitted(c)
+    indexPost(c, db, post)
+    respondVersioned(c, http.StatusOK, post.Version, post)
 }
 
 func DeletePost(c *gin.Context) {
@@ -189,8 +602,51 @@ func DeletePost(c *gin.Context) {
         return
     }
 
+    if !canWritePost(c, post) {
+        forbid(c, "You can only delete your own posts")
+        return
+    }
+    if !checkEditLock(c, db, models.ResourcePost, post.ID) {
+        return
+    }
+    version, ok := queryVersion(c)
+    if !ok || !checkPrecondition(c, post.Version, version, post) {
+        return
+    }
+
     tx := db.Begin()
-    if err := tx.Delete(&post).Error; err != nil {
+    result := tx.Where("version = ?", post.Version).Delete(&post)
+    if result.Error != nil {
+        tx.Rollback()
+        c.JSON(http.StatusInternalServerError, utils.HTTPError{
+            Code:    http.StatusInternalServerError,
+            Message: result.Error.Error(),
+        })
+        return
+    }
+    if result.RowsAffected == 0 {
+        tx.Rollback()
+        var current models.Post
+        preconditionFailed(c, db, post.ID, &current, func() uint { return current.Version }, "Post not found")
+        return
+    }
+    if err := recordAudit(c, tx, models.AuditActionDelete, models.ResourcePost, post.ID, post, nil); err != nil {
+        tx.Rollback()
+        c.JSON(http.StatusInternalServerError, utils.HTTPError{
+            Code:    http.StatusInternalServerError,
+            Message: err.Error(),
+        })
+        return
+    }
+    if err := deleteTranslations(c, tx, models.ResourcePost, post.ID); err != nil {
+        tx.Rollback()
+        c.JSON(http.StatusInternalServerError, utils.HTTPError{
+            Code:    http.StatusInternalServerError,
+            Message: err.Error(),
+        })
+        return
+    }
+    if err := recordEvents(c, tx, models.ResourcePost, post.ID, post, models.EventPostDeleted); err != nil {
         tx.Rollback()
         c.JSON(http.StatusInternalServerError, utils.HTTPError{
             Code:    http.StatusInternalServerError,
@@ -199,7 +655,77 @@ func DeletePost(c *gin.Context) {
         return
     }
     tx.Commit()
+    notifyCommitted(c)
+    unindex(c, models.ResourcePost, post.ID)
     c.JSON(http.StatusOK, utils.MessageResponse{
         Message: "Post deleted successfully",
     })
-}
\ No newline at end of file
+}
+
+func isValidPostStatus(status string) bool {
+	return status == models.PostStatusDraft || status == models.PostStatusPublished
+}
+
+func orderContributors(db *gorm.DB) *gorm.DB {
+	return db.Order("position")
+}
+
+func validateContributors(contributors []models.PostContributor) error {
+	for i, contributor := range contributors {
+		if contributor.Name == "" {
+			return fmt.Errorf("contributor %d: name is required", i)
+		}
+		if !models.IsValidContributorRole(contributor.Role) {
+			return fmt.Errorf("contributor %d: invalid role %q", i, contributor.Role)
+		}
+	}
+	return nil
+}
+
+// withPrimaryAuthor renames the first author credit, or prepends one when the
+// post has no author yet, so the legacy author field keeps working on update.
+// An empty name removes the credit.
+func withPrimaryAuthor(contributors []models.PostContributor, name string) []models.PostContributor {
+	for i := range contributors {
+		if contributors[i].Role == models.RoleAuthor {
+			if name == "" {
+				return append(contributors[:i], contributors[i+1:]...)
+			}
+			contributors[i].Name = name
+			return contributors
+		}
+	}
+	if name == "" {
+		return contributors
+	}
+	return append([]models.PostContributor{{Name: name, Role: models.RoleAuthor}}, contributors...)
+}
+
+// sameCredits reports whether two contributor lists credit the same names
+// in the same roles and order.
+func sameCredits(a, b []models.PostContributor) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name || a[i].Role != b[i].Role {
+			return false
+		}
+	}
+	return true
+}
+
+func replacePostContributors(tx *gorm.DB, postID uint, contributors []models.PostContributor) error {
+	if err := tx.Where("post_id = ?", postID).Delete(&models.PostContributor{}).Error; err != nil {
+		return err
+	}
+	if len(contributors) == 0 {
+		return nil
+	}
+	for i := range contributors {
+		contributors[i].ID = 0
+		contributors[i].PostID = postID
+		contributors[i].Position = i
+	}
+	return tx.Create(&contributors).Error
+}
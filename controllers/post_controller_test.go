@@ -7,6 +7,9 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -23,7 +26,15 @@ func TestGetPosts(t *testing.T) {
 		AddRow(2, "Second Post", "Content 2", "Author 2", time.Now(), time.Now())
 
 	mock.ExpectQuery(`SELECT \* FROM "posts"`).WillReturnRows(rows)
-	
+
+	// Mock the Preload("Contributors") query
+	mock.ExpectQuery(`SELECT \* FROM "post_contributors" WHERE "post_contributors"\."post_id" IN \(\$1,\$2\) ORDER BY position`).
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "post_id", "name", "role", "position"}).
+			AddRow(1, 1, "Author 1", "author", 0).
+			AddRow(2, 2, "Author 2", "author", 0).
+			AddRow(3, 2, "Photographer 2", "photographer", 1))
+
 	// Mock the Preload("Media") query
 	mock.ExpectQuery(`SELECT \* FROM "post_media" WHERE "post_media"\."post_id" IN \(\$1,\$2\)`).
 		WithArgs(1, 2).
@@ -52,6 +63,12 @@ func TestGetPosts(t *testing.T) {
 	if response[1].Title != "Second Post" {
 		t.Fatalf("Expected 'Second Post', but got '%s'", response[1].Title)
 	}
+	if len(response[1].Contributors) != 2 {
+		t.Fatalf("Expected 2 contributors, but got %d", len(response[1].Contributors))
+	}
+	if response[1].Contributors[1].Role != "photographer" {
+		t.Fatalf("Expected role 'photographer', but got '%s'", response[1].Contributors[1].Role)
+	}
 }
 
 func TestGetPostsWithFilters(t *testing.T) {
@@ -61,10 +78,14 @@ func TestGetPostsWithFilters(t *testing.T) {
 	rows := sqlmock.NewRows([]string{"id", "title", "content", "author", "created_at", "updated_at"}).
 		AddRow(1, "Test Post", "Test Content", "Test Author", time.Now(), time.Now())
 
-	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE title ILIKE \$1 AND author = \$2`).
-		WithArgs("%test%", "Test Author").
+	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE title ILIKE \$1 AND \(EXISTS \(SELECT 1 FROM post_contributors pc WHERE pc\.post_id = posts\.id AND pc\.name = \$2 AND pc\.role = \$3\)\)`).
+		WithArgs("%test%", "Test Author", "author").
 		WillReturnRows(rows)
-	
+
+	mock.ExpectQuery(`SELECT \* FROM "post_contributors" WHERE "post_contributors"\."post_id" = \$1 ORDER BY position`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "post_id", "name", "role", "position"}))
+
 	// Mock the Preload("Media") query
 	mock.ExpectQuery(`SELECT \* FROM "post_media" WHERE "post_media"\."post_id" = \$1`).
 		WithArgs(1).
@@ -102,11 +123,17 @@ func TestGetPost(t *testing.T) {
 	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE "posts"\."id" = \$1 ORDER BY "posts"\."id" LIMIT \$2`).
 		WithArgs(1, 1).
 		WillReturnRows(rows)
-	
+
+	mock.ExpectQuery(`SELECT \* FROM "post_contributors" WHERE "post_contributors"\."post_id" = \$1 ORDER BY position`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "post_id", "name", "role", "position"}).
+			AddRow(1, 1, "Test Author", "author", 0))
+
 	// Mock the Preload("Media") query
 	mock.ExpectQuery(`SELECT \* FROM "post_media" WHERE "post_media"\."post_id" = \$1`).
 		WithArgs(1).
 		WillReturnRows(sqlmock.NewRows([]string{"post_id", "media_id"}))
+	expectNoLock(mock)
 
 	router.GET("/posts/:id", GetPost)
 	w := httptest.NewRecorder()
@@ -225,8 +252,13 @@ func TestCreatePost(t *testing.T) {
 
 	mock.ExpectBegin()
 	mock.ExpectQuery(`INSERT INTO "posts"`).
-		WithArgs("New Post", "New Content", "New Author", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WithArgs(1, "New Post", "New Content", "New Author", sqlmock.AnyArg(), sqlmock.AnyArg(), nil, nil, "plain", "<p>New Content</p>\n", "published", 1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(`INSERT INTO "post_contributors"`).
+		WithArgs(1, "New Author", "author", 0, sqlmock.AnyArg(), sqlmock.AnyArg()).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	expectAudit(mock, models.AuditActionCreate, models.ResourcePost, 1)
+	expectOutbox(mock, 1, models.EventPostCreated, models.EventPostPublished)
 	mock.ExpectCommit()
 
 	post := models.Post{
@@ -360,7 +392,7 @@ func TestCreatePostDatabaseError(t *testing.T) {
 
 	mock.ExpectBegin()
 	mock.ExpectQuery(`INSERT INTO "posts"`).
-		WithArgs("New Post", "New Content", "New Author", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WithArgs(1, "New Post", "New Content", "New Author", sqlmock.AnyArg(), sqlmock.AnyArg(), nil, nil, "plain", "<p>New Content</p>\n", "published", 1, 1).
 		WillReturnError(gorm.ErrInvalidDB)
 	mock.ExpectRollback()
 
@@ -396,18 +428,35 @@ func TestUpdatePost(t *testing.T) {
 	defer mock.ExpectClose()
 
 	// Mock finding existing post
-	rows := sqlmock.NewRows([]string{"id", "title", "content", "author", "created_at", "updated_at"}).
-		AddRow(1, "Old Title", "Old Content", "Old Author", time.Now(), time.Now())
+	rows := sqlmock.NewRows([]string{"id", "title", "content", "author", "created_at", "updated_at", "status"}).
+		AddRow(1, "Old Title", "Old Content", "Old Author", time.Now(), time.Now(), "published")
 
 	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE "posts"\."id" = \$1 ORDER BY "posts"\."id" LIMIT \$2`).
 		WithArgs(1, 1).
 		WillReturnRows(rows)
+	expectNoLock(mock)
+
+	// Mock loading the existing credits so the author can be renamed
+	mock.ExpectQuery(`SELECT \* FROM "post_contributors" WHERE post_id = \$1 ORDER BY position`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "post_id", "name", "role", "position"}).
+			AddRow(1, 1, "Old Author", "author", 0).
+			AddRow(2, 1, "Some Editor", "editor", 1))
 
 	// Mock update transaction
 	mock.ExpectBegin()
-	mock.ExpectExec(`UPDATE "posts" SET "title"=\$1,"content"=\$2,"author"=\$3,"created_at"=\$4,"updated_at"=\$5 WHERE "id" = \$6`).
-		WithArgs("Updated Title", "Updated Content", "Updated Author", sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
+	mock.ExpectExec(`UPDATE "posts" SET "site_id"=\$1,"title"=\$2,"content"=\$3,"author"=\$4,"created_at"=\$5,"updated_at"=\$6,"fields"=\$7,"blocks"=\$8,"content_format"=\$9,"content_html"=\$10,"status"=\$11,"owner_id"=\$12,"version"=\$13 WHERE version = \$14 AND "id" = \$15`).
+		WithArgs(sqlmock.AnyArg(), "Updated Title", "Updated Content", "Updated Author", sqlmock.AnyArg(), sqlmock.AnyArg(), nil, nil, "plain", "<p>Updated Content</p>\n", "published", nil, 1, sqlmock.AnyArg(), 1).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`DELETE FROM "post_contributors" WHERE post_id = \$1`).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectQuery(`INSERT INTO "post_contributors"`).
+		WithArgs(1, "Updated Author", "author", 0, sqlmock.AnyArg(), sqlmock.AnyArg(),
+			1, "Some Editor", "editor", 1, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3).AddRow(4))
+	expectAudit(mock, models.AuditActionUpdate, models.ResourcePost, 1)
+	expectOutbox(mock, 1, models.EventPostUpdated)
 	mock.ExpectCommit()
 
 	updateData := models.Post{
@@ -524,6 +573,7 @@ func TestUpdatePostInvalidJSON(t *testing.T) {
 	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE "posts"\."id" = \$1 ORDER BY "posts"\."id" LIMIT \$2`).
 		WithArgs(1, 1).
 		WillReturnRows(rows)
+	expectNoLock(mock)
 
 	router.PUT("/posts/:id", UpdatePost)
 	w := httptest.NewRecorder()
@@ -556,12 +606,16 @@ func TestDeletePost(t *testing.T) {
 	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE "posts"\."id" = \$1 ORDER BY "posts"\."id" LIMIT \$2`).
 		WithArgs(1, 1).
 		WillReturnRows(rows)
+	expectNoLock(mock)
 
 	// Mock delete transaction
 	mock.ExpectBegin()
-	mock.ExpectExec(`DELETE FROM "posts" WHERE "posts"\."id" = \$1`).
-		WithArgs(1).
+	mock.ExpectExec(`DELETE FROM "posts" WHERE version = \$1 AND "posts"\."id" = \$2`).
+		WithArgs(sqlmock.AnyArg(), 1).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	expectAudit(mock, models.AuditActionDelete, models.ResourcePost, 1)
+	expectTranslationsDeleted(mock, models.ResourcePost, 1)
+	expectOutbox(mock, 1, models.EventPostDeleted)
 	mock.ExpectCommit()
 
 	router.DELETE("/posts/:id", DeletePost)
@@ -653,8 +707,8 @@ func TestDeletePostDatabaseError(t *testing.T) {
 
 	// Mock delete transaction error
 	mock.ExpectBegin()
-	mock.ExpectExec(`DELETE FROM "posts" WHERE "posts"\."id" = \$1`).
-		WithArgs(1).
+	mock.ExpectExec(`DELETE FROM "posts" WHERE version = \$1 AND "posts"\."id" = \$2`).
+		WithArgs(sqlmock.AnyArg(), 1).
 		WillReturnError(gorm.ErrInvalidDB)
 	mock.ExpectRollback()
 
@@ -675,4 +729,563 @@ func TestDeletePostDatabaseError(t *testing.T) {
 	if response.Code != http.StatusInternalServerError {
 		t.Fatalf("Expected error code 500, but got %d", response.Code)
 	}
-}
\ No newline at end of file
+}
+
+func TestGetPostsByContributorRole(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	rows := sqlmock.NewRows([]string{"id", "title", "content", "author", "created_at", "updated_at"}).
+		AddRow(1, "Photo Essay", "Content", "Writer", time.Now(), time.Now())
+
+	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE EXISTS \(SELECT 1 FROM post_contributors pc WHERE pc\.post_id = posts\.id AND pc\.name = \$1 AND pc\.role = \$2\)`).
+		WithArgs("Jane", "photographer").
+		WillReturnRows(rows)
+	mock.ExpectQuery(`SELECT \* FROM "post_contributors" WHERE "post_contributors"\."post_id" = \$1 ORDER BY position`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "post_id", "name", "role", "position"}).
+			AddRow(1, 1, "Writer", "author", 0).
+			AddRow(2, 1, "Jane", "photographer", 1))
+	mock.ExpectQuery(`SELECT \* FROM "post_media" WHERE "post_media"\."post_id" = \$1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"post_id", "media_id"}))
+
+	router.GET("/posts", GetPosts)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/posts?contributor=Jane&role=photographer", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d", w.Code)
+	}
+
+	var response []models.Post
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+	if len(response) != 1 || len(response[0].Contributors) != 2 {
+		t.Fatalf("Expected 1 post with 2 contributors, but got %+v", response)
+	}
+}
+
+func TestGetPostsInvalidRole(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	router.GET("/posts", GetPosts)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/posts?contributor=Jane&role=intern", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, but got %d", w.Code)
+	}
+}
+
+func TestCreatePostWithContributors(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "posts"`).
+		WithArgs(1, "Co-written", "Content", "Ann", sqlmock.AnyArg(), sqlmock.AnyArg(), nil, nil, "plain", "<p>Content</p>\n", "published", 1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(`INSERT INTO "post_contributors"`).
+		WithArgs(1, "Ed", "editor", 0, sqlmock.AnyArg(), sqlmock.AnyArg(),
+			1, "Ann", "author", 1, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	expectAudit(mock, models.AuditActionCreate, models.ResourcePost, 1)
+	expectOutbox(mock, 1, models.EventPostCreated, models.EventPostPublished)
+	mock.ExpectCommit()
+
+	body := `{"title":"Co-written","content":"Content","contributors":[{"name":"Ed","role":"editor"},{"name":"Ann","role":"author"}]}`
+
+	router.POST("/posts", CreatePost)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/posts", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, but got %d", w.Code)
+	}
+
+	var response models.Post
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+	if response.Author != "Ann" {
+		t.Fatalf("Expected author 'Ann', but got '%s'", response.Author)
+	}
+	if len(response.Contributors) != 2 || response.Contributors[1].Position != 1 {
+		t.Fatalf("Expected 2 ordered contributors, but got %+v", response.Contributors)
+	}
+}
+
+func TestCreatePostInvalidContributorRole(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	body := `{"title":"Post","content":"Content","contributors":[{"name":"Ed","role":"intern"}]}`
+
+	router.POST("/posts", CreatePost)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/posts", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, but got %d", w.Code)
+	}
+}
+
+func TestGetPostsWithFieldFilters(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE posts\.fields @@ \$1::jsonpath AND posts\.fields @@ \$2::jsonpath`).
+		WithArgs(`$."event_date" >= "2024-05-01"`, `$."venue"."capacity" < 100`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "content", "author", "created_at", "updated_at", "fields"}))
+
+	router.GET("/posts", GetPosts)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/posts?fields.event_date[gte]=2024-05-01&fields.venue.capacity[lt]=100", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d", w.Code)
+	}
+}
+
+func TestGetPostsFieldFilterNotANumber(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE posts\.fields @@ \$1::jsonpath AND posts\.fields @@ \$2::jsonpath`).
+		WithArgs(`$."x" == "NaN"`, `$."y" == "0x1p3"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "content", "author", "created_at", "updated_at", "fields"}))
+
+	router.GET("/posts", GetPosts)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/posts?fields.x=NaN&fields.y=0x1p3", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d", w.Code)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetPostsInvalidFieldOperator(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	router.GET("/posts", GetPosts)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/posts?fields.event_date[between]=x", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, but got %d", w.Code)
+	}
+}
+
+func TestCreatePostWithFields(t *testing.T) {
+	dir := t.TempDir()
+	schema := `{"type":"object","properties":{"subtitle":{"type":"string"},"cta_link":{"type":"string","format":"uri"}}}`
+	if err := os.WriteFile(filepath.Join(dir, "post.json"), []byte(schema), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("FIELD_SCHEMA_DIR", dir)
+
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	fields := `{"subtitle":"Part one","cta_link":"https://example.com/signup"}`
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "posts"`).
+		WithArgs(1, "Post", "Content", "", sqlmock.AnyArg(), sqlmock.AnyArg(), fields, nil, "plain", "<p>Content</p>\n", "published", 1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	expectAudit(mock, models.AuditActionCreate, models.ResourcePost, 1)
+	expectOutbox(mock, 1, models.EventPostCreated, models.EventPostPublished)
+	mock.ExpectCommit()
+
+	router.POST("/posts", CreatePost)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/posts", bytes.NewBufferString(`{"title":"Post","content":"Content","fields":`+fields+`}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, but got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestCreatePostFieldsSchemaViolation(t *testing.T) {
+	dir := t.TempDir()
+	schema := `{"type":"object","properties":{"cta_link":{"type":"string","format":"uri"}}}`
+	if err := os.WriteFile(filepath.Join(dir, "post.json"), []byte(schema), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("FIELD_SCHEMA_DIR", dir)
+
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	router.POST("/posts", CreatePost)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/posts", bytes.NewBufferString(`{"title":"Post","content":"Content","fields":{"cta_link":"not a uri"}}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, but got %d", w.Code)
+	}
+}
+
+func TestCreatePostWithBlocks(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	blocks := `[{"type":"heading","text":"Trip report","level":2},{"type":"paragraph","text":"We went hiking."},{"type":"image","media_id":3,"caption":"Summit"},{"type":"list","items":["boots","water"]}]`
+
+	mock.ExpectQuery(`SELECT "id" FROM "media" WHERE id IN \(\$1\)`).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "posts"`).
+		WithArgs(1, "Hike", "Trip report\n\nWe went hiking.\n\nSummit\n\nboots\nwater", "", sqlmock.AnyArg(), sqlmock.AnyArg(), nil, blocks,
+			"plain", "<p>Trip report</p>\n<p>We went hiking.</p>\n<p>Summit</p>\n<p>boots<br>water</p>\n", "published", 1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	expectAudit(mock, models.AuditActionCreate, models.ResourcePost, 1)
+	expectOutbox(mock, 1, models.EventPostCreated, models.EventPostPublished)
+	mock.ExpectCommit()
+
+	router.POST("/posts", CreatePost)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/posts", bytes.NewBufferString(`{"title":"Hike","blocks":`+blocks+`}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response models.Post
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+	if string(response.Blocks) != blocks {
+		t.Fatalf("Expected blocks to round-trip, but got %s", response.Blocks)
+	}
+}
+
+func TestCreatePostBlocksUnknownMedia(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT "id" FROM "media" WHERE id IN \(\$1,\$2\)`).
+		WithArgs(3, 4).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+
+	router.POST("/posts", CreatePost)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/posts", bytes.NewBufferString(`{"title":"Gallery","blocks":[{"type":"image","media_id":3},{"type":"image","media_id":4}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, but got %d", w.Code)
+	}
+
+	var response utils.HTTPError
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+	if response.Message != "blocks reference unknown media: 4" {
+		t.Fatalf("Expected unknown media message, but got '%s'", response.Message)
+	}
+}
+
+func TestCreatePostMarkdownIsSanitized(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	content := "# Hello\n\n<script>alert(1)</script>\n\n[click](javascript:alert(1)) **bold** <sup>1</sup>"
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "posts"`).
+		WithArgs(1, "Post", content, "", sqlmock.AnyArg(), sqlmock.AnyArg(), nil, nil, "markdown", sqlmock.AnyArg(), "published", 1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	expectAudit(mock, models.AuditActionCreate, models.ResourcePost, 1)
+	expectOutbox(mock, 1, models.EventPostCreated, models.EventPostPublished)
+	mock.ExpectCommit()
+
+	body, _ := json.Marshal(map[string]string{"title": "Post", "content": content, "content_format": "markdown"})
+
+	router.POST("/posts", CreatePost)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/posts", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response models.Post
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+	if !strings.Contains(response.ContentHTML, "<h1>Hello</h1>") || !strings.Contains(response.ContentHTML, "<strong>bold</strong> <sup>1</sup>") {
+		t.Fatalf("Expected rendered markdown, but got %q", response.ContentHTML)
+	}
+	if strings.Contains(response.ContentHTML, "<script") || strings.Contains(response.ContentHTML, "javascript:") {
+		t.Fatalf("Expected sanitized HTML, but got %q", response.ContentHTML)
+	}
+}
+
+func TestCreatePostInvalidContentFormat(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	router.POST("/posts", CreatePost)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/posts", bytes.NewBufferString(`{"title":"Post","content":"Content","content_format":"rst"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, but got %d", w.Code)
+	}
+}
+
+func TestGetPostRenderHTML(t *testing.T) {
+	for _, query := range []string{"", "?render=html"} {
+		router, _, mock := utils.SetupRouterAndMockDB(t)
+
+		mock.ExpectQuery(`SELECT \* FROM "posts" WHERE "posts"\."id" = \$1`).
+			WithArgs(1, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "title", "content", "content_format", "content_html"}).
+				AddRow(1, "Post", "*hi*", "markdown", "<p><em>hi</em></p>\n"))
+		mock.ExpectQuery(`SELECT \* FROM "post_contributors"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "post_id", "name", "role", "position"}))
+		mock.ExpectQuery(`SELECT \* FROM "post_media"`).
+			WillReturnRows(sqlmock.NewRows([]string{"post_id", "media_id"}))
+		expectNoLock(mock)
+
+		router.GET("/posts/:id", GetPost)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/posts/1"+query, nil)
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, but got %d", w.Code)
+		}
+
+		var response models.Post
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Error unmarshaling response: %v", err)
+		}
+		expected := ""
+		if query != "" {
+			expected = "<p><em>hi</em></p>\n"
+		}
+		if response.ContentHTML != expected {
+			t.Fatalf("Expected content_html %q for %q, but got %q", expected, query, response.ContentHTML)
+		}
+		mock.ExpectClose()
+	}
+}
+
+func TestGetPostsHidesDraftsFromAnonymous(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDBAs(t, nil)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE status = \$1`).
+		WithArgs("published").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	router.GET("/posts", GetPosts)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/posts", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestGetDraftPostAnonymousNotFound(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDBAs(t, nil)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE "posts"\."id" = \$1`).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "status"}).AddRow(1, "Draft", "draft"))
+	mock.ExpectQuery(`SELECT \* FROM "post_contributors"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`SELECT \* FROM "post_media"`).WillReturnRows(sqlmock.NewRows([]string{"post_id", "media_id"}))
+
+	router.GET("/posts/:id", GetPost)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/posts/1", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("Expected status 404, but got %d", w.Code)
+	}
+}
+
+func TestCreatePostAsAuthorIsOwnedDraft(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDBAs(t, &models.User{ID: 7, Role: models.UserRoleAuthor})
+	defer mock.ExpectClose()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "posts"`).
+		WithArgs(1, "Draft", "Content", "Ann", sqlmock.AnyArg(), sqlmock.AnyArg(), nil, nil, "plain", "<p>Content</p>\n", "draft", 7, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(`INSERT INTO "post_contributors"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	expectAudit(mock, models.AuditActionCreate, models.ResourcePost, 1)
+	expectOutbox(mock, 1, models.EventPostCreated)
+	mock.ExpectCommit()
+
+	router.POST("/posts", CreatePost)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/posts", strings.NewReader(`{"title":"Draft","content":"Content","author":"Ann","owner_id":3}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, but got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestCreatePostAsAuthorCannotPublish(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDBAs(t, &models.User{ID: 7, Role: models.UserRoleAuthor})
+	defer mock.ExpectClose()
+
+	router.POST("/posts", CreatePost)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/posts", strings.NewReader(`{"title":"Post","content":"Content","status":"published"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403, but got %d", w.Code)
+	}
+}
+
+func TestUpdatePostAsAuthorOfOthersPostForbidden(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDBAs(t, &models.User{ID: 7, Role: models.UserRoleAuthor})
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE "posts"\."id" = \$1`).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "status", "owner_id"}).AddRow(1, "Theirs", "draft", 3))
+
+	router.PUT("/posts/:id", UpdatePost)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPut, "/posts/1", strings.NewReader(`{"title":"Mine now"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403, but got %d", w.Code)
+	}
+}
+
+func TestUpdatePostAsOwnerCannotPublish(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDBAs(t, &models.User{ID: 7, Role: models.UserRoleAuthor})
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE "posts"\."id" = \$1`).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "status", "owner_id"}).AddRow(1, "Mine", "draft", 7))
+	expectNoLock(mock)
+
+	router.PUT("/posts/:id", UpdatePost)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPut, "/posts/1", strings.NewReader(`{"title":"Mine","status":"published"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403, but got %d", w.Code)
+	}
+}
+
+func TestPatchPostClearsAuthor(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE "posts"\."id" = \$1 ORDER BY "posts"\."id" LIMIT \$2`).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "content", "author", "status", "version"}).
+			AddRow(1, "Post", "Content", "Ann", "published", 1))
+	mock.ExpectQuery(`SELECT \* FROM "post_contributors" WHERE "post_contributors"\."post_id" = \$1 ORDER BY position`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "post_id", "name", "role", "position"}).
+			AddRow(1, 1, "Ann", "author", 0).
+			AddRow(2, 1, "Ed", "editor", 1))
+	expectNoLock(mock)
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "posts" SET .* WHERE version = \$14 AND "id" = \$15`).
+		WithArgs(sqlmock.AnyArg(), "Post", "Content", "", sqlmock.AnyArg(), sqlmock.AnyArg(), nil, nil, "plain", "<p>Content</p>\n", "published", nil, 2, 1, 1).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`DELETE FROM "post_contributors" WHERE post_id = \$1`).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectQuery(`INSERT INTO "post_contributors"`).
+		WithArgs(1, "Ed", "editor", 0, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	expectAudit(mock, models.AuditActionUpdate, models.ResourcePost, 1)
+	expectOutbox(mock, 1, models.EventPostUpdated)
+	mock.ExpectCommit()
+
+	router.PATCH("/posts/:id", PatchPost)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPatch, "/posts/1", strings.NewReader(`[{"op":"test","path":"/author","value":"Ann"},{"op":"replace","path":"/author","value":""}]`))
+	req.Header.Set("Content-Type", "application/json-patch+json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	var response models.Post
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+	if response.Author != "" || len(response.Contributors) != 1 || response.Contributors[0].Name != "Ed" {
+		t.Fatalf("Expected the author credit to be removed, but got %q and %+v", response.Author, response.Contributors)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestPatchPostAsAuthorCannotPublish(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDBAs(t, &models.User{ID: 7, Role: models.UserRoleAuthor})
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE "posts"\."id" = \$1`).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "content", "status", "owner_id"}).AddRow(1, "Mine", "Content", "draft", 7))
+	mock.ExpectQuery(`SELECT \* FROM "post_contributors"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "post_id", "name", "role", "position"}))
+	expectNoLock(mock)
+
+	router.PATCH("/posts/:id", PatchPost)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPatch, "/posts/1", strings.NewReader(`{"status":"published"}`))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403, but got %d: %s", w.Code, w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("There were unfulfilled expectations: %s", err)
+	}
+}
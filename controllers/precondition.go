@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"cms-backend/utils"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// checkPrecondition checks the version a write applies to against the
+// resource's current version: the versions named by If-Match, else the
+// version given in the body or ?version= (0 when there is none). On a
+// mismatch it answers 412 with current, the resource's representation;
+// without any precondition it answers 428 when REQUIRE_IF_MATCH is set.
+func checkPrecondition(c *gin.Context, version uint, requested uint, current interface{}) bool {
+	if match := c.GetHeader("If-Match"); match != "" {
+		for _, etag := range strings.Split(match, ",") {
+			if strings.TrimSpace(etag) == "*" {
+				return true
+			}
+			if tagged, ok := utils.ETagVersion(etag); ok && tagged == version {
+				return true
+			}
+		}
+		respondVersioned(c, http.StatusPreconditionFailed, version, current)
+		return false
+	}
+	if requested != 0 {
+		if requested == version {
+			return true
+		}
+		respondVersioned(c, http.StatusPreconditionFailed, version, current)
+		return false
+	}
+	if utils.RequireIfMatch() {
+		c.JSON(http.StatusPreconditionRequired, utils.HTTPError{
+			Code:    http.StatusPreconditionRequired,
+			Message: "If-Match or version is required",
+		})
+		return false
+	}
+	return true
+}
+
+// preconditionFailed answers a conditional write that matched no row, as
+// the resource changed or went away since it was read. The resource is read
+// again into dest, whose version is given by version.
+func preconditionFailed(c *gin.Context, db *gorm.DB, id uint, dest interface{}, version func() uint, notFound string) {
+	if err := db.First(dest, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, utils.HTTPError{
+				Code:    http.StatusNotFound,
+				Message: notFound,
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, utils.HTTPError{
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
+			})
+		}
+		return
+	}
+	respondVersioned(c, http.StatusPreconditionFailed, version(), dest)
+}
+
+// respondVersioned writes a representation of a versioned resource with its
+// ETag, so the client can make its next write conditional on it.
+func respondVersioned(c *gin.Context, status int, version uint, obj interface{}) {
+	body, err := json.Marshal(obj)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	c.Header("ETag", utils.VersionETag(version, body))
+	c.Data(status, "application/json; charset=utf-8", body)
+}
+
+// queryVersion is the ?version= precondition of a delete, 0 without one.
+// It answers 400 when the version is not a number.
+func queryVersion(c *gin.Context) (uint, bool) {
+	value := c.Query("version")
+	if value == "" {
+		return 0, true
+	}
+	version, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid version",
+		})
+		return 0, false
+	}
+	return uint(version), true
+}
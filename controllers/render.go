@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"cms-backend/utils"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// prepareContentFormat defaults and validates the format of a body about to
+// be written and caches its sanitized HTML rendering. Bodies projected from
+// blocks are always plain text.
+func prepareContentFormat(format *string, hasBlocks bool, content string, html *string) error {
+	if hasBlocks || *format == "" {
+		*format = utils.FormatPlain
+	}
+	if !utils.IsValidContentFormat(*format) {
+		return fmt.Errorf("content_format must be one of markdown, html or plain")
+	}
+	rendered, err := utils.RenderHTML(*format, content)
+	if err != nil {
+		return err
+	}
+	*html = rendered
+	return nil
+}
+
+// presentHTML prepares a body for a read response. An HTML body is served
+// sanitized like its rendering, since it reaches clients as markup too.
+// ?render=html keeps the cached HTML (or renders it, for translated or not
+// yet cached bodies); otherwise it is dropped.
+func presentHTML(c *gin.Context, translated bool, format string, content, html *string) error {
+	if format == utils.FormatHTML {
+		sanitized, err := utils.RenderHTML(format, *content)
+		if err != nil {
+			return err
+		}
+		*content = sanitized
+	}
+	if c.Query("render") != "html" {
+		*html = ""
+		return nil
+	}
+	if !translated && *html != "" {
+		return nil
+	}
+	rendered, err := utils.RenderHTML(format, *content)
+	if err != nil {
+		return err
+	}
+	*html = rendered
+	return nil
+}
+
+// setLastModified raises the response's Last-Modified to t. Read handlers
+// call it for everything a response is made of, so If-Modified-Since is
+// only answered with 304 when none of it changed.
+func setLastModified(c *gin.Context, t time.Time) {
+	if t.IsZero() {
+		return
+	}
+	if current, err := http.ParseTime(c.Writer.Header().Get("Last-Modified")); err == nil && !t.After(current) {
+		return
+	}
+	c.Header("Last-Modified", t.UTC().Format(http.TimeFormat))
+}
@@ -0,0 +1,194 @@
+package controllers
+
+import (
+	"cms-backend/models"
+	"cms-backend/utils"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultSuggestLimit = 10
+	maxSuggestLimit     = 25
+)
+
+// searchSources are the ?type= values accepted by /search.
+var searchSources = map[string]bool{
+	models.ResourcePost: true,
+	models.ResourcePage: true,
+}
+
+// Search runs a ranked full-text query over posts and pages on the
+// configured search backend, with facet counts and, on Postgres, a spelling
+// suggestion when nothing matches.
+func Search(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: "Query parameter q is required",
+		})
+		return
+	}
+
+	page, perPage, err := paginationParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	types, err := searchTypes(c.Query("type"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	year := 0
+	if value := c.Query("year"); value != "" {
+		if year, err = strconv.Atoi(value); err != nil {
+			c.JSON(http.StatusBadRequest, utils.HTTPError{
+				Code:    http.StatusBadRequest,
+				Message: "year must be an integer",
+			})
+			return
+		}
+	}
+
+	response, err := searchIndex(c).Search(utils.SearchQuery{
+		SiteID:   currentSiteID(c),
+		Text:     q,
+		Types:    types,
+		Author:   c.Query("author"),
+		Category: c.Query("category"),
+		Year:     year,
+		Page:     page,
+		PerPage:  perPage,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// SearchSuggest offers autocomplete for titles and author names by trigram
+// word similarity, so partial and slightly misspelt input still matches.
+// It always runs on Postgres.
+func SearchSuggest(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: "Query parameter q is required",
+		})
+		return
+	}
+
+	limit := defaultSuggestLimit
+	if value := c.Query("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 || limit > maxSuggestLimit {
+			c.JSON(http.StatusBadRequest, utils.HTTPError{
+				Code:    http.StatusBadRequest,
+				Message: fmt.Sprintf("limit must be between 1 and %d", maxSuggestLimit),
+			})
+			return
+		}
+	}
+
+	// Raw SQL bypasses the tenant scoping, so each branch names its site.
+	condition, siteArgs := utils.SiteCondition("site_id", currentSiteID(c))
+	joinedCondition, _ := utils.SiteCondition("p.site_id", currentSiteID(c))
+	var args []interface{}
+	for i := 0; i < 3; i++ {
+		args = append(append(append(args, q), siteArgs...), q)
+	}
+	args = append(args, limit)
+
+	suggestions := []models.SearchSuggestion{}
+	if err := db.Raw(`SELECT * FROM (
+SELECT 'post' AS type, id, title AS text, word_similarity(?, title) AS score FROM posts WHERE status = 'published'`+condition+` AND ? <% title
+UNION ALL SELECT 'page', id, title, word_similarity(?, title) FROM pages WHERE true`+condition+` AND ? <% title
+UNION ALL SELECT 'author', NULL, name, word_similarity(?, name) FROM (SELECT DISTINCT pc.name FROM post_contributors pc JOIN posts p ON p.id = pc.post_id WHERE pc.role = 'author' AND p.status = 'published'`+joinedCondition+`) authors WHERE ? <% name
+) suggestions ORDER BY score DESC, text LIMIT ?`, args...).Scan(&suggestions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, suggestions)
+}
+
+func searchTypes(value string) ([]string, error) {
+	if value == "" {
+		return []string{models.ResourcePost, models.ResourcePage}, nil
+	}
+	var types []string
+	for _, t := range strings.Split(value, ",") {
+		t = strings.TrimSpace(t)
+		if !searchSources[t] {
+			return nil, fmt.Errorf("type must be a comma-separated list of post and page")
+		}
+		types = append(types, t)
+	}
+	return types, nil
+}
+
+// searchIndex is the configured search backend, falling back to Postgres
+// full-text search on the request's connection.
+func searchIndex(c *gin.Context) utils.SearchIndex {
+	if index, ok := c.Get("search"); ok {
+		return index.(utils.SearchIndex)
+	}
+	return utils.NewPostgresSearchIndex(c.MustGet("db").(*gorm.DB))
+}
+
+// indexPost feeds a committed post write to the search backend, keeping
+// drafts out of it. The write already succeeded, so indexing failures are
+// logged rather than returned; the reindex command repairs the index.
+func indexPost(c *gin.Context, db *gorm.DB, post models.Post) {
+	index := searchIndex(c)
+	if _, ok := index.(*utils.PostgresSearchIndex); ok {
+		return
+	}
+	if post.Status != models.PostStatusPublished {
+		unindex(c, models.ResourcePost, post.ID)
+		return
+	}
+	if post.Contributors == nil {
+		db.Where("post_id = ?", post.ID).Find(&post.Contributors)
+	}
+	if err := index.Index(utils.PostSearchDocument(post)); err != nil {
+		log.Printf("Failed to index post %d: %v", post.ID, err)
+	}
+}
+
+func indexPage(c *gin.Context, page models.Page) {
+	if err := searchIndex(c).Index(utils.PageSearchDocument(page)); err != nil {
+		log.Printf("Failed to index page %d: %v", page.ID, err)
+	}
+}
+
+func unindex(c *gin.Context, resourceType string, id uint) {
+	if err := searchIndex(c).Delete(resourceType, id); err != nil {
+		log.Printf("Failed to remove %s %d from the search index: %v", resourceType, id, err)
+	}
+}
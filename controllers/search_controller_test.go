@@ -0,0 +1,404 @@
+package controllers
+
+import (
+	"cms-backend/models"
+	"cms-backend/utils"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func TestSearch(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`WITH m AS \(SELECT 'post' AS type, .* FROM posts t, websearch_to_tsquery\(\$1::regconfig, \$2\) query WHERE t\.search_vector @@ query AND t\.status = 'published' UNION ALL SELECT 'page' AS type, .* FROM pages t, websearch_to_tsquery\(\$3::regconfig, \$4\) query WHERE t\.search_vector @@ query\) SELECT 'type' AS facet`).
+		WithArgs("english", "hiking boots", "english", "hiking boots").
+		WillReturnRows(sqlmock.NewRows([]string{"facet", "value", "count"}).
+			AddRow("author", "Ann", 2).
+			AddRow("category", "outdoors", 3).
+			AddRow("type", "post", 2).
+			AddRow("type", "page", 1).
+			AddRow("year", "2024", 3))
+	mock.ExpectQuery(`WITH m AS \(.*\) SELECT type, id, ts_headline\(\$5::regconfig, title, query, \$6\) AS title, .* FROM m ORDER BY rank DESC, updated_at DESC, id LIMIT \$9 OFFSET \$10`).
+		WithArgs("english", "hiking boots", "english", "hiking boots", "english", "HighlightAll=true, StartSel=\x02, StopSel=\x03", "english", "StartSel=\x02, StopSel=\x03, MaxFragments=2, MaxWords=30, MinWords=10", 2, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "id", "title", "snippet", "rank", "created_at", "updated_at"}).
+			AddRow("page", 4, "Gear guide", "Our \x02hiking\x03 \x02boots\x03", 0.2, time.Now(), time.Now()))
+
+	router.GET("/search", Search)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/search?q=hiking+boots&page=2&per_page=2", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response models.SearchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+	if response.Total != 3 || response.Page != 2 || response.PerPage != 2 {
+		t.Fatalf("Expected total 3 on page 2 of 2, but got %+v", response)
+	}
+	if len(response.Results) != 1 || response.Results[0].Type != "page" || response.Results[0].Snippet != "Our <mark>hiking</mark> <mark>boots</mark>" {
+		t.Fatalf("Expected the highlighted page result, but got %+v", response.Results)
+	}
+	if len(response.Facets["type"]) != 2 || response.Facets["author"][0].Value != "Ann" || response.Facets["year"][0].Count != 3 {
+		t.Fatalf("Expected facet buckets, but got %+v", response.Facets)
+	}
+}
+
+func TestSearchEscapesHighlights(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`WITH m AS \(.*\) SELECT 'type' AS facet`).
+		WillReturnRows(sqlmock.NewRows([]string{"facet", "value", "count"}).AddRow("type", "post", 1))
+	mock.ExpectQuery(`WITH m AS \(.*\) SELECT type, id`).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "id", "title", "snippet", "rank"}).
+			AddRow("post", 1, "<b>\x02Hiking\x03</b>", "\x02hiking\x03 <script>alert(1)</script>", 0.1))
+
+	router.GET("/search", Search)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/search?q=hiking", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	var response models.SearchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+	if len(response.Results) != 1 {
+		t.Fatalf("Expected 1 result, but got %+v", response.Results)
+	}
+	if response.Results[0].Title != "&lt;b&gt;<mark>Hiking</mark>&lt;/b&gt;" {
+		t.Fatalf("Expected an escaped title, but got '%s'", response.Results[0].Title)
+	}
+	if response.Results[0].Snippet != "<mark>hiking</mark> &lt;script&gt;alert(1)&lt;/script&gt;" {
+		t.Fatalf("Expected an escaped snippet, but got '%s'", response.Results[0].Snippet)
+	}
+}
+
+func TestSearchWithFacetFilters(t *testing.T) {
+	t.Setenv("SEARCH_LANGUAGE", "german")
+
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`WITH m AS \(SELECT 'post' AS type, .* FROM posts t, websearch_to_tsquery\(\$1::regconfig, \$2\) query WHERE t\.search_vector @@ query AND t\.status = 'published' AND EXISTS \(SELECT 1 FROM post_contributors pc WHERE pc\.post_id = t\.id AND pc\.name = \$3 AND pc\.role = 'author'\) AND t\.fields->>'category' = \$4 AND extract\(year FROM t\.created_at\) = \$5\) SELECT 'type' AS facet`).
+		WithArgs("german", "wandern", "Ann", "outdoors", 2024).
+		WillReturnRows(sqlmock.NewRows([]string{"facet", "value", "count"}).AddRow("type", "post", 1))
+	mock.ExpectQuery(`WITH m AS \(.*\) SELECT type, id`).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "id", "title", "snippet", "rank"}).AddRow("post", 1, "Wandern", "", 0.1))
+
+	router.GET("/search", Search)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/search?q=wandern&author=Ann&category=outdoors&year=2024", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestSearchDidYouMean(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`WITH m AS \(.*\) SELECT 'type' AS facet`).
+		WillReturnRows(sqlmock.NewRows([]string{"facet", "value", "count"}))
+	mock.ExpectQuery(`SELECT word FROM \(.* FROM post_contributors pc JOIN posts p ON p\.id = pc\.post_id WHERE p\.status = 'published' \) words WHERE length\(word\) > 2 AND word % \$1 ORDER BY similarity\(word, \$2\) DESC, word LIMIT 1`).
+		WithArgs("hikking", "hikking").
+		WillReturnRows(sqlmock.NewRows([]string{"word"}).AddRow("hiking"))
+	mock.ExpectQuery(`SELECT word FROM`).
+		WithArgs("boots", "boots").
+		WillReturnRows(sqlmock.NewRows([]string{"word"}).AddRow("boots"))
+
+	router.GET("/search", Search)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/search?q=Hikking+boots", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response models.SearchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+	if response.Total != 0 || len(response.Results) != 0 {
+		t.Fatalf("Expected no results, but got %+v", response)
+	}
+	if response.DidYouMean != "hiking boots" {
+		t.Fatalf("Expected did_you_mean 'hiking boots', but got '%s'", response.DidYouMean)
+	}
+}
+
+func TestSearchValidation(t *testing.T) {
+	for _, query := range []string{"", "?q=x&type=media", "?q=x&per_page=500", "?q=x&page=0", "?q=x&year=last"} {
+		router, _, mock := utils.SetupRouterAndMockDB(t)
+
+		router.GET("/search", Search)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/search"+query, nil)
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("Expected status 400 for %q, but got %d", query, w.Code)
+		}
+		mock.ExpectClose()
+	}
+}
+
+func TestSearchDatabaseError(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`WITH m AS`).WillReturnError(gorm.ErrInvalidDB)
+
+	router.GET("/search", Search)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/search?q=x", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected status 500, but got %d", w.Code)
+	}
+}
+
+func TestSearchSuggest(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT \* FROM \( SELECT 'post' AS type, id, title AS text, word_similarity\(\$1, title\) AS score FROM posts WHERE status = 'published' AND \$2 <% title .*\) suggestions ORDER BY score DESC, text LIMIT \$7`).
+		WithArgs("hik", "hik", "hik", "hik", "hik", "hik", 5).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "id", "text", "score"}).
+			AddRow("post", 1, "Hiking in the Alps", 0.75).
+			AddRow("author", nil, "Hikaru", 0.5))
+
+	router.GET("/search/suggest", SearchSuggest)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/search/suggest?q=hik&limit=5", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response []models.SearchSuggestion
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+	if len(response) != 2 || response[0].ID == nil || *response[0].ID != 1 || response[1].ID != nil {
+		t.Fatalf("Expected a post and an author suggestion, but got %+v", response)
+	}
+}
+
+func TestSearchSuggestScopedToSite(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`FROM posts WHERE status = 'published' AND site_id = \$2 AND \$3 <% title .* FROM pages WHERE true AND site_id = \$5 .* WHERE pc\.role = 'author' AND p\.status = 'published' AND p\.site_id = \$8\) authors`).
+		WithArgs("hik", 2, "hik", "hik", 2, "hik", "hik", 2, "hik", 10).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "id", "text", "score"}))
+
+	router.Use(func(c *gin.Context) { c.Set("site", models.Site{ID: 2, Slug: "brand"}) })
+	router.GET("/search/suggest", SearchSuggest)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/search/suggest?q=hik", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestSearchSuggestInvalidLimit(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	router.GET("/search/suggest", SearchSuggest)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/search/suggest?q=hik&limit=100", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, but got %d", w.Code)
+	}
+}
+
+func setupBleveSearch(t *testing.T) *utils.BleveSearchIndex {
+	index, err := utils.NewMemoryBleveSearchIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { index.Close() })
+	return index
+}
+
+func TestSearchWithBleveIndex(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	index := setupBleveSearch(t)
+	created := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	for _, doc := range []utils.SearchDocument{
+		{Type: "post", ID: 1, Title: "Hiking boots reviewed", Content: "We tested ten pairs of boots.", Authors: []string{"Ann", "Bo"}, Category: "outdoors", CreatedAt: created, UpdatedAt: created},
+		{Type: "post", ID: 2, Title: "City walks", Content: "Comfortable boots for hiking the city.", Authors: []string{"Bo"}, CreatedAt: created.AddDate(1, 0, 0), UpdatedAt: created},
+		{Type: "page", ID: 3, Title: "About", Content: "A blog about hiking.", CreatedAt: created, UpdatedAt: created},
+	} {
+		if err := index.Index(doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	router.Use(func(c *gin.Context) { c.Set("search", utils.SearchIndex(index)) })
+	router.GET("/search", Search)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/search?q=hiking+boots", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response models.SearchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+	if response.Total != 2 || len(response.Results) != 2 {
+		t.Fatalf("Expected the two posts matching both words, but got %+v", response)
+	}
+	if response.Results[0].ID != 1 || response.Results[0].Title != "<mark>Hiking</mark> <mark>boots</mark> reviewed" {
+		t.Fatalf("Expected the title match ranked first and highlighted, but got %+v", response.Results[0])
+	}
+	if !response.Results[0].CreatedAt.Equal(created) {
+		t.Fatalf("Expected created_at %v, but got %v", created, response.Results[0].CreatedAt)
+	}
+	if len(response.Facets["author"]) != 2 || response.Facets["author"][0] != (models.FacetBucket{Value: "Bo", Count: 2}) {
+		t.Fatalf("Expected author buckets, but got %+v", response.Facets["author"])
+	}
+	if len(response.Facets["year"]) != 2 || len(response.Facets["category"]) != 1 {
+		t.Fatalf("Expected year and category buckets, but got %+v", response.Facets)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/search?q=hiking+-city&author=Ann&year=2024", nil)
+	router.ServeHTTP(w, req)
+
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+	if response.Total != 1 || response.Results[0].ID != 1 {
+		t.Fatalf("Expected only post 1 to match the filters, but got %+v", response)
+	}
+}
+
+func TestSearchWithBleveIndexEscapesTitles(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	index := setupBleveSearch(t)
+	if err := index.Index(utils.SearchDocument{Type: "post", ID: 1, Title: `<img src=x onerror=alert(1)>`, Content: "Hiking in the hills."}); err != nil {
+		t.Fatal(err)
+	}
+
+	router.Use(func(c *gin.Context) { c.Set("search", utils.SearchIndex(index)) })
+	router.GET("/search", Search)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/search?q=hiking", nil)
+	router.ServeHTTP(w, req)
+
+	var response models.SearchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+	if len(response.Results) != 1 || response.Results[0].Title != "&lt;img src=x onerror=alert(1)&gt;" {
+		t.Fatalf("Expected the title escaped, but got %+v", response.Results)
+	}
+}
+
+func TestDeletePageRemovesFromSearchIndex(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	index := setupBleveSearch(t)
+	if err := index.Index(utils.SearchDocument{Type: "page", ID: 1, Title: "About", Content: "A blog about hiking."}); err != nil {
+		t.Fatal(err)
+	}
+
+	mock.ExpectQuery(`SELECT \* FROM "pages" WHERE "pages"\."id" = \$1`).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "content"}).AddRow(1, "About", "A blog about hiking."))
+	expectNoLock(mock)
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM "pages"`).WithArgs(sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(1, 1))
+	expectAudit(mock, models.AuditActionDelete, models.ResourcePage, 1)
+	expectTranslationsDeleted(mock, models.ResourcePage, 1)
+	expectOutbox(mock, 1, models.EventPageDeleted)
+	mock.ExpectCommit()
+
+	router.Use(func(c *gin.Context) { c.Set("search", utils.SearchIndex(index)) })
+	router.DELETE("/pages/:id", DeletePage)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodDelete, "/pages/1", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	response, err := index.Search(utils.SearchQuery{Text: "hiking", Types: []string{"page"}, Page: 1, PerPage: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Total != 0 {
+		t.Fatalf("Expected the page to be removed from the index, but got %+v", response.Results)
+	}
+}
+
+func TestCreatePostFeedsSearchIndex(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	index := setupBleveSearch(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "posts"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectQuery(`INSERT INTO "post_contributors"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	expectAudit(mock, models.AuditActionCreate, models.ResourcePost, 7)
+	expectOutbox(mock, 7, models.EventPostCreated, models.EventPostPublished)
+	mock.ExpectCommit()
+
+	router.Use(func(c *gin.Context) { c.Set("search", utils.SearchIndex(index)) })
+	router.POST("/posts", CreatePost)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/posts", strings.NewReader(`{"title":"Trail running","content":"Shoes for muddy trails.","author":"Ann"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	response, err := index.Search(utils.SearchQuery{Text: "trails", Types: []string{"post"}, Author: "Ann", Page: 1, PerPage: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Total != 1 || response.Results[0].ID != 7 {
+		t.Fatalf("Expected the new post to be indexed, but got %+v", response.Results)
+	}
+}
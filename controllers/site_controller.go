@@ -0,0 +1,272 @@
+package controllers
+
+import (
+	"cms-backend/models"
+	"cms-backend/utils"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var siteSlug = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,49}$`)
+
+type siteInput struct {
+	Slug   string  `json:"slug" binding:"required"`
+	Name   string  `json:"name" binding:"required"`
+	Domain *string `json:"domain"`
+}
+
+// siteContentTables are checked before a site is deleted; a site still
+// owning rows in any of them cannot be removed.
+var siteContentTables = []string{"pages", "posts", "media", "content_types", "api_keys"}
+
+// currentSiteID is the site the request was resolved to, or 0 outside one.
+func currentSiteID(c *gin.Context) uint {
+	if value, ok := c.Get("site"); ok {
+		return value.(models.Site).ID
+	}
+	return 0
+}
+
+// GetCurrentSite returns the site the request was resolved to.
+func GetCurrentSite(c *gin.Context) {
+	c.JSON(http.StatusOK, c.MustGet("site"))
+}
+
+func GetSites(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var sites []models.Site
+	if err := db.Order("id").Find(&sites).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, sites)
+}
+
+func GetSite(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	site, ok := findSite(c, db)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, site)
+}
+
+func CreateSite(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var input siteInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	var site models.Site
+	if !applySiteInput(c, db, &site, input) {
+		return
+	}
+
+	tx := db.Begin()
+	if err := tx.Create(&site).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err := utils.NotifyInvalidation(tx, utils.Invalidation{SiteID: site.ID, Resource: models.ResourceSite}); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	tx.Commit()
+	c.JSON(http.StatusCreated, site)
+}
+
+func UpdateSite(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	site, ok := findSite(c, db)
+	if !ok {
+		return
+	}
+
+	var input siteInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	if !applySiteInput(c, db, &site, input) {
+		return
+	}
+
+	tx := db.Begin()
+	if err := tx.Save(&site).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err := utils.NotifyInvalidation(tx, utils.Invalidation{SiteID: site.ID, Resource: models.ResourceSite}); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	tx.Commit()
+	c.JSON(http.StatusOK, site)
+}
+
+// DeleteSite removes an empty site. The default site and sites that still
+// own content are kept.
+func DeleteSite(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	site, ok := findSite(c, db)
+	if !ok {
+		return
+	}
+	if site.ID == models.DefaultSiteID {
+		c.JSON(http.StatusConflict, utils.HTTPError{
+			Code:    http.StatusConflict,
+			Message: "The default site cannot be deleted",
+		})
+		return
+	}
+
+	for _, table := range siteContentTables {
+		var count int64
+		if err := db.Table(table).Where("site_id = ?", site.ID).Count(&count).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, utils.HTTPError{
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
+			})
+			return
+		}
+		if count > 0 {
+			c.JSON(http.StatusConflict, utils.HTTPError{
+				Code:    http.StatusConflict,
+				Message: "Site still has " + strings.ReplaceAll(table, "_", " ") + "; delete them first",
+			})
+			return
+		}
+	}
+
+	tx := db.Begin()
+	if err := tx.Delete(&site).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err := utils.NotifyInvalidation(tx, utils.Invalidation{SiteID: site.ID, Resource: models.ResourceSite}); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	tx.Commit()
+	c.JSON(http.StatusOK, utils.MessageResponse{
+		Message: "Site deleted successfully",
+	})
+}
+
+func findSite(c *gin.Context, db *gorm.DB) (models.Site, bool) {
+	var site models.Site
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid site ID",
+		})
+		return site, false
+	}
+	if err := db.First(&site, uint(id)).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, utils.HTTPError{
+				Code:    http.StatusNotFound,
+				Message: "Site not found",
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, utils.HTTPError{
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
+			})
+		}
+		return site, false
+	}
+	return site, true
+}
+
+// applySiteInput validates input onto site, answering 400 for a bad slug
+// and 409 when another site already uses the slug or domain.
+func applySiteInput(c *gin.Context, db *gorm.DB, site *models.Site, input siteInput) bool {
+	slug := strings.ToLower(strings.TrimSpace(input.Slug))
+	if !siteSlug.MatchString(slug) {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: "Slug must contain only lowercase letters, digits or '-' and be at most 50 characters",
+		})
+		return false
+	}
+	var domain *string
+	if input.Domain != nil {
+		if value := strings.ToLower(strings.TrimSpace(*input.Domain)); value != "" {
+			domain = &value
+		}
+	}
+
+	query := db.Model(&models.Site{}).Where("id <> ?", site.ID)
+	if domain != nil {
+		query = query.Where("slug = ? OR domain = ?", slug, *domain)
+	} else {
+		query = query.Where("slug = ?", slug)
+	}
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return false
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, utils.HTTPError{
+			Code:    http.StatusConflict,
+			Message: "Another site already uses this slug or domain",
+		})
+		return false
+	}
+
+	site.Slug = slug
+	site.Name = strings.TrimSpace(input.Name)
+	site.Domain = domain
+	return true
+}
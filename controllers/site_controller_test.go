@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"cms-backend/models"
+	"cms-backend/utils"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestCreateSite(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "sites" WHERE id <> \$1 AND \(slug = \$2 OR domain = \$3\)`).
+		WithArgs(0, "brand", "brand.example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "sites" \("slug","name","domain","created_at","updated_at"\)`).
+		WithArgs("brand", "Brand", "brand.example.com", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectExec(`SELECT pg_notify\(\$1, \$2\)`).
+		WithArgs(utils.InvalidationChannel, `{"site_id":2,"resource":"site"}`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	router.POST("/sites", CreateSite)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/sites", strings.NewReader(`{"slug":"Brand","name":"Brand","domain":" Brand.example.com "}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	var site models.Site
+	if err := json.Unmarshal(w.Body.Bytes(), &site); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+	if site.ID != 2 || site.Slug != "brand" || site.Domain == nil || *site.Domain != "brand.example.com" {
+		t.Fatalf("Expected the normalized site, but got %+v", site)
+	}
+}
+
+func TestCreateSiteInvalidSlug(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	router.POST("/sites", CreateSite)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/sites", strings.NewReader(`{"slug":"my site","name":"Brand"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, but got %d", w.Code)
+	}
+}
+
+func TestCreateSiteDuplicate(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "sites" WHERE id <> \$1 AND slug = \$2`).
+		WithArgs(0, "brand").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	router.POST("/sites", CreateSite)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/sites", strings.NewReader(`{"slug":"brand","name":"Brand"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("Expected status 409, but got %d", w.Code)
+	}
+}
+
+func TestDeleteSiteWithContent(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT \* FROM "sites" WHERE "sites"\."id" = \$1`).
+		WithArgs(2, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "slug", "name"}).AddRow(2, "brand", "Brand"))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "pages" WHERE site_id = \$1`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "posts" WHERE site_id = \$1`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	router.DELETE("/sites/:id", DeleteSite)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodDelete, "/sites/2", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("Expected status 409, but got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestDeleteDefaultSite(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT \* FROM "sites" WHERE "sites"\."id" = \$1`).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "slug", "name"}).AddRow(1, "default", "Default"))
+
+	router.DELETE("/sites/:id", DeleteSite)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodDelete, "/sites/1", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("Expected status 409, but got %d", w.Code)
+	}
+}
@@ -0,0 +1,458 @@
+package controllers
+
+import (
+	"cms-backend/models"
+	"cms-backend/utils"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	TranslationMissing  = "missing"
+	TranslationOutdated = "outdated"
+)
+
+type translationInput struct {
+	Title   string `json:"title" binding:"required"`
+	Content string `json:"content" binding:"required"`
+}
+
+func GetPageTranslations(c *gin.Context)   { getTranslations(c, models.ResourcePage) }
+func UpsertPageTranslation(c *gin.Context) { upsertTranslation(c, models.ResourcePage) }
+func DeletePageTranslation(c *gin.Context) { deleteTranslation(c, models.ResourcePage) }
+
+func GetPostTranslations(c *gin.Context)   { getTranslations(c, models.ResourcePost) }
+func UpsertPostTranslation(c *gin.Context) { upsertTranslation(c, models.ResourcePost) }
+func DeletePostTranslation(c *gin.Context) { deleteTranslation(c, models.ResourcePost) }
+
+// GetTranslationStatus lists every page/post and locale pair whose
+// translation is missing or older than the source item.
+func GetTranslationStatus(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	resourceTypes := []string{models.ResourcePage, models.ResourcePost}
+	if resourceType := c.Query("type"); resourceType != "" {
+		if resourceType != models.ResourcePage && resourceType != models.ResourcePost {
+			c.JSON(http.StatusBadRequest, utils.HTTPError{
+				Code:    http.StatusBadRequest,
+				Message: "Invalid resource type",
+			})
+			return
+		}
+		resourceTypes = []string{resourceType}
+	}
+
+	var locales []string
+	if locale := utils.NormalizeLocale(c.Query("locale")); locale != "" {
+		locales = []string{locale}
+	} else {
+		for _, l := range utils.SupportedLocales() {
+			if l != utils.SourceLocale() {
+				locales = append(locales, l)
+			}
+		}
+	}
+
+	statuses := []models.TranslationStatus{}
+	for _, resourceType := range resourceTypes {
+		var sources []struct {
+			ID        uint
+			Title     string
+			UpdatedAt time.Time
+		}
+		if err := visibleTranslatables(c, db, resourceType).Select("id", "title", "updated_at").Order("id").Find(&sources).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, utils.HTTPError{
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
+			})
+			return
+		}
+
+		var translations []models.Translation
+		if err := db.Where("resource_type = ? AND locale IN ?", resourceType, locales).Find(&translations).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, utils.HTTPError{
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
+			})
+			return
+		}
+		byKey := make(map[string]models.Translation, len(translations))
+		for _, t := range translations {
+			byKey[strconv.FormatUint(uint64(t.ResourceID), 10)+"/"+t.Locale] = t
+		}
+
+		for _, source := range sources {
+			for _, locale := range locales {
+				status := models.TranslationStatus{
+					ResourceType:    resourceType,
+					ResourceID:      source.ID,
+					Title:           source.Title,
+					Locale:          locale,
+					SourceUpdatedAt: source.UpdatedAt,
+				}
+				t, ok := byKey[strconv.FormatUint(uint64(source.ID), 10)+"/"+locale]
+				switch {
+				case !ok:
+					status.Status = TranslationMissing
+				case t.Outdated(source.UpdatedAt):
+					status.Status = TranslationOutdated
+					status.TranslatedAt = &t.UpdatedAt
+				default:
+					continue
+				}
+				statuses = append(statuses, status)
+			}
+		}
+	}
+	c.JSON(http.StatusOK, statuses)
+}
+
+func getTranslations(c *gin.Context, resourceType string) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	id, ok := translatableID(c, resourceType)
+	if !ok {
+		return
+	}
+	source, ok := findTranslatable(c, db, resourceType, id)
+	if !ok {
+		return
+	}
+
+	var translations []models.Translation
+	if err := db.Where("resource_type = ? AND resource_id = ?", resourceType, id).Order("locale").Find(&translations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	// Translations share their source's format, so HTML is sanitized as on
+	// the source's own reads.
+	if source.ContentFormat == utils.FormatHTML {
+		for i := range translations {
+			sanitized, err := utils.RenderHTML(utils.FormatHTML, translations[i].Content)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, utils.HTTPError{
+					Code:    http.StatusInternalServerError,
+					Message: err.Error(),
+				})
+				return
+			}
+			translations[i].Content = sanitized
+		}
+	}
+	c.JSON(http.StatusOK, translations)
+}
+
+func upsertTranslation(c *gin.Context, resourceType string) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	id, ok := translatableID(c, resourceType)
+	if !ok {
+		return
+	}
+	locale := utils.NormalizeLocale(c.Param("locale"))
+	if !utils.IsSupportedLocale(locale) || locale == utils.SourceLocale() {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: "Unsupported translation locale",
+		})
+		return
+	}
+
+	var input translationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	source, ok := findTranslatable(c, db, resourceType, id)
+	if !ok {
+		return
+	}
+
+	translation := models.Translation{
+		ResourceType:    resourceType,
+		ResourceID:      id,
+		Locale:          locale,
+		Title:           input.Title,
+		Content:         input.Content,
+		SourceUpdatedAt: source.UpdatedAt,
+	}
+
+	tx := db.Begin()
+	var existing []models.Translation
+	if err := tx.Where("resource_type = ? AND resource_id = ? AND locale = ?", resourceType, id, locale).Limit(1).Find(&existing).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	var before *models.Translation
+	if len(existing) > 0 {
+		before = &existing[0]
+		translation.CreatedAt = before.CreatedAt
+	}
+	if err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "resource_type"}, {Name: "resource_id"}, {Name: "locale"}},
+		DoUpdates: clause.AssignmentColumns([]string{"title", "content", "source_updated_at", "updated_at"}),
+	}).Create(&translation).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err := recordTranslationSaved(c, tx, before, translation); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err := invalidateCaches(c, tx, resourceType, id); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	tx.Commit()
+	notifyCommitted(c)
+	c.JSON(http.StatusOK, translation)
+}
+
+func deleteTranslation(c *gin.Context, resourceType string) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	id, ok := translatableID(c, resourceType)
+	if !ok {
+		return
+	}
+	locale := utils.NormalizeLocale(c.Param("locale"))
+
+	tx := db.Begin()
+	var translations []models.Translation
+	result := tx.Clauses(clause.Returning{}).Where("resource_type = ? AND resource_id = ? AND locale = ?", resourceType, id, locale).Delete(&translations)
+	if result.Error != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: result.Error.Error(),
+		})
+		return
+	}
+	if len(translations) == 0 {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, utils.HTTPError{
+			Code:    http.StatusNotFound,
+			Message: "Translation not found",
+		})
+		return
+	}
+	if err := recordTranslationsDeleted(c, tx, translations); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err := invalidateCaches(c, tx, resourceType, id); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	tx.Commit()
+	notifyCommitted(c)
+	c.JSON(http.StatusOK, utils.MessageResponse{
+		Message: "Translation deleted successfully",
+	})
+}
+
+// deleteTranslations deletes every translation of a page or post as part
+// of tx, which deletes the page or post itself.
+func deleteTranslations(c *gin.Context, tx *gorm.DB, resourceType string, id uint) error {
+	var translations []models.Translation
+	if err := tx.Clauses(clause.Returning{}).Where("resource_type = ? AND resource_id = ?", resourceType, id).Delete(&translations).Error; err != nil {
+		return err
+	}
+	return recordTranslationsDeleted(c, tx, translations)
+}
+
+// recordTranslationSaved writes the audit entry and translation.created or
+// translation.updated event of a translation saved in tx; before is nil
+// when it was created. The caller invalidates the caches of its page or
+// post.
+func recordTranslationSaved(c *gin.Context, tx *gorm.DB, before *models.Translation, translation models.Translation) error {
+	action, eventType := models.AuditActionCreate, models.EventTranslationCreated
+	var previous interface{}
+	if before != nil {
+		action, eventType = models.AuditActionUpdate, models.EventTranslationUpdated
+		previous = before
+	}
+	if err := recordAudit(c, tx, action, models.ResourceTranslation, translation.ID, previous, translation); err != nil {
+		return err
+	}
+	snapshot, err := auditSnapshot(translation)
+	if err != nil {
+		return err
+	}
+	return utils.AppendOutbox(tx, []models.OutboxEvent{{
+		EventID:      utils.RandomToken()[:22],
+		Type:         eventType,
+		ResourceType: models.ResourceTranslation,
+		ResourceID:   translation.ID,
+		Data:         snapshot,
+	}})
+}
+
+// recordTranslationsDeleted writes the audit entries and translation.deleted
+// events of translations deleted in tx. The caller invalidates the caches
+// of their page or post.
+func recordTranslationsDeleted(c *gin.Context, tx *gorm.DB, translations []models.Translation) error {
+	events := make([]models.OutboxEvent, 0, len(translations))
+	for _, translation := range translations {
+		if err := recordAudit(c, tx, models.AuditActionDelete, models.ResourceTranslation, translation.ID, translation, nil); err != nil {
+			return err
+		}
+		snapshot, err := auditSnapshot(translation)
+		if err != nil {
+			return err
+		}
+		events = append(events, models.OutboxEvent{
+			EventID:      utils.RandomToken()[:22],
+			Type:         models.EventTranslationDeleted,
+			ResourceType: models.ResourceTranslation,
+			ResourceID:   translation.ID,
+			Data:         snapshot,
+		})
+	}
+	return utils.AppendOutbox(tx, events)
+}
+
+// localize overlays the best available translation along the fallback chain
+// of the requested locale and returns the locale actually served. Requests
+// without a locale preference are served from the source without a lookup.
+func localize(c *gin.Context, db *gorm.DB, resourceType string, resourceID uint, title, content *string) (string, error) {
+	source := utils.SourceLocale()
+	requested := utils.RequestedLocale(c)
+	if requested == "" || requested == source {
+		return "", nil
+	}
+
+	chain := utils.FallbackChain(requested)
+	var translations []models.Translation
+	if err := db.Where("resource_type = ? AND resource_id = ? AND locale IN ?", resourceType, resourceID, chain).Find(&translations).Error; err != nil {
+		return "", err
+	}
+
+	served := source
+	for _, locale := range chain {
+		if locale == source {
+			break
+		}
+		if t, ok := findTranslation(translations, locale); ok {
+			*title = t.Title
+			*content = t.Content
+			served = locale
+			setLastModified(c, t.UpdatedAt)
+			break
+		}
+	}
+	c.Header("Content-Language", served)
+	c.Header("Vary", "Accept-Language")
+	return served, nil
+}
+
+func findTranslation(translations []models.Translation, locale string) (models.Translation, bool) {
+	for _, t := range translations {
+		if t.Locale == locale {
+			return t, true
+		}
+	}
+	return models.Translation{}, false
+}
+
+func translatableTable(resourceType string) string {
+	if resourceType == models.ResourcePage {
+		return "pages"
+	}
+	return "posts"
+}
+
+func translatableName(resourceType string) string {
+	if resourceType == models.ResourcePage {
+		return "Page"
+	}
+	return "Post"
+}
+
+func translatableID(c *gin.Context, resourceType string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid " + resourceType + " ID",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// visibleTranslatables queries the pages or posts the caller may see,
+// leaving out draft posts unless they can view drafts.
+func visibleTranslatables(c *gin.Context, db *gorm.DB, resourceType string) *gorm.DB {
+	query := db.Table(translatableTable(resourceType))
+	if resourceType == models.ResourcePost && !can(c, models.PermViewDrafts) {
+		query = query.Where("status = ?", models.PostStatusPublished)
+	}
+	return query
+}
+
+// translatableSource is what translation endpoints need of a page or post.
+type translatableSource struct {
+	ID            uint
+	UpdatedAt     time.Time
+	ContentFormat string
+}
+
+// findTranslatable checks the source page/post exists and is visible to
+// the caller and returns it, writing the error response itself when it
+// does not.
+func findTranslatable(c *gin.Context, db *gorm.DB, resourceType string, id uint) (translatableSource, bool) {
+	var source translatableSource
+	err := visibleTranslatables(c, db, resourceType).Select("id", "updated_at", "content_format").Where("id = ?", id).Take(&source).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, utils.HTTPError{
+				Code:    http.StatusNotFound,
+				Message: translatableName(resourceType) + " not found",
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, utils.HTTPError{
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
+			})
+		}
+		return translatableSource{}, false
+	}
+	return source, true
+}
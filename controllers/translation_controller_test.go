@@ -0,0 +1,349 @@
+package controllers
+
+import (
+	"bytes"
+	"cms-backend/models"
+	"cms-backend/utils"
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/gorm"
+)
+
+// expectTranslationsDeleted expects the translations of a deleted page or
+// post to be deleted along with it, returning the given translation IDs.
+func expectTranslationsDeleted(mock sqlmock.Sqlmock, resourceType string, resourceID uint, ids ...uint) {
+	rows := sqlmock.NewRows([]string{"id", "resource_type", "resource_id", "locale"})
+	for _, id := range ids {
+		rows.AddRow(id, resourceType, resourceID, "de")
+	}
+	mock.ExpectQuery(`DELETE FROM "translations" WHERE resource_type = \$1 AND resource_id = \$2 RETURNING \*`).
+		WithArgs(resourceType, resourceID).
+		WillReturnRows(rows)
+	if len(ids) == 0 {
+		return
+	}
+	var args []driver.Value
+	outbox := sqlmock.NewRows([]string{"id"})
+	for _, id := range ids {
+		expectAudit(mock, models.AuditActionDelete, models.ResourceTranslation, id)
+		args = append(args, 1, sqlmock.AnyArg(), models.EventTranslationDeleted, models.ResourceTranslation, id, sqlmock.AnyArg(), sqlmock.AnyArg())
+		outbox.AddRow(id)
+	}
+	mock.ExpectQuery(`INSERT INTO "outbox"`).
+		WithArgs(args...).
+		WillReturnRows(outbox)
+}
+
+func TestGetPageWithLocale(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	pageUpdatedAt := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	translationUpdatedAt := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT \* FROM "pages" WHERE "pages"\."id" = \$1 ORDER BY "pages"\."id" LIMIT \$2`).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "content", "created_at", "updated_at"}).
+			AddRow(1, "About", "About us", pageUpdatedAt, pageUpdatedAt))
+	expectNoLock(mock)
+	mock.ExpectQuery(`SELECT \* FROM "translations" WHERE resource_type = \$1 AND resource_id = \$2 AND locale IN \(\$3,\$4\)`).
+		WithArgs("page", 1, "de", "en").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "resource_type", "resource_id", "locale", "title", "content", "updated_at"}).
+			AddRow(1, "page", 1, "de", "Über uns", "Über uns Inhalt", translationUpdatedAt))
+
+	router.GET("/pages/:id", GetPage)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/pages/1?locale=de", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d", w.Code)
+	}
+
+	var response models.Page
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+	if response.Title != "Über uns" {
+		t.Fatalf("Expected title 'Über uns', but got '%s'", response.Title)
+	}
+	if response.Locale != "de" {
+		t.Fatalf("Expected locale 'de', but got '%s'", response.Locale)
+	}
+	if w.Header().Get("Content-Language") != "de" {
+		t.Fatalf("Expected Content-Language 'de', but got '%s'", w.Header().Get("Content-Language"))
+	}
+	if lastModified := w.Header().Get("Last-Modified"); lastModified != "Sun, 01 Feb 2026 00:00:00 GMT" {
+		t.Fatalf("Expected the translation's Last-Modified, but got '%s'", lastModified)
+	}
+}
+
+func TestGetPostWithAcceptLanguageFallback(t *testing.T) {
+	t.Setenv("LOCALE_FALLBACKS", "de")
+
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE "posts"\."id" = \$1 ORDER BY "posts"\."id" LIMIT \$2`).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "content", "author", "created_at", "updated_at"}).
+			AddRow(1, "Hello", "English", "Ann", time.Now(), time.Now()))
+	mock.ExpectQuery(`SELECT \* FROM "post_contributors"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "post_id", "name", "role", "position"}))
+	mock.ExpectQuery(`SELECT \* FROM "post_media"`).
+		WillReturnRows(sqlmock.NewRows([]string{"post_id", "media_id"}))
+	expectNoLock(mock)
+	mock.ExpectQuery(`SELECT \* FROM "translations" WHERE resource_type = \$1 AND resource_id = \$2 AND locale IN \(\$3,\$4,\$5\)`).
+		WithArgs("post", 1, "fr", "de", "en").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "resource_type", "resource_id", "locale", "title", "content"}).
+			AddRow(1, "post", 1, "de", "Hallo", "Deutsch"))
+
+	router.GET("/posts/:id", GetPost)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/posts/1", nil)
+	req.Header.Set("Accept-Language", "it;q=0.9, fr-CH;q=0.8, fr;q=0.7")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d", w.Code)
+	}
+
+	var response models.Post
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+	if response.Title != "Hallo" || response.Locale != "de" {
+		t.Fatalf("Expected German fallback, but got '%s' (%s)", response.Title, response.Locale)
+	}
+}
+
+func TestUpsertPageTranslation(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	updatedAt := time.Now()
+	mock.ExpectQuery(`SELECT "id","updated_at","content_format" FROM "pages" WHERE id = \$1 LIMIT \$2`).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "updated_at"}).AddRow(1, updatedAt))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "translations" WHERE resource_type = \$1 AND resource_id = \$2 AND locale = \$3 LIMIT \$4`).
+		WithArgs("page", 1, "fr", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`INSERT INTO "translations" .* ON CONFLICT \("resource_type","resource_id","locale"\) DO UPDATE SET`).
+		WithArgs(1, "page", 1, "fr", "À propos", "Contenu", updatedAt, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	expectAudit(mock, models.AuditActionCreate, models.ResourceTranslation, 1)
+	expectOutbox(mock, 1, models.EventTranslationCreated)
+	mock.ExpectCommit()
+
+	body, _ := json.Marshal(map[string]string{"title": "À propos", "content": "Contenu"})
+
+	router.PUT("/pages/:id/translations/:locale", UpsertPageTranslation)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPut, "/pages/1/translations/FR", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d", w.Code)
+	}
+
+	var response models.Translation
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+	if response.Locale != "fr" {
+		t.Fatalf("Expected locale 'fr', but got '%s'", response.Locale)
+	}
+}
+
+func TestUpsertPostTranslationRecordsUpdate(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	updatedAt := time.Now()
+	mock.ExpectQuery(`SELECT "id","updated_at","content_format" FROM "posts" WHERE id = \$1`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "updated_at"}).AddRow(1, updatedAt))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "translations" WHERE resource_type = \$1 AND resource_id = \$2 AND locale = \$3 LIMIT \$4`).
+		WithArgs("post", 1, "de", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "resource_type", "resource_id", "locale", "title", "content"}).
+			AddRow(7, "post", 1, "de", "Alter Titel", "Alter Inhalt"))
+	mock.ExpectQuery(`INSERT INTO "translations" .* ON CONFLICT`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectQuery(`INSERT INTO "audit_logs"`).
+		WithArgs(1, sqlmock.AnyArg(), sqlmock.AnyArg(), models.AuditActionUpdate, models.ResourceTranslation, 7,
+			jsonArg(`"title":"Alter Titel"`), jsonArg(`"title":"Neuer Titel"`), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	expectOutbox(mock, 7, models.EventTranslationUpdated)
+	mock.ExpectCommit()
+
+	body, _ := json.Marshal(map[string]string{"title": "Neuer Titel", "content": "Neuer Inhalt"})
+
+	router.PUT("/posts/:id/translations/:locale", UpsertPostTranslation)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPut, "/posts/1/translations/de", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpsertPageTranslationSourceLocale(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	body, _ := json.Marshal(map[string]string{"title": "About", "content": "About us"})
+
+	router.PUT("/pages/:id/translations/:locale", UpsertPageTranslation)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPut, "/pages/1/translations/en", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, but got %d", w.Code)
+	}
+}
+
+func TestDeletePostTranslationNotFound(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`DELETE FROM "translations" WHERE resource_type = \$1 AND resource_id = \$2 AND locale = \$3 RETURNING \*`).
+		WithArgs("post", 1, "de").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+
+	router.DELETE("/posts/:id/translations/:locale", DeletePostTranslation)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodDelete, "/posts/1/translations/de", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("Expected status 404, but got %d", w.Code)
+	}
+}
+
+func TestDeletePostDeletesTranslations(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE "posts"\."id" = \$1`).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "content"}).AddRow(1, "Test Post", "Test Content"))
+	expectNoLock(mock)
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM "posts"`).
+		WithArgs(sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	expectAudit(mock, models.AuditActionDelete, models.ResourcePost, 1)
+	expectTranslationsDeleted(mock, models.ResourcePost, 1, 7, 8)
+	expectOutbox(mock, 1, models.EventPostDeleted)
+	mock.ExpectCommit()
+
+	router.DELETE("/posts/:id", DeletePost)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodDelete, "/posts/1", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d", w.Code)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetTranslationStatus(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT "id","title","updated_at" FROM "posts" ORDER BY id`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "updated_at"}).
+			AddRow(1, "Fresh", now).
+			AddRow(2, "Stale", now))
+	mock.ExpectQuery(`SELECT \* FROM "translations" WHERE resource_type = \$1 AND locale IN \(\$2,\$3\)`).
+		WithArgs("post", "de", "fr").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "resource_type", "resource_id", "locale", "source_updated_at", "updated_at"}).
+			AddRow(1, "post", 1, "de", now, now).
+			AddRow(2, "post", 1, "fr", now, now).
+			AddRow(3, "post", 2, "de", now.Add(-time.Hour), now.Add(-time.Hour)))
+
+	router.GET("/translations/status", GetTranslationStatus)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/translations/status?type=post", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d", w.Code)
+	}
+
+	var response []models.TranslationStatus
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+	if len(response) != 2 {
+		t.Fatalf("Expected 2 statuses, but got %d", len(response))
+	}
+	if response[0].ResourceID != 2 || response[0].Locale != "de" || response[0].Status != TranslationOutdated {
+		t.Fatalf("Expected post 2 outdated in de, but got %+v", response[0])
+	}
+	if response[1].ResourceID != 2 || response[1].Locale != "fr" || response[1].Status != TranslationMissing {
+		t.Fatalf("Expected post 2 missing in fr, but got %+v", response[1])
+	}
+}
+
+func TestGetTranslationStatusHidesDrafts(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDBAs(t, nil)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT "id","title","updated_at" FROM "posts" WHERE status = \$1 ORDER BY id`).
+		WithArgs(models.PostStatusPublished).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "updated_at"}))
+	mock.ExpectQuery(`SELECT \* FROM "translations" WHERE resource_type = \$1 AND locale IN \(\$2,\$3\)`).
+		WithArgs("post", "de", "fr").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	router.GET("/translations/status", GetTranslationStatus)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/translations/status?type=post", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d", w.Code)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetDraftPostTranslationsNotFound(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDBAs(t, nil)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT "id","updated_at","content_format" FROM "posts" WHERE status = \$1 AND id = \$2 LIMIT \$3`).
+		WithArgs(models.PostStatusPublished, 1, 1).
+		WillReturnError(gorm.ErrRecordNotFound)
+
+	router.GET("/posts/:id/translations", GetPostTranslations)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/posts/1/translations", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("Expected status 404, but got %d", w.Code)
+	}
+}
@@ -0,0 +1,199 @@
+package controllers
+
+import (
+	"cms-backend/models"
+	"cms-backend/utils"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type userInput struct {
+	Email    string `json:"email" binding:"required"`
+	Name     string `json:"name"`
+	Password string `json:"password" binding:"required"`
+	Role     string `json:"role"`
+}
+
+type roleInput struct {
+	Role string `json:"role" binding:"required"`
+}
+
+const minPasswordLength = 8
+
+// GetRoles lists every role with the permissions it grants.
+func GetRoles(c *gin.Context) {
+	roles := make([]models.RoleInfo, 0, len(models.UserRoles))
+	for _, role := range models.UserRoles {
+		roles = append(roles, models.RoleInfo{Role: role, Permissions: models.RolePermissions[role]})
+	}
+	c.JSON(http.StatusOK, roles)
+}
+
+func GetUsers(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var users []models.User
+	if err := db.Order("id").Find(&users).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, users)
+}
+
+func CreateUser(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var input userInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	if input.Role == "" {
+		input.Role = models.UserRoleViewer
+	}
+	if !models.IsValidUserRole(input.Role) {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid role",
+		})
+		return
+	}
+	if len(input.Password) < minPasswordLength {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: "Password must be at least 8 characters",
+		})
+		return
+	}
+
+	user := models.User{
+		Email: strings.ToLower(strings.TrimSpace(input.Email)),
+		Name:  input.Name,
+		Role:  input.Role,
+	}
+
+	var count int64
+	if err := db.Model(&models.User{}).Where("email = ?", user.Email).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, utils.HTTPError{
+			Code:    http.StatusConflict,
+			Message: "A user with this email already exists",
+		})
+		return
+	}
+
+	hash, err := utils.HashPassword(input.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	user.PasswordHash = hash
+
+	tx := db.Begin()
+	if err := tx.Create(&user).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	tx.Commit()
+	c.JSON(http.StatusCreated, user)
+}
+
+// UpdateUserRole changes a user's role. The last admin cannot be demoted,
+// so the site always keeps someone able to manage users.
+func UpdateUserRole(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid user ID",
+		})
+		return
+	}
+
+	var input roleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	if !models.IsValidUserRole(input.Role) {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid role",
+		})
+		return
+	}
+
+	var user models.User
+	if err := db.First(&user, uint(id)).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, utils.HTTPError{
+				Code:    http.StatusNotFound,
+				Message: "User not found",
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, utils.HTTPError{
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
+			})
+		}
+		return
+	}
+
+	if user.Role == models.UserRoleAdmin && input.Role != models.UserRoleAdmin {
+		var admins int64
+		if err := db.Model(&models.User{}).Where("role = ?", models.UserRoleAdmin).Count(&admins).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, utils.HTTPError{
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
+			})
+			return
+		}
+		if admins <= 1 {
+			c.JSON(http.StatusConflict, utils.HTTPError{
+				Code:    http.StatusConflict,
+				Message: "Cannot demote the last admin",
+			})
+			return
+		}
+	}
+
+	tx := db.Begin()
+	if err := tx.Model(&user).Update("role", input.Role).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	tx.Commit()
+	c.JSON(http.StatusOK, user)
+}
@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"cms-backend/models"
+	"cms-backend/utils"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestGetRoles(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	router.GET("/roles", GetRoles)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/roles", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d", w.Code)
+	}
+
+	var roles []models.RoleInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &roles); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+	if len(roles) != len(models.UserRoles) || roles[0].Role != models.UserRoleAdmin {
+		t.Fatalf("Expected every role, admin first, but got %+v", roles)
+	}
+}
+
+func TestCreateUser(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "users" WHERE email = \$1`).
+		WithArgs("ed@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "users" \("email","name","password_hash","role","oidc_subject","created_at","updated_at"\)`).
+		WithArgs("ed@example.com", "Ed", sqlmock.AnyArg(), models.UserRoleEditor, nil, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectCommit()
+
+	router.POST("/users", CreateUser)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"email":"Ed@example.com","name":"Ed","password":"long enough","role":"editor"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, but got %d: %s", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "password") {
+		t.Fatalf("Expected the password hash to stay out of the response, but got %s", w.Body.String())
+	}
+}
+
+func TestCreateUserInvalidInput(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	router.POST("/users", CreateUser)
+	for _, body := range []string{
+		`{"email":"ed@example.com","password":"short"}`,
+		`{"email":"ed@example.com","password":"long enough","role":"owner"}`,
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("Expected status 400 for %s, but got %d", body, w.Code)
+		}
+	}
+}
+
+func TestUpdateUserRole(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"\."id" = \$1`).
+		WithArgs(2, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "role"}).AddRow(2, "ann@example.com", models.UserRoleViewer))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "users" SET "role"=\$1,"updated_at"=\$2 WHERE "id" = \$3`).
+		WithArgs(models.UserRoleAuthor, sqlmock.AnyArg(), 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	router.PUT("/users/:id/role", UpdateUserRole)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPut, "/users/2/role", strings.NewReader(`{"role":"author"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestUpdateUserRoleKeepsLastAdmin(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"\."id" = \$1`).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "role"}).AddRow(1, "admin@example.com", models.UserRoleAdmin))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "users" WHERE role = \$1`).
+		WithArgs(models.UserRoleAdmin).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	router.PUT("/users/:id/role", UpdateUserRole)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPut, "/users/1/role", strings.NewReader(`{"role":"editor"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("Expected status 409, but got %d", w.Code)
+	}
+}
@@ -0,0 +1,330 @@
+package controllers
+
+import (
+	"cms-backend/models"
+	"cms-backend/utils"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const minWebhookSecretLength = 16
+
+type webhookInput struct {
+	URL    string   `json:"url" binding:"required"`
+	Events []string `json:"events" binding:"required"`
+	Secret string   `json:"secret"`
+	Active *bool    `json:"active"`
+}
+
+func GetWebhooks(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var webhooks []models.Webhook
+	if err := db.Order("id").Find(&webhooks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, webhooks)
+}
+
+func GetWebhook(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	webhook, ok := findWebhook(c, db)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, webhook)
+}
+
+// CreateWebhook subscribes a URL to events. Without a secret one is
+// generated; either way it is only returned by this call.
+func CreateWebhook(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var input webhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	webhook := models.Webhook{Active: true, Secret: utils.RandomToken()}
+	if input.Secret != "" {
+		webhook.Secret = input.Secret
+	}
+	if !applyWebhookInput(c, &webhook, input) {
+		return
+	}
+
+	tx := db.Begin()
+	if err := tx.Create(&webhook).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	tx.Commit()
+	c.JSON(http.StatusCreated, models.WebhookWithSecret{Webhook: webhook, Secret: webhook.Secret})
+}
+
+// UpdateWebhook changes a webhook's URL, events or active flag, and its
+// secret when a new one is given.
+func UpdateWebhook(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	webhook, ok := findWebhook(c, db)
+	if !ok {
+		return
+	}
+
+	var input webhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	if input.Secret != "" {
+		webhook.Secret = input.Secret
+	}
+	if !applyWebhookInput(c, &webhook, input) {
+		return
+	}
+
+	tx := db.Begin()
+	if err := tx.Save(&webhook).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	tx.Commit()
+	c.JSON(http.StatusOK, webhook)
+}
+
+// DeleteWebhook removes a webhook along with its delivery log.
+func DeleteWebhook(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	webhook, ok := findWebhook(c, db)
+	if !ok {
+		return
+	}
+
+	tx := db.Begin()
+	if err := tx.Where("webhook_id = ?", webhook.ID).Delete(&models.WebhookDelivery{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err := tx.Delete(&webhook).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	tx.Commit()
+	c.JSON(http.StatusOK, utils.MessageResponse{
+		Message: "Webhook deleted successfully",
+	})
+}
+
+// GetWebhookDeliveries is a webhook's delivery log, newest first,
+// optionally filtered by ?status=.
+func GetWebhookDeliveries(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	webhook, ok := findWebhook(c, db)
+	if !ok {
+		return
+	}
+	page, perPage, err := paginationParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	query := db.Model(&models.WebhookDelivery{}).Where("webhook_id = ?", webhook.ID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	response := models.WebhookDeliveryResponse{Deliveries: []models.WebhookDelivery{}, Page: page, PerPage: perPage}
+	if err := query.Session(&gorm.Session{}).Count(&response.Total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err := query.Order("id DESC").Limit(perPage).Offset((page - 1) * perPage).Find(&response.Deliveries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// RedeliverWebhookDelivery queues the event of a logged delivery again as
+// a new delivery, whatever the state of the original.
+func RedeliverWebhookDelivery(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	webhook, ok := findWebhook(c, db)
+	if !ok {
+		return
+	}
+	deliveryID, err := strconv.ParseUint(c.Param("delivery_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid delivery ID",
+		})
+		return
+	}
+
+	var original models.WebhookDelivery
+	if err := db.Where("webhook_id = ?", webhook.ID).First(&original, uint(deliveryID)).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, utils.HTTPError{
+				Code:    http.StatusNotFound,
+				Message: "Delivery not found",
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, utils.HTTPError{
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
+			})
+		}
+		return
+	}
+
+	now := time.Now()
+	delivery := models.WebhookDelivery{
+		WebhookID:     webhook.ID,
+		EventID:       original.EventID,
+		Event:         original.Event,
+		Payload:       original.Payload,
+		Status:        models.DeliveryPending,
+		NextAttemptAt: &now,
+	}
+	tx := db.Begin()
+	if err := tx.Create(&delivery).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, utils.HTTPError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+	tx.Commit()
+	if dispatcher, ok := c.Get("webhooks"); ok {
+		dispatcher.(*utils.WebhookDispatcher).Wake()
+	}
+	c.JSON(http.StatusAccepted, delivery)
+}
+
+func findWebhook(c *gin.Context, db *gorm.DB) (models.Webhook, bool) {
+	var webhook models.Webhook
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid webhook ID",
+		})
+		return webhook, false
+	}
+	if err := db.First(&webhook, uint(id)).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, utils.HTTPError{
+				Code:    http.StatusNotFound,
+				Message: "Webhook not found",
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, utils.HTTPError{
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
+			})
+		}
+		return webhook, false
+	}
+	return webhook, true
+}
+
+// applyWebhookInput validates input onto webhook, answering 400 when the
+// URL, events or secret are unusable.
+func applyWebhookInput(c *gin.Context, webhook *models.Webhook, input webhookInput) bool {
+	target, err := url.Parse(input.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: "url must be an absolute http or https URL",
+		})
+		return false
+	}
+	if len(webhook.Secret) < minWebhookSecretLength {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: "Secret must be at least 16 characters",
+		})
+		return false
+	}
+
+	seen := map[string]bool{}
+	events := models.EventFilter{}
+	for _, event := range input.Events {
+		if !models.IsValidEventPattern(event) {
+			c.JSON(http.StatusBadRequest, utils.HTTPError{
+				Code:    http.StatusBadRequest,
+				Message: "Invalid event: " + event,
+			})
+			return false
+		}
+		if !seen[event] {
+			seen[event] = true
+			events = append(events, event)
+		}
+	}
+	if len(events) == 0 {
+		c.JSON(http.StatusBadRequest, utils.HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: "events must name at least one event",
+		})
+		return false
+	}
+	sort.Strings(events)
+
+	webhook.URL = input.URL
+	webhook.Events = events
+	if input.Active != nil {
+		webhook.Active = *input.Active
+	}
+	return true
+}
@@ -0,0 +1,255 @@
+package controllers
+
+import (
+	"bytes"
+	"cms-backend/models"
+	"cms-backend/utils"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+)
+
+var webhookColumns = []string{"id", "site_id", "url", "secret", "events", "active"}
+
+var deliveryColumns = []string{"id", "site_id", "webhook_id", "event_id", "event", "payload", "status", "attempts"}
+
+func TestCreateWebhook(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "webhooks" \("site_id","url","secret","events","active","created_at","updated_at"\)`).
+		WithArgs(1, "https://hooks.example.com/cms", sqlmock.AnyArg(), "media.deleted post.published", true, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	router.POST("/webhooks", CreateWebhook)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(`{"url":"https://hooks.example.com/cms","events":["post.published","media.deleted","post.published"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response models.WebhookWithSecret
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+	if len(response.Secret) < minWebhookSecretLength {
+		t.Fatalf("Expected a generated secret, but got %q", response.Secret)
+	}
+	if !response.Active || len(response.Events) != 2 {
+		t.Fatalf("Expected an active webhook with 2 events, but got %+v", response.Webhook)
+	}
+}
+
+func TestCreateWebhookInvalid(t *testing.T) {
+	tests := map[string]string{
+		"unknown event": `{"url":"https://hooks.example.com","events":["post.archived"]}`,
+		"no events":     `{"url":"https://hooks.example.com","events":[]}`,
+		"relative url":  `{"url":"/hooks","events":["*"]}`,
+		"short secret":  `{"url":"https://hooks.example.com","events":["*"],"secret":"short"}`,
+	}
+	for name, body := range tests {
+		t.Run(name, func(t *testing.T) {
+			router, _, mock := utils.SetupRouterAndMockDB(t)
+			defer mock.ExpectClose()
+
+			router.POST("/webhooks", CreateWebhook)
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("Expected status 400, but got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestWebhookSinkQueuesDeliveries(t *testing.T) {
+	_, db, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT \* FROM "webhooks" WHERE active = \$1 AND "webhooks"\."site_id" = \$2`).
+		WithArgs(true, 2).
+		WillReturnRows(sqlmock.NewRows(webhookColumns).
+			AddRow(1, 2, "https://hooks.example.com/pages", "secret-secret-secret", "page.*", true).
+			AddRow(2, 2, "https://hooks.example.com/posts", "secret-secret-secret", "post.published", true).
+			AddRow(3, 2, "https://hooks.example.com/all", "secret-secret-secret", "*", true))
+	mock.ExpectQuery(`SELECT "webhook_id" FROM "webhook_deliveries" WHERE event_id = \$1 AND "webhook_deliveries"\."site_id" = \$2`).
+		WithArgs("evt", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"webhook_id"}).AddRow(3))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "webhook_deliveries" \("site_id","webhook_id","event_id","event","payload","status","attempts","next_attempt_at","last_attempt_at","response_status","response_body","error","created_at","updated_at"\)`).
+		WithArgs(2, 1, "evt", models.EventPageCreated, jsonArg(`"type":"page.created"`), models.DeliveryPending, 0,
+			sqlmock.AnyArg(), nil, 0, "", "", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	event := models.Event{ID: "evt", Type: models.EventPageCreated, SiteID: 2, ResourceType: models.ResourcePage, ResourceID: 1}
+	if err := utils.NewWebhookDispatcher(db).Publish(context.Background(), event); err != nil {
+		t.Fatalf("Expected the event to be queued, but got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("Expected one new delivery: %v", err)
+	}
+}
+
+func TestDeliverDueSignsDelivery(t *testing.T) {
+	_, db, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+
+	payload := `{"id":"evt","type":"post.published"}`
+	var received *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = io.ReadAll(r.Body)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	mock.ExpectQuery(`UPDATE webhook_deliveries SET next_attempt_at = \$1 WHERE id IN`).
+		WillReturnRows(sqlmock.NewRows(deliveryColumns).
+			AddRow(5, 1, 1, "evt", models.EventPostPublished, payload, models.DeliveryPending, 0))
+	mock.ExpectQuery(`SELECT \* FROM "webhooks" WHERE "webhooks"\."id" = \$1 LIMIT \$2`).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows(webhookColumns).AddRow(1, 1, server.URL, "secret-secret-secret", "*", true))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "webhook_deliveries" SET`).
+		WithArgs(1, "", sqlmock.AnyArg(), nil, "ok", 200, models.DeliverySucceeded, sqlmock.AnyArg(), 5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	sent, err := utils.NewWebhookDispatcher(db).DeliverDue(context.Background())
+	if err != nil || sent != 1 {
+		t.Fatalf("Expected 1 delivery, but got %d (%v)", sent, err)
+	}
+
+	if !bytes.Equal(body, []byte(payload)) {
+		t.Fatalf("Expected the stored payload, but got %s", body)
+	}
+	timestamp := received.Header.Get(utils.WebhookTimestampHeader)
+	expected := utils.WebhookSignature("secret-secret-secret", timestamp, body)
+	if received.Header.Get(utils.WebhookSignatureHeader) != expected {
+		t.Fatalf("Expected signature %s, but got %s", expected, received.Header.Get(utils.WebhookSignatureHeader))
+	}
+	if received.Header.Get(utils.WebhookEventHeader) != models.EventPostPublished || received.Header.Get(utils.WebhookDeliveryHeader) != "5" {
+		t.Fatalf("Expected event and delivery headers, but got %v", received.Header)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeliverDueRetriesThenDies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		attempts int
+		status   string
+		retry    bool
+	}{
+		{attempts: 0, status: models.DeliveryFailed, retry: true},
+		{attempts: 7, status: models.DeliveryDead},
+	}
+	for _, test := range tests {
+		_, db, mock := utils.SetupRouterAndMockDB(t)
+
+		mock.ExpectQuery(`UPDATE webhook_deliveries SET next_attempt_at = \$1 WHERE id IN`).
+			WillReturnRows(sqlmock.NewRows(deliveryColumns).
+				AddRow(5, 1, 1, "evt", models.EventPostPublished, `{}`, models.DeliveryFailed, test.attempts))
+		mock.ExpectQuery(`SELECT \* FROM "webhooks" WHERE "webhooks"\."id" = \$1 LIMIT \$2`).
+			WithArgs(1, 1).
+			WillReturnRows(sqlmock.NewRows(webhookColumns).AddRow(1, 1, server.URL, "secret-secret-secret", "*", true))
+		mock.ExpectBegin()
+		nextAttempt := sqlmock.Argument(nilArg{})
+		if test.retry {
+			nextAttempt = futureArg{after: time.Now().Add(utils.WebhookBackoff(1) - time.Second)}
+		}
+		mock.ExpectExec(`UPDATE "webhook_deliveries" SET`).
+			WithArgs(test.attempts+1, jsonArg("503"), sqlmock.AnyArg(), nextAttempt, "down\n", 503, test.status, sqlmock.AnyArg(), 5).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		dispatcher := utils.NewWebhookDispatcher(db)
+		dispatcher.MaxAttempts = 8
+		if _, err := dispatcher.DeliverDue(context.Background()); err != nil {
+			t.Fatalf("Expected the failure to be recorded, but got %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatalf("After %d attempts: %v", test.attempts, err)
+		}
+	}
+}
+
+func TestRedeliverWebhookDelivery(t *testing.T) {
+	router, db, mock := utils.SetupRouterAndMockDB(t)
+	defer mock.ExpectClose()
+	dispatcher := utils.NewWebhookDispatcher(db)
+	router.Use(func(c *gin.Context) { c.Set("webhooks", dispatcher) })
+
+	mock.ExpectQuery(`SELECT \* FROM "webhooks" WHERE "webhooks"\."id" = \$1`).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows(webhookColumns).AddRow(1, 1, "https://hooks.example.com", "secret-secret-secret", "*", true))
+	mock.ExpectQuery(`SELECT \* FROM "webhook_deliveries" WHERE webhook_id = \$1 AND "webhook_deliveries"\."id" = \$2`).
+		WithArgs(1, 5, 1).
+		WillReturnRows(sqlmock.NewRows(deliveryColumns).
+			AddRow(5, 1, 1, "evt", models.EventMediaDeleted, `{"id":"evt"}`, models.DeliveryDead, 8))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "webhook_deliveries"`).
+		WithArgs(1, 1, "evt", models.EventMediaDeleted, `{"id":"evt"}`, models.DeliveryPending, 0,
+			sqlmock.AnyArg(), nil, 0, "", "", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(6))
+	mock.ExpectCommit()
+
+	router.POST("/webhooks/:id/deliveries/:delivery_id/redeliver", RedeliverWebhookDelivery)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/webhooks/1/deliveries/5/redeliver", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected status 202, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response models.WebhookDelivery
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Error unmarshaling response: %v", err)
+	}
+	if response.ID != 6 || response.Attempts != 0 || response.Status != models.DeliveryPending {
+		t.Fatalf("Expected a new pending delivery, but got %+v", response)
+	}
+}
+
+// nilArg matches a NULL argument.
+type nilArg struct{}
+
+func (nilArg) Match(v driver.Value) bool {
+	return v == nil
+}
+
+// futureArg matches a time argument later than after.
+type futureArg struct {
+	after time.Time
+}
+
+func (a futureArg) Match(v driver.Value) bool {
+	at, ok := v.(time.Time)
+	return ok && at.After(a.after)
+}
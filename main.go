@@ -4,11 +4,14 @@ import (
 	"cms-backend/models"
 	"cms-backend/routes"
 	"cms-backend/utils"
+	"context"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	_ "github.com/joho/godotenv/autoload"
+	"gorm.io/gorm"
 )
 
 func main() {
@@ -23,6 +26,18 @@ func main() {
 	}
 	defer sqlDB.Close()
 
+	// Migrations, the reindex command, the outbox relay and webhook
+	// deliveries span every site, which row-level security hides from db.
+	system, err := utils.ConnectSystemDB()
+	if err != nil {
+		log.Fatalf("Could not connect to the database as the system role: %v", err)
+	}
+	systemSQL, err := system.DB()
+	if err != nil {
+		log.Fatalf("Failed to get database instance: %v", err)
+	}
+	defer systemSQL.Close()
+
 	env := os.Getenv("ENV")
 	if env == "" {
 		env = "development"
@@ -30,19 +45,142 @@ func main() {
 
 	if env == "development" {
 		log.Println("Running AutoMigrate...")
-		if err := db.AutoMigrate(&models.Page{}, &models.Post{}, &models.Media{}); err != nil {
+		if err := system.AutoMigrate(&models.Site{}, &models.Page{}, &models.Post{}, &models.Media{}, &models.PostContributor{}, &models.Translation{}, &models.ContentType{}, &models.ContentEntry{}, &models.User{}, &models.RefreshToken{}, &models.RevokedToken{}, &models.APIKey{}, &models.OIDCState{}, &models.AuditLog{}, &models.Webhook{}, &models.WebhookDelivery{}, &models.OutboxEvent{}, &models.OutboxCursor{}, &models.EditLock{}); err != nil {
 			log.Fatalf("Failed to automigrate database: %v", err)
 		}
 	}
 
+	if err := utils.EnsureSearchIndex(system); err != nil {
+		log.Fatalf("Failed to set up search index: %v", err)
+	}
+
+	if err := utils.EnsureAuditLog(system); err != nil {
+		log.Fatalf("Failed to set up audit log: %v", err)
+	}
+
+	if err := utils.EnsureTenantIsolation(system); err != nil {
+		log.Fatalf("Failed to set up site isolation: %v", err)
+	}
+
+	if err := ensureAdmin(system); err != nil {
+		log.Fatalf("Failed to create admin user: %v", err)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "reindex" {
+		reindex(system)
+		return
+	}
+
+	search, err := utils.OpenSearchIndex()
+	if err != nil {
+		log.Fatalf("Failed to open search index: %v", err)
+	}
+	if search != nil {
+		defer search.Close()
+	}
+
+	sso, err := utils.OpenOIDCClient(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to set up single sign-on: %v", err)
+	}
+
 	if env == "production" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
+	webhooks := utils.NewWebhookDispatcher(system)
+	go webhooks.Run(context.Background())
+
+	sinks, err := utils.OpenEventSinks(webhooks)
+	if err != nil {
+		log.Fatalf("Failed to set up event sinks: %v", err)
+	}
+	outbox := utils.NewOutboxRelay(system, sinks...)
+	go outbox.Run(context.Background())
+
+	// Other replicas announce their writes over LISTEN/NOTIFY; local caches
+	// are dropped when one arrives.
+	invalidations := utils.NewInvalidationListener(utils.DSN())
+	events := utils.NewEventNotifier()
+	sites := utils.NewLocalCache(invalidations.MaxAge)
+	cache, err := utils.OpenResponseCache(invalidations.MaxAge)
+	if err != nil {
+		log.Fatalf("Failed to open response cache: %v", err)
+	}
+	invalidations.Subscribe(func(invalidation utils.Invalidation) {
+		// A Redis cache is shared, so the writer has invalidated it already.
+		if cache != nil && utils.CacheBackend() == utils.CacheBackendMemory {
+			if err := cache.Invalidate(context.Background(), invalidation); err != nil {
+				log.Printf("Failed to invalidate response cache: %v", err)
+			}
+		}
+		switch invalidation.Resource {
+		case models.ResourceSite, utils.InvalidateAll:
+			sites.Clear()
+		default:
+			// Event streams pick up events written by other replicas.
+			events.Notify()
+		}
+	})
+	go invalidations.Run(context.Background())
+
 	router := gin.Default()
-	routes.InitializeRoutes(router, db)
+	// Audit entries record the client IP, which only trusted proxies may set.
+	if err := router.SetTrustedProxies(utils.TrustedProxies()); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+	routes.InitializeRoutes(router, db, system, search, sso, webhooks, outbox, events, sites, cache)
 
 	if err := router.Run(":8080"); err != nil {
 		log.Fatalf("Failed to run server: %v", err)
 	}
 }
+
+// reindex rebuilds the configured search index from the database. A Bleve
+// index is recreated from scratch, so run it while the API is stopped.
+func reindex(db *gorm.DB) {
+	if utils.SearchBackend() == utils.SearchBackendBleve {
+		if err := os.RemoveAll(utils.SearchIndexPath()); err != nil {
+			log.Fatalf("Failed to remove search index: %v", err)
+		}
+	}
+
+	search, err := utils.OpenSearchIndex()
+	if err != nil {
+		log.Fatalf("Failed to open search index: %v", err)
+	}
+	if search != nil {
+		defer search.Close()
+	}
+
+	count, err := utils.ReindexSearch(db, search)
+	if err != nil {
+		log.Fatalf("Failed to reindex search: %v", err)
+	}
+	log.Printf("Reindexed %d documents", count)
+}
+
+// ensureAdmin creates the ADMIN_EMAIL user with ADMIN_PASSWORD when both are
+// set and no such user exists yet, so a fresh install has someone to log in.
+func ensureAdmin(db *gorm.DB) error {
+	email := strings.ToLower(strings.TrimSpace(os.Getenv("ADMIN_EMAIL")))
+	password := os.Getenv("ADMIN_PASSWORD")
+	if email == "" || password == "" {
+		return nil
+	}
+
+	var count int64
+	if err := db.Model(&models.User{}).Where("email = ?", email).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	log.Printf("Creating admin user %s", email)
+	return db.Create(&models.User{Email: email, Name: "Admin", PasswordHash: hash, Role: models.UserRoleAdmin}).Error
+}
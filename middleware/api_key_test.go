@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"cms-backend/models"
+	"cms-backend/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+)
+
+func setupAPIKeyRouter(t *testing.T) (*gin.Engine, sqlmock.Sqlmock) {
+	router, _, mock := utils.SetupRouterAndMockDBAs(t, nil)
+	router.Use(AuthenticateAPIKey(), Authenticate())
+	ok := func(c *gin.Context) {
+		_, viaKey := c.Get("api_key")
+		c.JSON(http.StatusOK, gin.H{"api_key": viaKey})
+	}
+	router.GET("/posts", RequireScope(models.ScopePostsRead), ok)
+	router.POST("/posts", RequirePermission(models.PermWritePosts), ok)
+	return router, mock
+}
+
+func expectAPIKey(mock sqlmock.Sqlmock, key string, scopes string, expiresAt interface{}) {
+	mock.ExpectQuery(`SELECT \* FROM "api_keys" WHERE key_hash = \$1`).
+		WithArgs(utils.HashToken(key), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "prefix", "scopes", "expires_at", "last_used_at"}).
+			AddRow(4, 3, "cms_0123abcd", scopes, expiresAt, time.Now()))
+}
+
+func TestAPIKeyViaHeader(t *testing.T) {
+	router, mock := setupAPIKeyRouter(t)
+	defer mock.ExpectClose()
+
+	expectAPIKey(mock, "cms_0123abcd_secret", "posts:read", nil)
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"\."id" = \$1`).
+		WithArgs(3, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "role"}).AddRow(3, models.UserRoleEditor))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/posts", nil)
+	req.Header.Set("X-API-Key", "cms_0123abcd_secret")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || w.Body.String() != `{"api_key":true}` {
+		t.Fatalf("Expected a request authenticated by API key, but got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAPIKeyScopesLimitRole(t *testing.T) {
+	router, mock := setupAPIKeyRouter(t)
+	defer mock.ExpectClose()
+
+	expectAPIKey(mock, "cms_0123abcd_secret", "posts:read", nil)
+	mock.ExpectQuery(`SELECT \* FROM "users"`).
+		WithArgs(3, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "role"}).AddRow(3, models.UserRoleEditor))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/posts", nil)
+	req.Header.Set("Authorization", "Bearer cms_0123abcd_secret")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403, but got %d", w.Code)
+	}
+}
+
+func TestAPIKeyMissingReadScope(t *testing.T) {
+	router, mock := setupAPIKeyRouter(t)
+	defer mock.ExpectClose()
+
+	expectAPIKey(mock, "cms_0123abcd_secret", "media:read media:write", nil)
+	mock.ExpectQuery(`SELECT \* FROM "users"`).
+		WithArgs(3, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "role"}).AddRow(3, models.UserRoleEditor))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/posts", nil)
+	req.Header.Set("X-API-Key", "cms_0123abcd_secret")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403, but got %d", w.Code)
+	}
+}
+
+func TestAPIKeyRejected(t *testing.T) {
+	router, mock := setupAPIKeyRouter(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT \* FROM "api_keys"`).
+		WithArgs(utils.HashToken("cms_unknown"), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	expectAPIKey(mock, "cms_0123abcd_expired", "posts:read", time.Now().Add(-time.Hour))
+
+	for _, key := range []string{"cms_unknown", "cms_0123abcd_expired"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/posts", nil)
+		req.Header.Set("X-API-Key", key)
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected status 401 for %s, but got %d", key, w.Code)
+		}
+	}
+}
+
+func TestAPIKeyTracksLastUse(t *testing.T) {
+	router, mock := setupAPIKeyRouter(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT \* FROM "api_keys"`).
+		WithArgs(utils.HashToken("cms_0123abcd_secret"), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "scopes", "last_used_at"}).
+			AddRow(4, 3, "posts:read", time.Now().Add(-time.Hour)))
+	mock.ExpectQuery(`SELECT \* FROM "users"`).
+		WithArgs(3, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "role"}).AddRow(3, models.UserRoleViewer))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "api_keys" SET "last_used_at"=\$1 WHERE "id" = \$2`).
+		WithArgs(sqlmock.AnyArg(), 4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/posts", nil)
+	req.Header.Set("X-API-Key", "cms_0123abcd_secret")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAPIKeyLookedUpOnSystemDB(t *testing.T) {
+	router, _, mock := utils.SetupRouterAndMockDBAs(t, nil)
+	defer mock.ExpectClose()
+	_, system, systemMock := utils.SetupRouterAndMockDBAs(t, nil)
+	defer systemMock.ExpectClose()
+
+	router.Use(func(c *gin.Context) { c.Set("system_db", system) }, AuthenticateAPIKey(), Authenticate())
+	router.GET("/posts", RequireScope(models.ScopePostsRead), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	expectAPIKey(systemMock, "cms_0123abcd_secret", "posts:read", nil)
+	systemMock.ExpectQuery(`SELECT \* FROM "users"`).
+		WithArgs(3, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "role"}).AddRow(3, models.UserRoleEditor))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/posts", nil)
+	req.Header.Set("X-API-Key", "cms_0123abcd_secret")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	if err := systemMock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
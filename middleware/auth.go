@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"cms-backend/models"
+	"cms-backend/utils"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Authenticate requires a valid bearer access token on every write
+// (POST, PUT, PATCH, DELETE). Reads stay public, but a token sent with one
+// is still verified so handlers can see who is asking. The user and the
+// token claims are stored in the context as "user" and "claims". Requests
+// already authenticated by AuthenticateAPIKey pass through.
+func Authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("api_key"); ok {
+			c.Next()
+			return
+		}
+
+		header := c.GetHeader("Authorization")
+		if header == "" && isSafeMethod(c.Request.Method) {
+			c.Next()
+			return
+		}
+
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok || token == "" {
+			abortUnauthorized(c, "Authentication required")
+			return
+		}
+
+		claims, err := utils.ParseAccessToken(token)
+		if err != nil {
+			abortUnauthorized(c, "Invalid or expired access token")
+			return
+		}
+		userID, err := claims.UserID()
+		if err != nil {
+			abortUnauthorized(c, err.Error())
+			return
+		}
+
+		db := c.MustGet("db").(*gorm.DB)
+
+		var revoked int64
+		if err := db.Model(&models.RevokedToken{}).Where("jti = ?", claims.ID).Count(&revoked).Error; err != nil {
+			abortError(c, err)
+			return
+		}
+		if revoked > 0 {
+			abortUnauthorized(c, "Access token has been revoked")
+			return
+		}
+
+		var user models.User
+		if err := db.First(&user, userID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				abortUnauthorized(c, "Invalid or expired access token")
+			} else {
+				abortError(c, err)
+			}
+			return
+		}
+
+		c.Set("user", user)
+		c.Set("claims", claims)
+		c.Next()
+	}
+}
+
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+func abortUnauthorized(c *gin.Context, message string) {
+	c.Header("WWW-Authenticate", `Bearer realm="api"`)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, utils.HTTPError{
+		Code:    http.StatusUnauthorized,
+		Message: message,
+	})
+}
+
+func abortError(c *gin.Context, err error) {
+	c.AbortWithStatusJSON(http.StatusInternalServerError, utils.HTTPError{
+		Code:    http.StatusInternalServerError,
+		Message: err.Error(),
+	})
+}
@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"cms-backend/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+)
+
+func setupAuthRouter(t *testing.T) (*gin.Engine, sqlmock.Sqlmock) {
+	router, _, mock := utils.SetupRouterAndMockDBAs(t, nil)
+	router.Use(Authenticate())
+	ok := func(c *gin.Context) {
+		_, authenticated := c.Get("user")
+		c.JSON(http.StatusOK, gin.H{"authenticated": authenticated})
+	}
+	router.GET("/posts", ok)
+	router.POST("/posts", ok)
+	return router, mock
+}
+
+func TestAuthenticateAllowsAnonymousReads(t *testing.T) {
+	router, mock := setupAuthRouter(t)
+	defer mock.ExpectClose()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/posts", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, but got %d", w.Code)
+	}
+}
+
+func TestAuthenticateRejectsAnonymousWrites(t *testing.T) {
+	router, mock := setupAuthRouter(t)
+	defer mock.ExpectClose()
+
+	for _, header := range []string{"", "Basic abc", "Bearer not-a-jwt"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/posts", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected status 401 for %q, but got %d", header, w.Code)
+		}
+		if w.Header().Get("WWW-Authenticate") == "" {
+			t.Fatalf("Expected a WWW-Authenticate challenge")
+		}
+	}
+}
+
+func TestAuthenticateAcceptsValidToken(t *testing.T) {
+	router, mock := setupAuthRouter(t)
+	defer mock.ExpectClose()
+
+	token, claims, _ := utils.IssueAccessToken(3)
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "revoked_tokens" WHERE jti = \$1`).
+		WithArgs(claims.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"\."id" = \$1`).
+		WithArgs(3, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(3, "ann@example.com"))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/posts", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || w.Body.String() != `{"authenticated":true}` {
+		t.Fatalf("Expected an authenticated request, but got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAuthenticateRejectsRevokedToken(t *testing.T) {
+	router, mock := setupAuthRouter(t)
+	defer mock.ExpectClose()
+
+	token, claims, _ := utils.IssueAccessToken(3)
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "revoked_tokens"`).
+		WithArgs(claims.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/posts", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected status 401, but got %d", w.Code)
+	}
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"cms-backend/models"
+	"cms-backend/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePermission lets a request through only when the authenticated
+// user's role grants at least one of the permissions. It runs after
+// Authenticate; anonymous requests get 401 and denied ones 403.
+func RequirePermission(permissions ...models.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, ok := c.Get("user")
+		if !ok {
+			abortUnauthorized(c, "Authentication required")
+			return
+		}
+		user := value.(models.User)
+		for _, permission := range permissions {
+			if user.Can(permission) {
+				c.Next()
+				return
+			}
+		}
+		abortForbidden(c, "You do not have permission to perform this action")
+	}
+}
+
+func abortForbidden(c *gin.Context, message string) {
+	c.AbortWithStatusJSON(http.StatusForbidden, utils.HTTPError{
+		Code:    http.StatusForbidden,
+		Message: message,
+	})
+}
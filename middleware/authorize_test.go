@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"cms-backend/models"
+	"cms-backend/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequirePermission(t *testing.T) {
+	tests := []struct {
+		user  *models.User
+		perms []models.Permission
+		code  int
+	}{
+		{nil, []models.Permission{models.PermWritePages}, http.StatusUnauthorized},
+		{&models.User{ID: 1, Role: models.UserRoleViewer}, []models.Permission{models.PermWritePages}, http.StatusForbidden},
+		{&models.User{ID: 1, Role: models.UserRoleEditor}, []models.Permission{models.PermDeleteMedia}, http.StatusForbidden},
+		{&models.User{ID: 1, Role: models.UserRoleAuthor}, []models.Permission{models.PermWritePosts, models.PermWriteOwnPosts}, http.StatusOK},
+		{&models.User{ID: 1, Role: models.UserRoleAdmin}, []models.Permission{models.PermManageUsers}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		router, _, mock := utils.SetupRouterAndMockDBAs(t, tt.user)
+		router.POST("/resource", RequirePermission(tt.perms...), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/resource", nil)
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.code {
+			t.Fatalf("Expected status %d for %+v with %v, but got %d", tt.code, tt.user, tt.perms, w.Code)
+		}
+		mock.ExpectClose()
+	}
+}
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"bytes"
+	"cms-backend/models"
+	"cms-backend/utils"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// cachedHeaders are the response headers stored along with cached bodies.
+var cachedHeaders = []string{"Content-Type", "Content-Language", "ETag", "Last-Modified", "Vary"}
+
+// CacheResponse serves reads of a resource type from the "cache" context
+// value, caching successful responses on a miss. A list depends on every
+// resource of the type and a single resource, named by the :id route
+// parameter, only on itself. Responses embedding other resources, such as
+// the media of posts, also depend on every resource of the dependsOn types.
+// X-Cache tells whether the response was a HIT or a MISS.
+func CacheResponse(resource string, dependsOn ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, ok := c.Get("cache")
+		if !ok || c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+		cache := value.(*utils.ResponseCache)
+		site := c.MustGet("site").(models.Site)
+
+		var id uint
+		if param := c.Param("id"); param != "" {
+			parsed, err := strconv.ParseUint(param, 10, 32)
+			if err != nil {
+				c.Next()
+				return
+			}
+			id = uint(parsed)
+		}
+		scopes := []string{utils.CacheScope(site.ID, resource, id)}
+		for _, other := range dependsOn {
+			scopes = append(scopes, utils.CacheScope(site.ID, other, 0))
+		}
+
+		ctx := c.Request.Context()
+		entryKey, cached, err := cache.Lookup(ctx, responseCacheKey(c, site.ID, resource, id), scopes)
+		if err != nil {
+			log.Printf("Failed to read response cache: %v", err)
+			c.Next()
+			return
+		}
+		if cached != nil {
+			for name, values := range cached.Header {
+				c.Writer.Header()[name] = values
+			}
+			c.Header("X-Cache", "HIT")
+			c.Data(cached.Status, cached.Header.Get("Content-Type"), cached.Body)
+			c.Abort()
+			return
+		}
+
+		c.Header("X-Cache", "MISS")
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+		if recorder.Status() != http.StatusOK || recorder.Header().Get("Cache-Control") == noStore {
+			return
+		}
+
+		header := http.Header{}
+		for _, name := range cachedHeaders {
+			if values := recorder.Header().Values(name); len(values) > 0 {
+				header[name] = values
+			}
+		}
+		response := utils.CachedResponse{Status: http.StatusOK, Header: header, Body: recorder.body.Bytes()}
+		if err := cache.Store(ctx, entryKey, response); err != nil {
+			log.Printf("Failed to write response cache: %v", err)
+		}
+	}
+}
+
+// responseCacheKey identifies a read by what its response depends on: the
+// resource, the query parameters in a canonical order, the locale it is
+// served in, whether the user may see drafts and whether they may write,
+// which shows them edit locks.
+func responseCacheKey(c *gin.Context, siteID uint, resource string, id uint) string {
+	query := c.Request.URL.Query()
+	query.Del("locale")
+	drafts, writes := false, false
+	if value, ok := c.Get("user"); ok {
+		user := value.(models.User)
+		drafts = user.Can(models.PermViewDrafts)
+		writes = user.Can(models.PermWritePages) || user.Can(models.PermWritePosts) || user.Can(models.PermWriteOwnPosts)
+	}
+	return fmt.Sprintf("%d/%s/%d?%s#locale=%s&drafts=%t&writes=%t", siteID, resource, id, query.Encode(), utils.RequestedLocale(c), drafts, writes)
+}
+
+// responseRecorder keeps a copy of the body written through it.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
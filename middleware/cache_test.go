@@ -0,0 +1,196 @@
+package middleware
+
+import (
+	"cms-backend/models"
+	"cms-backend/utils"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// setupCacheRouter serves posts and media through a cached handler
+// answering with the number of times it ran.
+func setupCacheRouter(cache *utils.ResponseCache, user *models.User) (*gin.Engine, *int) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("cache", cache)
+		c.Set("site", models.Site{ID: 1, Slug: "default"})
+		if user != nil {
+			c.Set("user", *user)
+		}
+	})
+	calls := 0
+	handler := func(c *gin.Context) {
+		calls++
+		if locale := utils.RequestedLocale(c); locale != "" {
+			c.Header("Content-Language", locale)
+		}
+		c.JSON(http.StatusOK, gin.H{"calls": calls})
+	}
+	router.GET("/posts", CacheResponse(models.ResourcePost, models.ResourceMedia), handler)
+	router.GET("/posts/:id", CacheResponse(models.ResourcePost, models.ResourceMedia), handler)
+	router.GET("/media/:id", CacheResponse(models.ResourceMedia), handler)
+	router.GET("/missing", CacheResponse(models.ResourcePost), func(c *gin.Context) {
+		calls++
+		c.JSON(http.StatusNotFound, gin.H{"calls": calls})
+	})
+	return router, &calls
+}
+
+func newMemoryResponseCache() *utils.ResponseCache {
+	return utils.NewResponseCache(utils.NewMemoryCacheStore(1<<20), func() time.Duration { return time.Minute })
+}
+
+// get requests path, checking the X-Cache header and which run of the
+// handler produced the body.
+func get(t *testing.T, router *gin.Engine, path, xCache string, calls int) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	router.ServeHTTP(w, req)
+	body := `{"calls":` + strconv.Itoa(calls) + `}`
+	if w.Header().Get("X-Cache") != xCache || w.Body.String() != body {
+		t.Fatalf("Expected %s %s from %s, but got %s %s", xCache, body, path, w.Header().Get("X-Cache"), w.Body.String())
+	}
+	return w
+}
+
+func TestCacheResponseNormalizesQuery(t *testing.T) {
+	router, calls := setupCacheRouter(newMemoryResponseCache(), nil)
+
+	get(t, router, "/posts?title=go&status=published", "MISS", 1)
+	get(t, router, "/posts?status=published&title=go", "HIT", 1)
+	get(t, router, "/posts?title=rust&status=published", "MISS", 2)
+
+	get(t, router, "/posts/7?locale=fr", "MISS", 3)
+	w := get(t, router, "/posts/7?locale=FR", "HIT", 3)
+	if w.Header().Get("Content-Language") != "fr" || w.Header().Get("Content-Type") != "application/json; charset=utf-8" {
+		t.Fatalf("Expected the cached headers to be served, but got %v", w.Header())
+	}
+	get(t, router, "/posts/7", "MISS", 4)
+
+	get(t, router, "/missing", "MISS", 5)
+	get(t, router, "/missing", "MISS", 6)
+	if *calls != 6 {
+		t.Fatalf("Expected the handler to run 6 times, but it ran %d times", *calls)
+	}
+}
+
+func TestCacheResponseSeparatesDraftReaders(t *testing.T) {
+	cache := newMemoryResponseCache()
+	public, _ := setupCacheRouter(cache, nil)
+	editor, _ := setupCacheRouter(cache, &models.User{ID: 1, Role: models.UserRoleEditor})
+
+	get(t, public, "/posts", "MISS", 1)
+	get(t, editor, "/posts", "MISS", 1)
+	get(t, public, "/posts", "HIT", 1)
+	get(t, editor, "/posts", "HIT", 1)
+}
+
+func TestCacheResponseInvalidatesPrecisely(t *testing.T) {
+	cache := newMemoryResponseCache()
+	router, _ := setupCacheRouter(cache, nil)
+	ctx := context.Background()
+
+	get(t, router, "/posts", "MISS", 1)
+	get(t, router, "/posts/7", "MISS", 2)
+	get(t, router, "/posts/8", "MISS", 3)
+	get(t, router, "/media/3", "MISS", 4)
+
+	// A write to post 8 leaves post 7 cached.
+	if err := cache.Invalidate(ctx, utils.Invalidation{SiteID: 1, Resource: models.ResourcePost, ID: 8}); err != nil {
+		t.Fatalf("Failed to invalidate: %v", err)
+	}
+	get(t, router, "/posts/7", "HIT", 2)
+	get(t, router, "/posts/8", "MISS", 5)
+	get(t, router, "/posts", "MISS", 6)
+	get(t, router, "/media/3", "HIT", 4)
+
+	// Other sites' writes change nothing here.
+	if err := cache.Invalidate(ctx, utils.Invalidation{SiteID: 2, Resource: models.ResourceMedia, ID: 3}); err != nil {
+		t.Fatalf("Failed to invalidate: %v", err)
+	}
+	get(t, router, "/posts/7", "HIT", 2)
+	get(t, router, "/media/3", "HIT", 4)
+
+	// Posts embed media, so a media write drops them.
+	if err := cache.Invalidate(ctx, utils.Invalidation{SiteID: 1, Resource: models.ResourceMedia, ID: 3}); err != nil {
+		t.Fatalf("Failed to invalidate: %v", err)
+	}
+	get(t, router, "/posts/7", "MISS", 7)
+	get(t, router, "/media/3", "MISS", 8)
+
+	if err := cache.Invalidate(ctx, utils.Invalidation{Resource: utils.InvalidateAll}); err != nil {
+		t.Fatalf("Failed to invalidate: %v", err)
+	}
+	get(t, router, "/posts/7", "MISS", 9)
+}
+
+func TestMemoryCacheStoreEvictsLeastRecentlyUsed(t *testing.T) {
+	store := utils.NewMemoryCacheStore(12)
+	ctx := context.Background()
+
+	store.Set(ctx, "a", []byte("1234"), time.Minute)
+	store.Set(ctx, "b", []byte("1234"), time.Minute)
+	store.Get(ctx, "a")
+	store.Set(ctx, "c", []byte("1234"), time.Minute)
+	store.Set(ctx, "d", []byte("123456789012"), time.Minute)
+	store.Set(ctx, "e", []byte("1"), -time.Second)
+
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true, "d": false, "e": false} {
+		if _, ok, _ := store.Get(ctx, key); ok != want {
+			t.Errorf("Expected %q cached to be %t", key, want)
+		}
+	}
+}
+
+func TestCacheResponseWithRedis(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	cache := utils.NewResponseCache(utils.NewRedisCacheStore(client), func() time.Duration { return time.Minute })
+	router, _ := setupCacheRouter(cache, nil)
+
+	get(t, router, "/posts/7", "MISS", 1)
+	get(t, router, "/posts/7", "HIT", 1)
+
+	// Another replica sharing the server sees the entry and its
+	// invalidation.
+	other := utils.NewResponseCache(utils.NewRedisCacheStore(client), func() time.Duration { return time.Minute })
+	otherRouter, _ := setupCacheRouter(other, nil)
+	get(t, otherRouter, "/posts/7", "HIT", 1)
+	if err := other.Invalidate(context.Background(), utils.Invalidation{SiteID: 1, Resource: models.ResourcePost, ID: 7}); err != nil {
+		t.Fatalf("Failed to invalidate: %v", err)
+	}
+	get(t, router, "/posts/7", "MISS", 2)
+
+	// Entries expire with the cache's max age.
+	server.FastForward(2 * time.Minute)
+	get(t, router, "/posts/7", "MISS", 3)
+}
+
+func TestCacheResponseSkipsNoStore(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("cache", newMemoryResponseCache())
+		c.Set("site", models.Site{ID: 1, Slug: "default"})
+	})
+	calls := 0
+	router.GET("/pages/:id", CacheResponse(models.ResourcePage), func(c *gin.Context) {
+		calls++
+		c.Header("Cache-Control", "no-store")
+		c.JSON(http.StatusOK, gin.H{"calls": calls})
+	})
+
+	get(t, router, "/pages/1", "MISS", 1)
+	get(t, router, "/pages/1", "MISS", 2)
+}
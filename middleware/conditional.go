@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"bytes"
+	"cms-backend/utils"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// privateCacheControl is sent instead of a route's Cache-Control when the
+// response depends on who asked: caches may keep it for that client only,
+// revalidating it on every use.
+const privateCacheControl = "private, no-cache"
+
+// noStore is set by handlers whose response must not be stored at all; it
+// is kept as is.
+const noStore = "no-store"
+
+// ConditionalGET gives successful GET responses a strong ETag, a hash of
+// the body, completing the version ETag set by handlers of versioned
+// resources (see utils.VersionETag), and answers 304 Not Modified when it matches If-None-Match or,
+// without If-None-Match, when the Last-Modified set by the handler is no
+// later than If-Modified-Since. Anonymous responses are sent with
+// cacheControl, others as private.
+func ConditionalGET(cacheControl string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+
+		writer := &bufferedWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		status := writer.Status()
+		if status == http.StatusOK {
+			header := c.Writer.Header()
+			etag := utils.BodyETag(writer.body.Bytes())
+			if version, ok := utils.ETagVersion(header.Get("ETag")); ok {
+				etag = utils.VersionETag(version, writer.body.Bytes())
+			}
+			header.Set("ETag", etag)
+			if _, ok := c.Get("user"); ok && header.Get("Cache-Control") != noStore {
+				header.Set("Cache-Control", privateCacheControl)
+			} else if !ok && cacheControl != "" {
+				header.Set("Cache-Control", cacheControl)
+			}
+			addVary(header, "Accept-Language", "Authorization", "X-API-Key")
+			if notModified(c.Request, etag, header.Get("Last-Modified")) {
+				header.Del("Content-Type")
+				header.Del("Content-Length")
+				c.Writer.WriteHeader(http.StatusNotModified)
+				c.Writer.WriteHeaderNow()
+				return
+			}
+		}
+		c.Writer.WriteHeader(status)
+		c.Writer.WriteHeaderNow()
+		c.Writer.Write(writer.body.Bytes())
+	}
+}
+
+func notModified(r *http.Request, etag, lastModified string) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == "*" || candidate == etag {
+				return true
+			}
+		}
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	modified, err := http.ParseTime(lastModified)
+	return err == nil && !modified.After(since)
+}
+
+// addVary adds header names to Vary unless they are listed already.
+func addVary(header http.Header, names ...string) {
+	listed := map[string]bool{}
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			listed[http.CanonicalHeaderKey(strings.TrimSpace(name))] = true
+		}
+	}
+	for _, name := range names {
+		if !listed[name] {
+			header.Add("Vary", name)
+		}
+	}
+}
+
+// bufferedWriter holds back the body written through it, so headers can
+// still be changed once the handler is done.
+type bufferedWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {}
+
+func (w *bufferedWriter) Written() bool {
+	return false
+}
+
+func (w *bufferedWriter) Size() int {
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Flush() {}
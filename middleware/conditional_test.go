@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"cms-backend/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var pageModified = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func setupConditionalRouter(user *models.User) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	cache := newMemoryResponseCache()
+	router.Use(func(c *gin.Context) {
+		c.Set("cache", cache)
+		c.Set("site", models.Site{ID: 1})
+		if user != nil {
+			c.Set("user", *user)
+		}
+	})
+	conditional := ConditionalGET("public, max-age=60")
+	router.GET("/pages/:id", conditional, CacheResponse(models.ResourcePage), func(c *gin.Context) {
+		c.Header("Last-Modified", pageModified.Format(http.TimeFormat))
+		c.JSON(http.StatusOK, gin.H{"id": 1, "title": "About"})
+	})
+	router.GET("/missing", conditional, func(c *gin.Context) {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Page not found"})
+	})
+	return router
+}
+
+func conditionalGet(router *gin.Engine, path string, header map[string]string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	for name, value := range header {
+		req.Header.Set(name, value)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestConditionalGETIfNoneMatch(t *testing.T) {
+	router := setupConditionalRouter(nil)
+
+	w := conditionalGet(router, "/pages/1", nil)
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || len(etag) != 34 || w.Body.String() != `{"id":1,"title":"About"}` {
+		t.Fatalf("Expected the page with a strong ETag, but got %d %q: %s", w.Code, etag, w.Body.String())
+	}
+	if w.Header().Get("Cache-Control") != "public, max-age=60" {
+		t.Fatalf("Expected the route's Cache-Control, but got %q", w.Header().Get("Cache-Control"))
+	}
+	if w.Header().Get("Last-Modified") != "Sun, 01 Mar 2026 12:00:00 GMT" {
+		t.Fatalf("Expected the handler's Last-Modified, but got %q", w.Header().Get("Last-Modified"))
+	}
+
+	for _, match := range []string{etag, `"other", ` + etag, "W/" + etag, "*"} {
+		w = conditionalGet(router, "/pages/1", map[string]string{"If-None-Match": match})
+		if w.Code != http.StatusNotModified || w.Body.Len() != 0 || w.Header().Get("ETag") != etag {
+			t.Fatalf("Expected 304 for If-None-Match %s, but got %d: %s", match, w.Code, w.Body.String())
+		}
+		if w.Header().Get("X-Cache") != "HIT" || w.Header().Get("Cache-Control") != "public, max-age=60" {
+			t.Fatalf("Expected a cached 304 with Cache-Control, but got %v", w.Header())
+		}
+	}
+
+	// If-None-Match wins over If-Modified-Since.
+	w = conditionalGet(router, "/pages/1", map[string]string{
+		"If-None-Match":     `"other"`,
+		"If-Modified-Since": pageModified.Format(http.TimeFormat),
+	})
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != etag {
+		t.Fatalf("Expected the page for a stale ETag, but got %d", w.Code)
+	}
+}
+
+func TestConditionalGETIfModifiedSince(t *testing.T) {
+	router := setupConditionalRouter(nil)
+
+	w := conditionalGet(router, "/pages/1", map[string]string{"If-Modified-Since": pageModified.Format(http.TimeFormat)})
+	if w.Code != http.StatusNotModified {
+		t.Fatalf("Expected 304 when unchanged since, but got %d", w.Code)
+	}
+	w = conditionalGet(router, "/pages/1", map[string]string{"If-Modified-Since": pageModified.Add(-time.Second).Format(http.TimeFormat)})
+	if w.Code != http.StatusOK || w.Body.Len() == 0 {
+		t.Fatalf("Expected the page when changed since, but got %d", w.Code)
+	}
+	w = conditionalGet(router, "/pages/1", map[string]string{"If-Modified-Since": "yesterday"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected an unparsable date to be ignored, but got %d", w.Code)
+	}
+}
+
+func TestConditionalGETPrivateAndErrors(t *testing.T) {
+	router := setupConditionalRouter(&models.User{ID: 1, Role: models.UserRoleEditor})
+
+	w := conditionalGet(router, "/pages/1", nil)
+	if w.Header().Get("Cache-Control") != "private, no-cache" {
+		t.Fatalf("Expected authenticated responses to be private, but got %q", w.Header().Get("Cache-Control"))
+	}
+	if vary := w.Header().Values("Vary"); len(vary) != 3 {
+		t.Fatalf("Expected Vary on the language and credentials, but got %v", vary)
+	}
+
+	w = conditionalGet(router, "/missing", map[string]string{"If-None-Match": "*"})
+	if w.Code != http.StatusNotFound || w.Header().Get("ETag") != "" || w.Body.String() != `{"message":"Page not found"}` {
+		t.Fatalf("Expected errors to pass through, but got %d %v: %s", w.Code, w.Header(), w.Body.String())
+	}
+}
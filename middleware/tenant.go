@@ -0,0 +1,158 @@
+package middleware
+
+import (
+	"cms-backend/models"
+	"cms-backend/utils"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ResolveSite picks the site a request works on: the :site path parameter
+// when the route has one, else the site whose domain is the Host header,
+// else the site of the request's API key, else the default site. An API
+// key is only valid on its own site. The site is stored in the context as
+// "site", and "db" is replaced by a connection confined to it.
+func ResolveSite() gin.HandlerFunc {
+	return resolveSite(true)
+}
+
+// ResolveSiteUnpinned is ResolveSite for long-lived requests such as event
+// streams. Rather than holding a connection confined to the site for the
+// whole request, "db" is only scoped to the site by the query callbacks, so
+// it takes a pooled connection per statement.
+func ResolveSiteUnpinned() gin.HandlerFunc {
+	return resolveSite(false)
+}
+
+// SystemDB makes "db" the "system_db" connection, which row-level security
+// does not confine to a site, for routes working on the whole deployment.
+func SystemDB() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("db", systemDB(c))
+		c.Next()
+	}
+}
+
+// systemDB is the "system_db" connection, or "db" when there is none.
+func systemDB(c *gin.Context) *gorm.DB {
+	if db, ok := c.Get("system_db"); ok {
+		return db.(*gorm.DB)
+	}
+	return c.MustGet("db").(*gorm.DB)
+}
+
+func resolveSite(pin bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := c.MustGet("db").(*gorm.DB)
+
+		var site models.Site
+		found := false
+		if slug := strings.ToLower(c.Param("site")); slug != "" {
+			var err error
+			site, found, err = lookupSite(c, "slug:"+slug, func(site *models.Site) *gorm.DB {
+				return db.Where("slug = ?", slug).Limit(1).Find(site)
+			})
+			if err != nil {
+				abortError(c, err)
+				return
+			}
+			if !found {
+				c.AbortWithStatusJSON(http.StatusNotFound, utils.HTTPError{
+					Code:    http.StatusNotFound,
+					Message: "Site not found",
+				})
+				return
+			}
+		} else if host := requestHost(c.Request); host != "" {
+			var err error
+			site, found, err = lookupSite(c, "domain:"+host, func(site *models.Site) *gorm.DB {
+				return db.Where("domain = ?", host).Limit(1).Find(site)
+			})
+			if err != nil {
+				abortError(c, err)
+				return
+			}
+		}
+
+		siteID := uint(models.DefaultSiteID)
+		if found {
+			siteID = site.ID
+		}
+		if value, ok := c.Get("api_key"); ok {
+			apiKey := value.(models.APIKey)
+			if found && apiKey.SiteID != site.ID {
+				abortForbidden(c, "API key belongs to another site")
+				return
+			}
+			siteID = apiKey.SiteID
+		}
+		if site.ID != siteID {
+			var err error
+			site, found, err = lookupSite(c, "id:"+strconv.FormatUint(uint64(siteID), 10), func(site *models.Site) *gorm.DB {
+				return db.Limit(1).Find(site, siteID)
+			})
+			if err == nil && !found {
+				err = gorm.ErrRecordNotFound
+			}
+			if err != nil {
+				abortError(c, err)
+				return
+			}
+		}
+
+		c.Set("site", site)
+		if !pin {
+			c.Set("db", db.WithContext(utils.WithSite(c.Request.Context(), site.ID)))
+			c.Next()
+			return
+		}
+
+		scoped, release, err := utils.PinSite(c.Request.Context(), db, site.ID)
+		if err != nil {
+			abortError(c, err)
+			return
+		}
+		defer release()
+
+		c.Set("db", scoped)
+		c.Next()
+	}
+}
+
+// lookupSite runs query for the site under key, going through the
+// "site_cache" context value when there is one. Misses are cached too, so
+// requests for unknown hosts do not query the database either.
+func lookupSite(c *gin.Context, key string, query func(*models.Site) *gorm.DB) (models.Site, bool, error) {
+	var cache *utils.LocalCache
+	if value, ok := c.Get("site_cache"); ok {
+		cache = value.(*utils.LocalCache)
+		if cached, ok := cache.Get(key); ok {
+			site := cached.(models.Site)
+			return site, site.ID != 0, nil
+		}
+	}
+
+	var site models.Site
+	result := query(&site)
+	if result.Error != nil {
+		return site, false, result.Error
+	}
+	if cache != nil {
+		cache.Set(key, site)
+	}
+	return site, result.RowsAffected > 0, nil
+}
+
+// requestHost is the lowercased Host header without its port.
+func requestHost(r *http.Request) string {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(host)
+}
@@ -0,0 +1,225 @@
+package middleware
+
+import (
+	"cms-backend/models"
+	"cms-backend/utils"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func setupSiteRouter(t *testing.T) (*gin.Engine, sqlmock.Sqlmock) {
+	return setupCachedSiteRouter(t, nil)
+}
+
+// setupCachedSiteRouter is setupSiteRouter resolving sites through cache
+// when it is not nil.
+func setupCachedSiteRouter(t *testing.T, cache *utils.LocalCache) (*gin.Engine, sqlmock.Sqlmock) {
+	router, _, mock := utils.SetupRouterAndMockDBAs(t, nil)
+	if cache != nil {
+		router.Use(func(c *gin.Context) {
+			c.Set("site_cache", cache)
+		})
+	}
+	router.Use(AuthenticateAPIKey())
+	pages := func(c *gin.Context) {
+		var pages []models.Page
+		if err := c.MustGet("db").(*gorm.DB).Find(&pages).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"site": c.MustGet("site").(models.Site).Slug, "pages": len(pages)})
+	}
+	router.GET("/pages", ResolveSite(), pages)
+	router.GET("/sites/:site/pages", ResolveSite(), pages)
+	router.GET("/sites/:site/stream", ResolveSiteUnpinned(), pages)
+	return router, mock
+}
+
+var siteColumns = []string{"id", "slug", "name", "domain"}
+
+// expectSitePages expects the pinned connection of a request on the site
+// and the scoped page query the handler runs on it.
+func expectSitePages(mock sqlmock.Sqlmock, siteID uint) {
+	mock.ExpectExec(`SELECT set_config\('app.site_id', \$1, false\)`).
+		WithArgs(strconv.FormatUint(uint64(siteID), 10)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT \* FROM "pages" WHERE "pages"\."site_id" = \$1`).
+		WithArgs(siteID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "site_id"}).AddRow(1, siteID))
+	mock.ExpectExec(`RESET app.site_id`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func TestResolveSiteByPath(t *testing.T) {
+	router, mock := setupSiteRouter(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT \* FROM "sites" WHERE slug = \$1`).
+		WithArgs("brand", 1).
+		WillReturnRows(sqlmock.NewRows(siteColumns).AddRow(2, "brand", "Brand", nil))
+	expectSitePages(mock, 2)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/sites/Brand/pages", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || w.Body.String() != `{"pages":1,"site":"brand"}` {
+		t.Fatalf("Expected the brand site's pages, but got %d: %s", w.Code, w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("Expected the connection to be reset: %v", err)
+	}
+}
+
+func TestResolveSiteByHost(t *testing.T) {
+	router, mock := setupSiteRouter(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT \* FROM "sites" WHERE domain = \$1 LIMIT \$2`).
+		WithArgs("brand.example.com", 1).
+		WillReturnRows(sqlmock.NewRows(siteColumns).AddRow(2, "brand", "Brand", "brand.example.com"))
+	expectSitePages(mock, 2)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/pages", nil)
+	req.Host = "Brand.example.com:8080"
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || w.Body.String() != `{"pages":1,"site":"brand"}` {
+		t.Fatalf("Expected the brand site's pages, but got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestResolveSiteFallsBackToDefault(t *testing.T) {
+	router, mock := setupSiteRouter(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT \* FROM "sites" WHERE domain = \$1`).
+		WithArgs("cms.example.com", 1).
+		WillReturnRows(sqlmock.NewRows(siteColumns))
+	mock.ExpectQuery(`SELECT \* FROM "sites" WHERE "sites"\."id" = \$1`).
+		WithArgs(models.DefaultSiteID, 1).
+		WillReturnRows(sqlmock.NewRows(siteColumns).AddRow(1, "default", "Default", nil))
+	expectSitePages(mock, 1)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/pages", nil)
+	req.Host = "cms.example.com"
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || w.Body.String() != `{"pages":1,"site":"default"}` {
+		t.Fatalf("Expected the default site's pages, but got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestResolveSiteCachesLookups(t *testing.T) {
+	cache := utils.NewLocalCache(func() time.Duration { return time.Minute })
+	router, mock := setupCachedSiteRouter(t, cache)
+	defer mock.ExpectClose()
+
+	expectLookups := func() {
+		mock.ExpectQuery(`SELECT \* FROM "sites" WHERE domain = \$1`).
+			WithArgs("cms.example.com", 1).
+			WillReturnRows(sqlmock.NewRows(siteColumns))
+		mock.ExpectQuery(`SELECT \* FROM "sites" WHERE "sites"\."id" = \$1`).
+			WithArgs(models.DefaultSiteID, 1).
+			WillReturnRows(sqlmock.NewRows(siteColumns).AddRow(1, "default", "Default", nil))
+	}
+	request := func() {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/pages", nil)
+		req.Host = "cms.example.com"
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK || w.Body.String() != `{"pages":1,"site":"default"}` {
+			t.Fatalf("Expected the default site's pages, but got %d: %s", w.Code, w.Body.String())
+		}
+	}
+
+	// The unknown host is cached as a miss, the default site as a hit.
+	expectLookups()
+	expectSitePages(mock, 1)
+	request()
+	expectSitePages(mock, 1)
+	request()
+
+	cache.Clear()
+	expectLookups()
+	expectSitePages(mock, 1)
+	request()
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("Expected sites to be looked up once per invalidation: %v", err)
+	}
+}
+
+func TestResolveSiteUnpinned(t *testing.T) {
+	router, mock := setupSiteRouter(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT \* FROM "sites" WHERE slug = \$1`).
+		WithArgs("brand", 1).
+		WillReturnRows(sqlmock.NewRows(siteColumns).AddRow(2, "brand", "Brand", nil))
+	mock.ExpectQuery(`SELECT \* FROM "pages" WHERE "pages"\."site_id" = \$1`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "site_id"}).AddRow(1, 2))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/sites/brand/stream", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || w.Body.String() != `{"pages":1,"site":"brand"}` {
+		t.Fatalf("Expected the brand site's pages, but got %d: %s", w.Code, w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("Expected scoped queries without a pinned connection: %v", err)
+	}
+}
+
+func TestResolveSiteUnknownSlug(t *testing.T) {
+	router, mock := setupSiteRouter(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT \* FROM "sites" WHERE slug = \$1`).
+		WithArgs("missing", 1).
+		WillReturnRows(sqlmock.NewRows(siteColumns))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/sites/missing/pages", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("Expected status 404, but got %d", w.Code)
+	}
+}
+
+func TestResolveSiteRejectsAPIKeyOfAnotherSite(t *testing.T) {
+	router, mock := setupSiteRouter(t)
+	defer mock.ExpectClose()
+
+	mock.ExpectQuery(`SELECT \* FROM "api_keys" WHERE key_hash = \$1`).
+		WithArgs(utils.HashToken("cms_0123abcd_secret"), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "site_id", "user_id", "scopes", "last_used_at"}).
+			AddRow(4, 1, 3, "pages:read", time.Now()))
+	mock.ExpectQuery(`SELECT \* FROM "users"`).
+		WithArgs(3, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "role"}).AddRow(3, models.UserRoleEditor))
+	mock.ExpectQuery(`SELECT \* FROM "sites" WHERE slug = \$1`).
+		WithArgs("brand", 1).
+		WillReturnRows(sqlmock.NewRows(siteColumns).AddRow(2, "brand", "Brand", nil))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/sites/brand/pages", nil)
+	req.Header.Set("X-API-Key", "cms_0123abcd_secret")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403, but got %d: %s", w.Code, w.Body.String())
+	}
+}
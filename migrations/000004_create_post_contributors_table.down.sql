@@ -0,0 +1,8 @@
+-- This migration drops the post_contributors table
+
+-- Drop indexes first
+DROP INDEX IF EXISTS idx_post_contributors_name_role;
+DROP INDEX IF EXISTS idx_post_contributors_post_id;
+
+-- Drop the post_contributors table
+DROP TABLE IF EXISTS post_contributors;
@@ -0,0 +1,26 @@
+-- This migration creates the post_contributors table and backfills it from posts.author
+
+CREATE TABLE post_contributors (
+    -- id is the primary key for the table
+    id SERIAL PRIMARY KEY,
+    -- post_id references the posts table
+    post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    -- name is the credited contributor's display name
+    name VARCHAR(100) NOT NULL,
+    -- role is the kind of credit (author, editor, photographer)
+    role VARCHAR(50) NOT NULL,
+    -- position orders the credits on a post
+    position INTEGER NOT NULL DEFAULT 0,
+    -- created_at is the timestamp when the credit was created
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    -- updated_at is the timestamp when the credit was last updated
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Existing single-author posts become a single author credit
+INSERT INTO post_contributors (post_id, name, role, position)
+SELECT id, author, 'author', 0 FROM posts WHERE author IS NOT NULL AND author <> '';
+
+-- Add indexes for performance
+CREATE INDEX idx_post_contributors_post_id ON post_contributors(post_id);
+CREATE INDEX idx_post_contributors_name_role ON post_contributors(name, role);
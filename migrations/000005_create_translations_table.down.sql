@@ -0,0 +1,7 @@
+-- This migration drops the translations table
+
+-- Drop indexes first
+DROP INDEX IF EXISTS idx_translations_resource_locale;
+
+-- Drop the translations table
+DROP TABLE IF EXISTS translations;
@@ -0,0 +1,25 @@
+-- This migration creates the translations table for locale variants of pages and posts
+
+CREATE TABLE translations (
+    -- id is the primary key for the table
+    id SERIAL PRIMARY KEY,
+    -- resource_type is the kind of canonical item translated (page, post)
+    resource_type VARCHAR(20) NOT NULL,
+    -- resource_id is the id of the canonical page or post
+    resource_id INTEGER NOT NULL,
+    -- locale is the BCP 47 tag of the translation (de, fr, de-at)
+    locale VARCHAR(20) NOT NULL,
+    -- title is the translated title
+    title VARCHAR(255) NOT NULL,
+    -- content is the translated content
+    content TEXT NOT NULL,
+    -- source_updated_at is the canonical item's updated_at when this translation was written
+    source_updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    -- created_at is the timestamp when the translation was created
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    -- updated_at is the timestamp when the translation was last updated
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- One translation per item and locale
+CREATE UNIQUE INDEX idx_translations_resource_locale ON translations(resource_type, resource_id, locale);
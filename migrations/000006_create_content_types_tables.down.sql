@@ -0,0 +1,12 @@
+-- This migration drops the content_entries and content_types tables
+
+-- Drop indexes first
+DROP INDEX IF EXISTS idx_content_entries_data;
+DROP INDEX IF EXISTS idx_content_entries_content_type_id;
+DROP INDEX IF EXISTS idx_content_types_slug;
+
+-- Drop content_entries first (due to foreign key constraints)
+DROP TABLE IF EXISTS content_entries;
+
+-- Drop content_types after content_entries is removed
+DROP TABLE IF EXISTS content_types;
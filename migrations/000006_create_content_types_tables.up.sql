@@ -0,0 +1,36 @@
+-- This migration creates the content_types registry and the generic content_entries table
+
+CREATE TABLE content_types (
+    -- id is the primary key for the table
+    id SERIAL PRIMARY KEY,
+    -- slug identifies the type in /api/v1/content/:type
+    slug VARCHAR(100) NOT NULL,
+    -- name is the human readable name of the type
+    name VARCHAR(255) NOT NULL,
+    -- description explains what the type is used for
+    description TEXT,
+    -- schema is the JSON Schema every entry's data must satisfy
+    schema JSONB NOT NULL,
+    -- created_at is the timestamp when the type was created
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    -- updated_at is the timestamp when the type was last updated
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE content_entries (
+    -- id is the primary key for the table
+    id SERIAL PRIMARY KEY,
+    -- content_type_id references the content_types table
+    content_type_id INTEGER NOT NULL REFERENCES content_types(id) ON DELETE CASCADE,
+    -- data holds the entry's fields, validated against the type's schema
+    data JSONB NOT NULL,
+    -- created_at is the timestamp when the entry was created
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    -- updated_at is the timestamp when the entry was last updated
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Add indexes for performance
+CREATE UNIQUE INDEX idx_content_types_slug ON content_types(slug);
+CREATE INDEX idx_content_entries_content_type_id ON content_entries(content_type_id);
+CREATE INDEX idx_content_entries_data ON content_entries USING GIN (data);
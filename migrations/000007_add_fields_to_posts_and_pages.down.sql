@@ -0,0 +1,8 @@
+-- This migration removes custom JSONB fields from posts and pages
+
+-- Drop indexes first
+DROP INDEX IF EXISTS idx_pages_fields;
+DROP INDEX IF EXISTS idx_posts_fields;
+
+ALTER TABLE pages DROP COLUMN IF EXISTS fields;
+ALTER TABLE posts DROP COLUMN IF EXISTS fields;
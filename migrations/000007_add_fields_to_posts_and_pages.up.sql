@@ -0,0 +1,9 @@
+-- This migration adds custom JSONB fields to posts and pages
+
+-- fields holds arbitrary structured data (subtitle, cta_link, event_date, ...)
+ALTER TABLE posts ADD COLUMN fields JSONB;
+ALTER TABLE pages ADD COLUMN fields JSONB;
+
+-- GIN indexes serve the jsonpath (@@) filters on list endpoints
+CREATE INDEX idx_posts_fields ON posts USING GIN (fields);
+CREATE INDEX idx_pages_fields ON pages USING GIN (fields);
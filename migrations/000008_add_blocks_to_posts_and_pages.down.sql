@@ -0,0 +1,4 @@
+-- This migration removes structured block documents from posts and pages
+
+ALTER TABLE pages DROP COLUMN IF EXISTS blocks;
+ALTER TABLE posts DROP COLUMN IF EXISTS blocks;
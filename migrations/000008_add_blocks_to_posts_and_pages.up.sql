@@ -0,0 +1,5 @@
+-- This migration adds structured block documents to posts and pages
+
+-- blocks holds the ordered block document; content keeps its plain-text projection
+ALTER TABLE posts ADD COLUMN blocks JSONB;
+ALTER TABLE pages ADD COLUMN blocks JSONB;
@@ -0,0 +1,6 @@
+-- This migration removes the authoring format and cached HTML rendering from posts and pages
+
+ALTER TABLE pages DROP COLUMN IF EXISTS content_html;
+ALTER TABLE posts DROP COLUMN IF EXISTS content_html;
+ALTER TABLE pages DROP COLUMN IF EXISTS content_format;
+ALTER TABLE posts DROP COLUMN IF EXISTS content_format;
@@ -0,0 +1,9 @@
+-- This migration adds the authoring format and cached HTML rendering to posts and pages
+
+-- content_format is how content is written (markdown, html, plain)
+ALTER TABLE posts ADD COLUMN content_format VARCHAR(20) NOT NULL DEFAULT 'plain';
+ALTER TABLE pages ADD COLUMN content_format VARCHAR(20) NOT NULL DEFAULT 'plain';
+
+-- content_html is the sanitized HTML rendering of content, refreshed on every write
+ALTER TABLE posts ADD COLUMN content_html TEXT;
+ALTER TABLE pages ADD COLUMN content_html TEXT;
@@ -0,0 +1,13 @@
+-- This migration removes full-text search vectors from posts and pages
+
+-- Drop indexes first
+DROP INDEX IF EXISTS idx_pages_search_vector;
+DROP INDEX IF EXISTS idx_posts_search_vector;
+
+-- Drop triggers before the function they use
+DROP TRIGGER IF EXISTS pages_search_vector_update ON pages;
+DROP TRIGGER IF EXISTS posts_search_vector_update ON posts;
+DROP FUNCTION IF EXISTS cms_search_vector_update();
+
+ALTER TABLE pages DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE posts DROP COLUMN IF EXISTS search_vector;
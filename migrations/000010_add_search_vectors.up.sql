@@ -0,0 +1,31 @@
+-- This migration adds weighted full-text search vectors to posts and pages
+
+-- search_vector holds title (weight A) and content (weight B) lexemes
+ALTER TABLE posts ADD COLUMN search_vector tsvector;
+ALTER TABLE pages ADD COLUMN search_vector tsvector;
+
+-- cms_search_vector_update keeps search_vector in sync on writes; its comment
+-- records the text search configuration it was built with (SEARCH_LANGUAGE)
+CREATE OR REPLACE FUNCTION cms_search_vector_update() RETURNS trigger AS $$
+BEGIN
+    NEW.search_vector :=
+        setweight(to_tsvector('english', COALESCE(NEW.title, '')), 'A') ||
+        setweight(to_tsvector('english', COALESCE(NEW.content, '')), 'B');
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION cms_search_vector_update() IS 'english';
+
+CREATE TRIGGER posts_search_vector_update BEFORE INSERT OR UPDATE OF title, content ON posts
+    FOR EACH ROW EXECUTE FUNCTION cms_search_vector_update();
+CREATE TRIGGER pages_search_vector_update BEFORE INSERT OR UPDATE OF title, content ON pages
+    FOR EACH ROW EXECUTE FUNCTION cms_search_vector_update();
+
+-- Backfill existing rows through the trigger
+UPDATE posts SET title = title;
+UPDATE pages SET title = title;
+
+-- GIN indexes serve the @@ matches of /api/v1/search
+CREATE INDEX idx_posts_search_vector ON posts USING GIN (search_vector);
+CREATE INDEX idx_pages_search_vector ON pages USING GIN (search_vector);
@@ -0,0 +1,5 @@
+-- This migration removes the trigram indexes used by search suggestions
+
+DROP INDEX IF EXISTS idx_post_contributors_name_trgm;
+DROP INDEX IF EXISTS idx_pages_title_trgm;
+DROP INDEX IF EXISTS idx_posts_title_trgm;
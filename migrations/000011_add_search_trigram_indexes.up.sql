@@ -0,0 +1,7 @@
+-- This migration adds trigram indexes for search suggestions and corrections
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX idx_posts_title_trgm ON posts USING GIN (title gin_trgm_ops);
+CREATE INDEX idx_pages_title_trgm ON pages USING GIN (title gin_trgm_ops);
+CREATE INDEX idx_post_contributors_name_trgm ON post_contributors USING GIN (name gin_trgm_ops);
@@ -0,0 +1,5 @@
+-- This migration removes users and the token authentication tables
+
+DROP TABLE IF EXISTS revoked_tokens;
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,48 @@
+-- This migration creates users and the tables backing token authentication
+
+CREATE TABLE users (
+    -- id is the primary key for the table
+    id SERIAL PRIMARY KEY,
+    -- email is the login name of the user
+    email VARCHAR(255) NOT NULL,
+    -- name is the display name of the user
+    name VARCHAR(100),
+    -- password_hash is the bcrypt hash of the user's password
+    password_hash VARCHAR(255) NOT NULL,
+    -- created_at is the timestamp when the user was created
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    -- updated_at is the timestamp when the user was last updated
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_users_email ON users(email);
+
+CREATE TABLE refresh_tokens (
+    -- id is the primary key for the table
+    id SERIAL PRIMARY KEY,
+    -- user_id is the user the token was issued to
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- token_hash is the SHA-256 hex digest of the opaque token
+    token_hash VARCHAR(64) NOT NULL,
+    -- family_id groups a login's chain of rotated tokens
+    family_id VARCHAR(64) NOT NULL,
+    -- expires_at is when the token stops being accepted
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    -- revoked_at is set once the token was rotated or logged out
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    -- created_at is the timestamp when the token was issued
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_refresh_tokens_token_hash ON refresh_tokens(token_hash);
+CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens(user_id);
+CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens(family_id);
+
+CREATE TABLE revoked_tokens (
+    -- jti is the ID of the revoked access token
+    jti VARCHAR(64) PRIMARY KEY,
+    -- expires_at is when the access token expires; the row can go after that
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_revoked_tokens_expires_at ON revoked_tokens(expires_at);
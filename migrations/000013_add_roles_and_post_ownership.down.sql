@@ -0,0 +1,8 @@
+-- This migration removes user roles and post ownership and status
+
+DROP INDEX IF EXISTS idx_posts_owner_id;
+DROP INDEX IF EXISTS idx_posts_status;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS owner_id;
+ALTER TABLE posts DROP COLUMN IF EXISTS status;
+ALTER TABLE users DROP COLUMN IF EXISTS role;
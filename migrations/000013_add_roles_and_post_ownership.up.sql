@@ -0,0 +1,15 @@
+-- This migration adds user roles and post ownership and status for access control
+
+-- role is admin, editor, author or viewer
+ALTER TABLE users ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'viewer';
+
+-- Everyone could do everything before roles existed, so existing users keep that
+UPDATE users SET role = 'admin';
+
+-- status is draft or published; drafts are hidden from the public
+ALTER TABLE posts ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'published';
+-- owner_id is the user who created the post; authors may only edit their own
+ALTER TABLE posts ADD COLUMN owner_id INTEGER REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_posts_status ON posts(status);
+CREATE INDEX idx_posts_owner_id ON posts(owner_id);
@@ -0,0 +1,3 @@
+-- This migration removes the api_keys table
+
+DROP TABLE IF EXISTS api_keys;
@@ -0,0 +1,26 @@
+-- This migration creates the api_keys table for machine clients
+
+CREATE TABLE api_keys (
+    -- id is the primary key for the table
+    id SERIAL PRIMARY KEY,
+    -- user_id is the user the key acts as
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- name describes what the key is used for
+    name VARCHAR(100) NOT NULL,
+    -- prefix is the public start of the key that identifies it
+    prefix VARCHAR(16) NOT NULL,
+    -- key_hash is the SHA-256 hex digest of the full key
+    key_hash VARCHAR(64) NOT NULL,
+    -- scopes is the space separated list of scopes the key holds
+    scopes TEXT NOT NULL,
+    -- expires_at is when the key stops being accepted; NULL never expires
+    expires_at TIMESTAMP WITH TIME ZONE,
+    -- last_used_at is roughly when the key was last used
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    -- created_at is the timestamp when the key was issued
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_api_keys_prefix ON api_keys(prefix);
+CREATE UNIQUE INDEX idx_api_keys_key_hash ON api_keys(key_hash);
+CREATE INDEX idx_api_keys_user_id ON api_keys(user_id);
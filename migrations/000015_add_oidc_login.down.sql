@@ -0,0 +1,5 @@
+-- This migration removes single sign-on through OpenID Connect
+
+DROP TABLE IF EXISTS oidc_states;
+DROP INDEX IF EXISTS idx_users_oidc_subject;
+ALTER TABLE users DROP COLUMN IF EXISTS oidc_subject;
@@ -0,0 +1,19 @@
+-- This migration adds single sign-on through OpenID Connect
+
+-- oidc_subject links a user to their identity provider account
+ALTER TABLE users ADD COLUMN oidc_subject VARCHAR(255);
+
+CREATE UNIQUE INDEX idx_users_oidc_subject ON users(oidc_subject);
+
+CREATE TABLE oidc_states (
+    -- state_hash is the SHA-256 hex digest of the state parameter
+    state_hash VARCHAR(64) PRIMARY KEY,
+    -- nonce is the value the ID token must carry back
+    nonce VARCHAR(64) NOT NULL,
+    -- code_verifier is the PKCE verifier for the authorization code
+    code_verifier VARCHAR(128) NOT NULL,
+    -- expires_at is when the pending login is abandoned
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_oidc_states_expires_at ON oidc_states(expires_at);
@@ -0,0 +1,4 @@
+-- This migration removes the audit log
+
+DROP TABLE IF EXISTS audit_logs;
+DROP FUNCTION IF EXISTS cms_audit_logs_append_only();
@@ -0,0 +1,46 @@
+-- This migration creates the append-only audit log of content mutations
+
+CREATE TABLE audit_logs (
+    -- id is the primary key for the table
+    id SERIAL PRIMARY KEY,
+    -- actor_id is the user who made the change; kept without a foreign key so entries outlive users
+    actor_id INTEGER,
+    -- actor_email is the email of the actor at the time of the change
+    actor_email VARCHAR(255),
+    -- action is create, update or delete
+    action VARCHAR(20) NOT NULL,
+    -- resource_type is page, post or media
+    resource_type VARCHAR(20) NOT NULL,
+    -- resource_id is the ID of the changed resource
+    resource_id INTEGER NOT NULL,
+    -- before is the resource before the change; NULL for creates
+    before JSONB,
+    -- after is the resource after the change; NULL for deletes
+    after JSONB,
+    -- ip is the client address of the request
+    ip VARCHAR(45),
+    -- user_agent is the User-Agent header of the request
+    user_agent TEXT,
+    -- request_id is the X-Request-ID of the request
+    request_id VARCHAR(64),
+    -- created_at is the timestamp of the change
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_audit_logs_actor_id ON audit_logs(actor_id);
+CREATE INDEX idx_audit_logs_action ON audit_logs(action);
+CREATE INDEX idx_audit_logs_resource ON audit_logs(resource_type, resource_id);
+CREATE INDEX idx_audit_logs_request_id ON audit_logs(request_id);
+CREATE INDEX idx_audit_logs_created_at ON audit_logs(created_at);
+
+CREATE OR REPLACE FUNCTION cms_audit_logs_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_logs is append-only';
+END
+$$ LANGUAGE plpgsql;
+
+-- Entries can be added but never changed or removed
+CREATE TRIGGER audit_logs_append_only BEFORE UPDATE OR DELETE ON audit_logs
+    FOR EACH ROW EXECUTE FUNCTION cms_audit_logs_append_only();
+CREATE TRIGGER audit_logs_no_truncate BEFORE TRUNCATE ON audit_logs
+    FOR EACH STATEMENT EXECUTE FUNCTION cms_audit_logs_append_only();
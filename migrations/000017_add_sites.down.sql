@@ -0,0 +1,41 @@
+-- This migration removes sites, merging the content of every site back into one
+
+DROP POLICY IF EXISTS site_isolation ON api_keys;
+ALTER TABLE api_keys NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE api_keys DISABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS site_isolation ON audit_logs;
+ALTER TABLE audit_logs NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE audit_logs DISABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS site_isolation ON content_entries;
+ALTER TABLE content_entries NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE content_entries DISABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS site_isolation ON content_types;
+ALTER TABLE content_types NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE content_types DISABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS site_isolation ON media;
+ALTER TABLE media NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE media DISABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS site_isolation ON pages;
+ALTER TABLE pages NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE pages DISABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS site_isolation ON posts;
+ALTER TABLE posts NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE posts DISABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS site_isolation ON translations;
+ALTER TABLE translations NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE translations DISABLE ROW LEVEL SECURITY;
+DROP FUNCTION IF EXISTS cms_current_site();
+
+DROP INDEX IF EXISTS idx_content_types_site_slug;
+CREATE UNIQUE INDEX idx_content_types_slug ON content_types(slug);
+
+ALTER TABLE pages DROP COLUMN IF EXISTS site_id;
+ALTER TABLE posts DROP COLUMN IF EXISTS site_id;
+ALTER TABLE media DROP COLUMN IF EXISTS site_id;
+ALTER TABLE translations DROP COLUMN IF EXISTS site_id;
+ALTER TABLE content_types DROP COLUMN IF EXISTS site_id;
+ALTER TABLE content_entries DROP COLUMN IF EXISTS site_id;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS site_id;
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS site_id;
+
+DROP TABLE IF EXISTS sites;
@@ -0,0 +1,77 @@
+-- This migration adds sites, so several isolated sites share one deployment
+
+CREATE TABLE sites (
+    -- id is the primary key for the table
+    id SERIAL PRIMARY KEY,
+    -- slug identifies the site in /sites/:site/api/v1
+    slug VARCHAR(50) NOT NULL,
+    -- name is the human readable name of the site
+    name VARCHAR(100) NOT NULL,
+    -- domain is the host name requests for the site are sent to
+    domain VARCHAR(255),
+    -- created_at is the timestamp when the site was created
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    -- updated_at is the timestamp when the site was last updated
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_sites_slug ON sites(slug);
+CREATE UNIQUE INDEX idx_sites_domain ON sites(domain);
+
+-- Everything written before sites existed belongs to the default site
+INSERT INTO sites (id, slug, name) VALUES (1, 'default', 'Default');
+SELECT setval(pg_get_serial_sequence('sites', 'id'), 1);
+
+-- site_id is the site owning the row
+ALTER TABLE pages ADD COLUMN site_id INTEGER NOT NULL DEFAULT 1 REFERENCES sites(id);
+ALTER TABLE posts ADD COLUMN site_id INTEGER NOT NULL DEFAULT 1 REFERENCES sites(id);
+ALTER TABLE media ADD COLUMN site_id INTEGER NOT NULL DEFAULT 1 REFERENCES sites(id);
+ALTER TABLE translations ADD COLUMN site_id INTEGER NOT NULL DEFAULT 1 REFERENCES sites(id);
+ALTER TABLE content_types ADD COLUMN site_id INTEGER NOT NULL DEFAULT 1 REFERENCES sites(id);
+ALTER TABLE content_entries ADD COLUMN site_id INTEGER NOT NULL DEFAULT 1 REFERENCES sites(id);
+ALTER TABLE api_keys ADD COLUMN site_id INTEGER NOT NULL DEFAULT 1 REFERENCES sites(id);
+-- audit_logs keeps no foreign key so entries outlive their site
+ALTER TABLE audit_logs ADD COLUMN site_id INTEGER NOT NULL DEFAULT 1;
+
+CREATE INDEX idx_pages_site_id ON pages(site_id);
+CREATE INDEX idx_posts_site_id ON posts(site_id);
+CREATE INDEX idx_media_site_id ON media(site_id);
+CREATE INDEX idx_translations_site_id ON translations(site_id);
+CREATE INDEX idx_content_entries_site_id ON content_entries(site_id);
+CREATE INDEX idx_api_keys_site_id ON api_keys(site_id);
+CREATE INDEX idx_audit_logs_site_id ON audit_logs(site_id);
+
+-- Content type slugs are unique per site
+DROP INDEX IF EXISTS idx_content_types_slug;
+CREATE UNIQUE INDEX idx_content_types_site_slug ON content_types(site_id, slug);
+
+-- cms_current_site is the site the connection is confined to, NULL when unrestricted
+CREATE OR REPLACE FUNCTION cms_current_site() RETURNS integer AS $$
+    SELECT NULLIF(current_setting('app.site_id', true), '')::integer
+$$ LANGUAGE sql STABLE;
+
+-- Row-level security confines every site-owned table to app.site_id; it does not apply to superusers
+ALTER TABLE api_keys ENABLE ROW LEVEL SECURITY;
+ALTER TABLE api_keys FORCE ROW LEVEL SECURITY;
+CREATE POLICY site_isolation ON api_keys USING (cms_current_site() IS NULL OR site_id = cms_current_site());
+ALTER TABLE audit_logs ENABLE ROW LEVEL SECURITY;
+ALTER TABLE audit_logs FORCE ROW LEVEL SECURITY;
+CREATE POLICY site_isolation ON audit_logs USING (cms_current_site() IS NULL OR site_id = cms_current_site());
+ALTER TABLE content_entries ENABLE ROW LEVEL SECURITY;
+ALTER TABLE content_entries FORCE ROW LEVEL SECURITY;
+CREATE POLICY site_isolation ON content_entries USING (cms_current_site() IS NULL OR site_id = cms_current_site());
+ALTER TABLE content_types ENABLE ROW LEVEL SECURITY;
+ALTER TABLE content_types FORCE ROW LEVEL SECURITY;
+CREATE POLICY site_isolation ON content_types USING (cms_current_site() IS NULL OR site_id = cms_current_site());
+ALTER TABLE media ENABLE ROW LEVEL SECURITY;
+ALTER TABLE media FORCE ROW LEVEL SECURITY;
+CREATE POLICY site_isolation ON media USING (cms_current_site() IS NULL OR site_id = cms_current_site());
+ALTER TABLE pages ENABLE ROW LEVEL SECURITY;
+ALTER TABLE pages FORCE ROW LEVEL SECURITY;
+CREATE POLICY site_isolation ON pages USING (cms_current_site() IS NULL OR site_id = cms_current_site());
+ALTER TABLE posts ENABLE ROW LEVEL SECURITY;
+ALTER TABLE posts FORCE ROW LEVEL SECURITY;
+CREATE POLICY site_isolation ON posts USING (cms_current_site() IS NULL OR site_id = cms_current_site());
+ALTER TABLE translations ENABLE ROW LEVEL SECURITY;
+ALTER TABLE translations FORCE ROW LEVEL SECURITY;
+CREATE POLICY site_isolation ON translations USING (cms_current_site() IS NULL OR site_id = cms_current_site());
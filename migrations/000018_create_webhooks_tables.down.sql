@@ -0,0 +1,4 @@
+-- This migration drops webhook subscriptions and their delivery log
+
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
@@ -0,0 +1,68 @@
+-- This migration creates webhook subscriptions and their delivery log
+
+CREATE TABLE webhooks (
+    -- id is the primary key for the table
+    id SERIAL PRIMARY KEY,
+    -- site_id is the site whose events the webhook receives
+    site_id INTEGER NOT NULL DEFAULT 1 REFERENCES sites(id),
+    -- url is the endpoint events are posted to
+    url VARCHAR(2048) NOT NULL,
+    -- secret is the HMAC-SHA256 key deliveries are signed with
+    secret VARCHAR(255) NOT NULL,
+    -- events is a space-separated list of event types, '*' or '<resource>.*' patterns
+    events TEXT NOT NULL,
+    -- active is false for webhooks that should receive nothing
+    active BOOLEAN NOT NULL,
+    -- created_at is the timestamp when the webhook was created
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    -- updated_at is the timestamp when the webhook was last updated
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_webhooks_site_id ON webhooks(site_id);
+
+CREATE TABLE webhook_deliveries (
+    -- id is the primary key for the table, sent as X-Webhook-Delivery
+    id SERIAL PRIMARY KEY,
+    -- site_id is the site of the delivered event
+    site_id INTEGER NOT NULL DEFAULT 1 REFERENCES sites(id),
+    -- webhook_id is the webhook the event is delivered to
+    webhook_id INTEGER NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    -- event_id identifies the event; redeliveries share it
+    event_id VARCHAR(64) NOT NULL,
+    -- event is the event type, sent as X-Webhook-Event
+    event VARCHAR(50) NOT NULL,
+    -- payload is the event body exactly as it is signed and sent
+    payload JSONB NOT NULL,
+    -- status is pending, succeeded, failed (to be retried) or dead
+    status VARCHAR(20) NOT NULL,
+    -- attempts is the number of times delivery was tried
+    attempts INTEGER NOT NULL DEFAULT 0,
+    -- next_attempt_at is when the delivery is due; NULL once it succeeded or died
+    next_attempt_at TIMESTAMP WITH TIME ZONE,
+    -- last_attempt_at is when delivery was last tried
+    last_attempt_at TIMESTAMP WITH TIME ZONE,
+    -- response_status is the HTTP status of the last attempt, 0 when there was none
+    response_status INTEGER,
+    -- response_body is the start of the body of the last response
+    response_body TEXT,
+    -- error is why the last attempt failed
+    error TEXT,
+    -- created_at is the timestamp when the delivery was queued
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    -- updated_at is the timestamp when the delivery was last updated
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_webhook_deliveries_site_id ON webhook_deliveries(site_id);
+CREATE INDEX idx_webhook_deliveries_webhook_id ON webhook_deliveries(webhook_id);
+CREATE INDEX idx_webhook_deliveries_event_id ON webhook_deliveries(event_id);
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries(status, next_attempt_at);
+
+-- Webhooks and deliveries are confined to app.site_id like the rest of a site's data
+ALTER TABLE webhooks ENABLE ROW LEVEL SECURITY;
+ALTER TABLE webhooks FORCE ROW LEVEL SECURITY;
+CREATE POLICY site_isolation ON webhooks USING (cms_current_site() IS NULL OR site_id = cms_current_site());
+ALTER TABLE webhook_deliveries ENABLE ROW LEVEL SECURITY;
+ALTER TABLE webhook_deliveries FORCE ROW LEVEL SECURITY;
+CREATE POLICY site_isolation ON webhook_deliveries USING (cms_current_site() IS NULL OR site_id = cms_current_site());
@@ -0,0 +1,4 @@
+-- This migration drops the transactional outbox
+
+DROP TABLE IF EXISTS outbox_cursors;
+DROP TABLE IF EXISTS outbox;
@@ -0,0 +1,38 @@
+-- This migration creates the transactional outbox of content events and the relay's per-sink cursors
+
+CREATE TABLE outbox (
+    -- id is the primary key for the table; events are published in id order
+    id SERIAL PRIMARY KEY,
+    -- site_id is the site the event happened on
+    site_id INTEGER NOT NULL DEFAULT 1 REFERENCES sites(id),
+    -- event_id is the public ID of the event, sent to every sink
+    event_id VARCHAR(64) NOT NULL,
+    -- type is the event type, such as post.published
+    type VARCHAR(50) NOT NULL,
+    -- resource_type is page, post or media
+    resource_type VARCHAR(20) NOT NULL,
+    -- resource_id is the ID of the changed resource
+    resource_id INTEGER NOT NULL,
+    -- data is the resource after the change, or before it for deletes
+    data JSONB,
+    -- created_at is the timestamp of the change
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_outbox_event_id ON outbox(event_id);
+CREATE INDEX idx_outbox_site_id ON outbox(site_id);
+CREATE INDEX idx_outbox_created_at ON outbox(created_at);
+
+CREATE TABLE outbox_cursors (
+    -- sink is the name of the sink, such as webhooks or nats
+    sink VARCHAR(50) PRIMARY KEY,
+    -- last_event_id is the outbox id of the last event the sink accepted
+    last_event_id INTEGER NOT NULL DEFAULT 0,
+    -- updated_at is the timestamp when the cursor last moved
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Events are confined to app.site_id like the rest of a site's data; the relay runs without it
+ALTER TABLE outbox ENABLE ROW LEVEL SECURITY;
+ALTER TABLE outbox FORCE ROW LEVEL SECURITY;
+CREATE POLICY site_isolation ON outbox USING (cms_current_site() IS NULL OR site_id = cms_current_site());
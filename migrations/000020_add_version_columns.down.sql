@@ -0,0 +1,5 @@
+-- This migration removes the version of pages, posts and media
+
+ALTER TABLE media DROP COLUMN IF EXISTS version;
+ALTER TABLE posts DROP COLUMN IF EXISTS version;
+ALTER TABLE pages DROP COLUMN IF EXISTS version;
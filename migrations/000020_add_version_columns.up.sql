@@ -0,0 +1,6 @@
+-- This migration adds the version that optimistic concurrency checks writes against to pages, posts and media
+
+-- version starts at 1 and goes up by one with every update
+ALTER TABLE pages ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE posts ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE media ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
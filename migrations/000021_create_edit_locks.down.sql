@@ -0,0 +1,3 @@
+-- This migration drops the edit locks
+
+DROP TABLE IF EXISTS edit_locks;
@@ -0,0 +1,30 @@
+-- This migration creates the edit locks editors hold on pages and posts while they work on them
+
+CREATE TABLE edit_locks (
+    -- id is the primary key for the table
+    id SERIAL PRIMARY KEY,
+    -- site_id is the site the locked page or post belongs to
+    site_id INTEGER NOT NULL DEFAULT 1 REFERENCES sites(id),
+    -- resource_type is page or post
+    resource_type VARCHAR(20) NOT NULL,
+    -- resource_id is the ID of the locked page or post
+    resource_id INTEGER NOT NULL,
+    -- user_id is the user holding the lock
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- user_name is the holder's name, shown to other editors
+    user_name VARCHAR(255) NOT NULL,
+    -- expires_at is when the lock lapses unless the holder renews it
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    -- created_at is the timestamp when the lock was first taken
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    -- updated_at is the timestamp when the lock was last taken or renewed
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_edit_locks_resource ON edit_locks(resource_type, resource_id);
+CREATE INDEX idx_edit_locks_site_id ON edit_locks(site_id);
+
+-- Locks are confined to app.site_id like the rest of a site's data
+ALTER TABLE edit_locks ENABLE ROW LEVEL SECURITY;
+ALTER TABLE edit_locks FORCE ROW LEVEL SECURITY;
+CREATE POLICY site_isolation ON edit_locks USING (cms_current_site() IS NULL OR site_id = cms_current_site());
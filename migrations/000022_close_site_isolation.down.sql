@@ -0,0 +1,26 @@
+-- This migration lets connections without app.site_id see every site again
+
+DROP POLICY IF EXISTS site_isolation ON api_keys;
+CREATE POLICY site_isolation ON api_keys USING (cms_current_site() IS NULL OR site_id = cms_current_site());
+DROP POLICY IF EXISTS site_isolation ON audit_logs;
+CREATE POLICY site_isolation ON audit_logs USING (cms_current_site() IS NULL OR site_id = cms_current_site());
+DROP POLICY IF EXISTS site_isolation ON content_entries;
+CREATE POLICY site_isolation ON content_entries USING (cms_current_site() IS NULL OR site_id = cms_current_site());
+DROP POLICY IF EXISTS site_isolation ON content_types;
+CREATE POLICY site_isolation ON content_types USING (cms_current_site() IS NULL OR site_id = cms_current_site());
+DROP POLICY IF EXISTS site_isolation ON edit_locks;
+CREATE POLICY site_isolation ON edit_locks USING (cms_current_site() IS NULL OR site_id = cms_current_site());
+DROP POLICY IF EXISTS site_isolation ON media;
+CREATE POLICY site_isolation ON media USING (cms_current_site() IS NULL OR site_id = cms_current_site());
+DROP POLICY IF EXISTS site_isolation ON outbox;
+CREATE POLICY site_isolation ON outbox USING (cms_current_site() IS NULL OR site_id = cms_current_site());
+DROP POLICY IF EXISTS site_isolation ON pages;
+CREATE POLICY site_isolation ON pages USING (cms_current_site() IS NULL OR site_id = cms_current_site());
+DROP POLICY IF EXISTS site_isolation ON posts;
+CREATE POLICY site_isolation ON posts USING (cms_current_site() IS NULL OR site_id = cms_current_site());
+DROP POLICY IF EXISTS site_isolation ON translations;
+CREATE POLICY site_isolation ON translations USING (cms_current_site() IS NULL OR site_id = cms_current_site());
+DROP POLICY IF EXISTS site_isolation ON webhook_deliveries;
+CREATE POLICY site_isolation ON webhook_deliveries USING (cms_current_site() IS NULL OR site_id = cms_current_site());
+DROP POLICY IF EXISTS site_isolation ON webhooks;
+CREATE POLICY site_isolation ON webhooks USING (cms_current_site() IS NULL OR site_id = cms_current_site());
@@ -0,0 +1,27 @@
+-- This migration confines every site-owned table to app.site_id, hiding all rows from connections without it
+-- Work spanning every site connects as a role with BYPASSRLS (DB_SYSTEM_USER)
+
+DROP POLICY IF EXISTS site_isolation ON api_keys;
+CREATE POLICY site_isolation ON api_keys USING (site_id = cms_current_site());
+DROP POLICY IF EXISTS site_isolation ON audit_logs;
+CREATE POLICY site_isolation ON audit_logs USING (site_id = cms_current_site());
+DROP POLICY IF EXISTS site_isolation ON content_entries;
+CREATE POLICY site_isolation ON content_entries USING (site_id = cms_current_site());
+DROP POLICY IF EXISTS site_isolation ON content_types;
+CREATE POLICY site_isolation ON content_types USING (site_id = cms_current_site());
+DROP POLICY IF EXISTS site_isolation ON edit_locks;
+CREATE POLICY site_isolation ON edit_locks USING (site_id = cms_current_site());
+DROP POLICY IF EXISTS site_isolation ON media;
+CREATE POLICY site_isolation ON media USING (site_id = cms_current_site());
+DROP POLICY IF EXISTS site_isolation ON outbox;
+CREATE POLICY site_isolation ON outbox USING (site_id = cms_current_site());
+DROP POLICY IF EXISTS site_isolation ON pages;
+CREATE POLICY site_isolation ON pages USING (site_id = cms_current_site());
+DROP POLICY IF EXISTS site_isolation ON posts;
+CREATE POLICY site_isolation ON posts USING (site_id = cms_current_site());
+DROP POLICY IF EXISTS site_isolation ON translations;
+CREATE POLICY site_isolation ON translations USING (site_id = cms_current_site());
+DROP POLICY IF EXISTS site_isolation ON webhook_deliveries;
+CREATE POLICY site_isolation ON webhook_deliveries USING (site_id = cms_current_site());
+DROP POLICY IF EXISTS site_isolation ON webhooks;
+CREATE POLICY site_isolation ON webhooks USING (site_id = cms_current_site());
@@ -0,0 +1,5 @@
+-- This migration drops the transaction order of the outbox
+
+ALTER TABLE outbox_cursors DROP COLUMN IF EXISTS last_txid;
+DROP INDEX IF EXISTS idx_outbox_position;
+ALTER TABLE outbox DROP COLUMN IF EXISTS txid;
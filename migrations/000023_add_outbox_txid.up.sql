@@ -0,0 +1,10 @@
+-- This migration orders the outbox by the transaction that wrote each event, so writers need no lock for the relay to read events in order
+
+-- Existing events keep their id order ahead of every new event
+ALTER TABLE outbox ADD COLUMN txid BIGINT NOT NULL DEFAULT 0;
+-- txid is the transaction that wrote the event; the relay reads events of finished transactions in (txid, id) order
+ALTER TABLE outbox ALTER COLUMN txid SET DEFAULT pg_current_xact_id()::text::bigint;
+CREATE INDEX idx_outbox_position ON outbox(txid, id);
+
+-- last_txid is the txid of the last event the sink accepted
+ALTER TABLE outbox_cursors ADD COLUMN last_txid BIGINT NOT NULL DEFAULT 0;
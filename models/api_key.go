@@ -0,0 +1,124 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"time"
+)
+
+// APIKeyPrefix starts every API key, so a bearer credential can be told
+// apart from an access JWT.
+const APIKeyPrefix = "cms_"
+
+// APIKey is a long-lived credential for machine clients. It acts as the
+// user who created it, limited to its scopes. Only the SHA-256 hash of the
+// key is stored; Prefix identifies it in listings and logs.
+type APIKey struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	SiteID     uint       `gorm:"not null;default:1;index" json:"site_id"`
+	UserID     uint       `gorm:"not null;index" json:"user_id"`
+	Name       string     `gorm:"size:100;not null" json:"name"`
+	Prefix     string     `gorm:"size:16;not null;uniqueIndex" json:"prefix"`
+	KeyHash    string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	Scopes     Scopes     `gorm:"type:text;not null" json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// APIKeyWithSecret is returned once, when the key is created.
+type APIKeyWithSecret struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// Read scopes gate the public GET endpoints for API key requests.
+const (
+	ScopePagesRead   = "pages:read"
+	ScopePostsRead   = "posts:read"
+	ScopeMediaRead   = "media:read"
+	ScopeContentRead = "content:read"
+)
+
+// APIKeyScopes lists the scopes a key can hold and the permissions each one
+// covers. A key never holds more than its user's role grants, and keys
+// cannot manage users.
+var APIKeyScopes = map[string][]Permission{
+	ScopePagesRead:         {},
+	"pages:write":          {PermWritePages},
+	ScopePostsRead:         {PermViewDrafts},
+	"posts:write":          {PermWritePosts, PermWriteOwnPosts, PermPublishPosts},
+	ScopeMediaRead:         {},
+	"media:write":          {PermWriteMedia},
+	"media:delete":         {PermDeleteMedia},
+	"translations:write":   {PermWriteTranslations},
+	ScopeContentRead:       {},
+	"content:write":        {PermWriteContent},
+	"content-types:manage": {PermManageContentTypes},
+}
+
+// CanGrantScope reports whether the user may put the scope on a key: read
+// scopes are open to everyone, other scopes need a permission they cover.
+func (u User) CanGrantScope(scope string) bool {
+	permissions, ok := APIKeyScopes[scope]
+	if !ok {
+		return false
+	}
+	if strings.HasSuffix(scope, ":read") {
+		return true
+	}
+	for _, permission := range permissions {
+		if u.Can(permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScope reports whether the user may use the scope: always for password
+// and token logins, and for API keys only when the key holds it.
+func (u User) HasScope(scope string) bool {
+	if u.Scopes == nil {
+		return true
+	}
+	for _, s := range u.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// scopesCover reports whether one of the scopes covers the permission.
+func scopesCover(scopes []string, permission Permission) bool {
+	for _, scope := range scopes {
+		for _, p := range APIKeyScopes[scope] {
+			if p == permission {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Scopes is a list of scopes stored space separated in a text column.
+type Scopes []string
+
+func (s Scopes) Value() (driver.Value, error) {
+	return strings.Join(s, " "), nil
+}
+
+func (s *Scopes) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*s = Scopes{}
+	case []byte:
+		*s = strings.Fields(string(v))
+	case string:
+		*s = strings.Fields(v)
+	default:
+		return errors.New("models: unsupported type for Scopes column")
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+const ResourceMedia = "media"
+
+const (
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+)
+
+// AuditLog records one content mutation. Rows are only ever inserted, in
+// the transaction of the change they describe; a trigger rejects updates
+// and deletes. The actor's email is copied so the entry outlives the user.
+type AuditLog struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	SiteID       uint      `gorm:"not null;default:1;index" json:"site_id"`
+	ActorID      *uint     `gorm:"index" json:"actor_id"`
+	ActorEmail   string    `gorm:"size:255" json:"actor_email"`
+	Action       string    `gorm:"size:20;not null;index" json:"action"`
+	ResourceType string    `gorm:"size:20;not null;index:idx_audit_logs_resource" json:"resource_type"`
+	ResourceID   uint      `gorm:"not null;index:idx_audit_logs_resource" json:"resource_id"`
+	Before       JSON      `gorm:"type:jsonb" json:"before"`
+	After        JSON      `gorm:"type:jsonb" json:"after"`
+	IP           string    `gorm:"size:45" json:"ip"`
+	UserAgent    string    `gorm:"type:text" json:"user_agent"`
+	RequestID    string    `gorm:"size:64;index" json:"request_id"`
+	CreatedAt    time.Time `gorm:"index" json:"created_at"`
+}
+
+// AuditLogResponse is a page of audit entries.
+type AuditLogResponse struct {
+	Entries []AuditLog `json:"entries"`
+	Total   int64      `json:"total"`
+	Page    int        `json:"page"`
+	PerPage int        `json:"per_page"`
+}
@@ -0,0 +1,137 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	BlockParagraph = "paragraph"
+	BlockHeading   = "heading"
+	BlockImage     = "image"
+	BlockQuote     = "quote"
+	BlockEmbed     = "embed"
+	BlockCode      = "code"
+	BlockList      = "list"
+)
+
+// Block is one element of a structured content document. Which properties
+// are meaningful depends on Type; ParseBlocks rejects anything else.
+type Block struct {
+	Type     string   `json:"type"`
+	Text     string   `json:"text,omitempty"`
+	Level    int      `json:"level,omitempty"`
+	MediaID  uint     `json:"media_id,omitempty"`
+	Alt      string   `json:"alt,omitempty"`
+	Caption  string   `json:"caption,omitempty"`
+	Cite     string   `json:"cite,omitempty"`
+	URL      string   `json:"url,omitempty"`
+	Language string   `json:"language,omitempty"`
+	Code     string   `json:"code,omitempty"`
+	Ordered  bool     `json:"ordered,omitempty"`
+	Items    []string `json:"items,omitempty"`
+}
+
+// ParseBlocks decodes and validates a block document.
+func ParseBlocks(doc JSON) ([]Block, error) {
+	decoder := json.NewDecoder(bytes.NewReader(doc))
+	decoder.DisallowUnknownFields()
+
+	var blocks []Block
+	if err := decoder.Decode(&blocks); err != nil {
+		return nil, fmt.Errorf("blocks must be an array of blocks: %v", err)
+	}
+	for i, block := range blocks {
+		if err := block.validate(); err != nil {
+			return nil, fmt.Errorf("block %d: %v", i, err)
+		}
+	}
+	return blocks, nil
+}
+
+func (b Block) validate() error {
+	switch b.Type {
+	case BlockParagraph:
+		if b.Text == "" {
+			return fmt.Errorf("paragraph requires text")
+		}
+	case BlockHeading:
+		if b.Text == "" {
+			return fmt.Errorf("heading requires text")
+		}
+		if b.Level < 1 || b.Level > 6 {
+			return fmt.Errorf("heading level must be between 1 and 6")
+		}
+	case BlockImage:
+		if b.MediaID == 0 {
+			return fmt.Errorf("image requires media_id")
+		}
+	case BlockQuote:
+		if b.Text == "" {
+			return fmt.Errorf("quote requires text")
+		}
+	case BlockEmbed:
+		u, err := url.Parse(b.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("embed requires an http(s) url")
+		}
+	case BlockCode:
+		if b.Code == "" {
+			return fmt.Errorf("code requires code")
+		}
+	case BlockList:
+		if len(b.Items) == 0 {
+			return fmt.Errorf("list requires items")
+		}
+	default:
+		return fmt.Errorf("unknown block type %q", b.Type)
+	}
+	return nil
+}
+
+// MediaIDs returns the distinct media referenced by image blocks.
+func MediaIDs(blocks []Block) []uint {
+	seen := make(map[uint]bool)
+	var ids []uint
+	for _, b := range blocks {
+		if b.Type == BlockImage && !seen[b.MediaID] {
+			seen[b.MediaID] = true
+			ids = append(ids, b.MediaID)
+		}
+	}
+	return ids
+}
+
+// PlainText projects a block document to the text stored in Content so
+// search keeps working on structured posts and pages.
+func PlainText(blocks []Block) string {
+	var parts []string
+	for _, b := range blocks {
+		switch b.Type {
+		case BlockParagraph, BlockHeading:
+			parts = append(parts, b.Text)
+		case BlockQuote:
+			if b.Cite != "" {
+				parts = append(parts, b.Text+" — "+b.Cite)
+			} else {
+				parts = append(parts, b.Text)
+			}
+		case BlockImage:
+			if text := strings.TrimSpace(b.Alt + " " + b.Caption); text != "" {
+				parts = append(parts, text)
+			}
+		case BlockEmbed:
+			if b.Caption != "" {
+				parts = append(parts, b.Caption)
+			}
+		case BlockCode:
+			parts = append(parts, b.Code)
+		case BlockList:
+			parts = append(parts, strings.Join(b.Items, "\n"))
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
@@ -0,0 +1,50 @@
+package models
+
+import "encoding/json"
+
+// Bulk request modes. An atomic request applies every operation in one
+// transaction or none of them; a best-effort request applies each on its
+// own and reports how each went.
+const (
+	BulkAtomic     = "atomic"
+	BulkBestEffort = "best_effort"
+)
+
+// Bulk operations, each run as the matching single-item request would be.
+const (
+	BulkCreate = "create"
+	BulkUpdate = "update"
+	BulkPatch  = "patch"
+	BulkDelete = "delete"
+)
+
+type BulkRequest struct {
+	Mode       string          `json:"mode"`
+	Operations []BulkOperation `json:"operations"`
+}
+
+// BulkOperation is one create, update, patch or delete. Data is the body of
+// the matching single-item request and Version its If-Match precondition.
+type BulkOperation struct {
+	Op      string          `json:"op"`
+	ID      uint            `json:"id,omitempty"`
+	Version uint            `json:"version,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// BulkResult is how one operation went: the status and body, or error
+// message, the single-item request would have answered with.
+type BulkResult struct {
+	Index  int             `json:"index"`
+	Op     string          `json:"op"`
+	Status int             `json:"status"`
+	Data   json.RawMessage `json:"data,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+type BulkResponse struct {
+	Mode      string       `json:"mode"`
+	Succeeded int          `json:"succeeded"`
+	Failed    int          `json:"failed"`
+	Results   []BulkResult `json:"results"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+type ContentType struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	SiteID      uint      `gorm:"not null;default:1;uniqueIndex:idx_content_types_site_slug" json:"site_id"`
+	Slug        string    `gorm:"size:100;not null;uniqueIndex:idx_content_types_site_slug" json:"slug" binding:"required"`
+	Name        string    `gorm:"size:255;not null" json:"name" binding:"required"`
+	Description string    `gorm:"type:text" json:"description"`
+	Schema      JSON      `gorm:"type:jsonb;not null" json:"schema" binding:"required"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type ContentEntry struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	SiteID        uint      `gorm:"not null;default:1;index" json:"site_id"`
+	ContentTypeID uint      `gorm:"not null;index" json:"content_type_id"`
+	Data          JSON      `gorm:"type:jsonb;not null" json:"data"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+const (
+	RoleAuthor       = "author"
+	RoleEditor       = "editor"
+	RolePhotographer = "photographer"
+)
+
+var ContributorRoles = []string{RoleAuthor, RoleEditor, RolePhotographer}
+
+type PostContributor struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	PostID    uint      `gorm:"not null;index" json:"post_id"`
+	Name      string    `gorm:"size:100;not null" json:"name" binding:"required"`
+	Role      string    `gorm:"size:50;not null" json:"role" binding:"required"`
+	Position  int       `gorm:"not null;default:0" json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func IsValidContributorRole(role string) bool {
+	for _, r := range ContributorRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// PrimaryAuthor returns the name of the first contributor credited as an
+// author, which is what the legacy Author column mirrors.
+func PrimaryAuthor(contributors []PostContributor) string {
+	for _, c := range contributors {
+		if c.Role == RoleAuthor {
+			return c.Name
+		}
+	}
+	return ""
+}
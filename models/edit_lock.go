@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// EditLock is an editor's lease on a page or post, so others can see who is
+// editing it and cannot overwrite their work. The lease lapses at ExpiresAt
+// unless the holder renews it; the holder's name is copied so reads can show
+// it without looking up the user.
+type EditLock struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	SiteID       uint      `gorm:"not null;default:1;index" json:"site_id"`
+	ResourceType string    `gorm:"size:20;not null;uniqueIndex:idx_edit_locks_resource" json:"resource_type"`
+	ResourceID   uint      `gorm:"not null;uniqueIndex:idx_edit_locks_resource" json:"resource_id"`
+	UserID       uint      `gorm:"not null" json:"user_id"`
+	UserName     string    `gorm:"size:255;not null" json:"user_name"`
+	ExpiresAt    time.Time `gorm:"not null" json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Active reports whether the lease is still held at now.
+func (l EditLock) Active(now time.Time) bool {
+	return now.Before(l.ExpiresAt)
+}
@@ -4,8 +4,10 @@ import "time"
 
 type Media struct {
     ID        uint      `gorm:"primaryKey" json:"id"`
+    SiteID    uint      `gorm:"not null;default:1;index" json:"site_id"`
     URL       string    `gorm:"size:255;not null" json:"url" binding:"required"`
     Type      string    `gorm:"size:50" json:"type" binding:"required"`
     CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
     UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+    Version   uint      `gorm:"not null;default:1" json:"version"`
 }
\ No newline at end of file
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// OutboxEvent is a content event written in the same transaction as the
+// change it describes, so an event is never lost nor sent for a change that
+// was rolled back. The relay publishes events to every sink in the order of
+// the transaction that wrote them, then of ID.
+type OutboxEvent struct {
+	ID           uint      `gorm:"primaryKey;index:idx_outbox_position,priority:2" json:"id"`
+	TxID         int64     `gorm:"column:txid;->;not null;default:pg_current_xact_id()::text::bigint;index:idx_outbox_position,priority:1" json:"-"`
+	SiteID       uint      `gorm:"not null;default:1;index" json:"site_id"`
+	EventID      string    `gorm:"size:64;not null;uniqueIndex" json:"event_id"`
+	Type         string    `gorm:"size:50;not null" json:"type"`
+	ResourceType string    `gorm:"size:20;not null" json:"resource_type"`
+	ResourceID   uint      `gorm:"not null" json:"resource_id"`
+	Data         JSON      `gorm:"type:jsonb" json:"data"`
+	CreatedAt    time.Time `gorm:"index" json:"created_at"`
+}
+
+func (OutboxEvent) TableName() string {
+	return "outbox"
+}
+
+// Event is the event as published to sinks.
+func (e OutboxEvent) Event() Event {
+	return Event{
+		ID:           e.EventID,
+		Type:         e.Type,
+		SiteID:       e.SiteID,
+		ResourceType: e.ResourceType,
+		ResourceID:   e.ResourceID,
+		OccurredAt:   e.CreatedAt.UTC(),
+		Data:         e.Data,
+	}
+}
+
+// OutboxCursor is how far the relay has published the outbox to a sink.
+type OutboxCursor struct {
+	Sink        string    `gorm:"primaryKey;size:50" json:"sink"`
+	LastTxID    int64     `gorm:"column:last_txid;not null;default:0" json:"last_txid"`
+	LastEventID uint      `gorm:"not null;default:0" json:"last_event_id"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
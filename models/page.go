@@ -4,8 +4,16 @@ import "time"
 
 type Page struct {
     ID uint    `gorm:"primaryKey" json:"id"`
+    SiteID uint `gorm:"not null;default:1;index" json:"site_id"`
     Title string `gorm:"size:255;not null" json:"title" binding:"required"`
-    Content string `gorm:"type:text;not null" json:"content" binding:"required"`
+    Content string `gorm:"type:text;not null" json:"content"`
     CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
     UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+    Fields JSON `gorm:"type:jsonb;index:idx_pages_fields,type:gin" json:"fields,omitempty"`
+    Blocks JSON `gorm:"type:jsonb" json:"blocks,omitempty"`
+    ContentFormat string `gorm:"size:20;not null;default:plain" json:"content_format"`
+    ContentHTML string `gorm:"type:text" json:"content_html,omitempty"`
+    Version uint `gorm:"not null;default:1" json:"version"`
+    Locale string `gorm:"-" json:"locale,omitempty"`
+    Lock *EditLock `gorm:"-" json:"lock,omitempty"`
 }
@@ -1,13 +1,50 @@
 package models
 
-import "time"
+import (
+    "time"
+
+    "gorm.io/gorm"
+)
+
+const (
+    PostStatusDraft     = "draft"
+    PostStatusPublished = "published"
+)
 
 type Post struct {
-    ID        uint      `gorm:"primaryKey" json:"id"`
-    Title     string    `gorm:"size:255;not null" json:"title" binding:"required"`
-    Content   string    `gorm:"type:text;not null" json:"content" binding:"required"`
-    Author    string    `gorm:"size:100" json:"author"`
-    CreatedAt time.Time `json:"created_at"`
-    UpdatedAt time.Time `json:"updated_at"`
-    Media     []Media   `gorm:"many2many:post_media" json:"media"`
+    ID            uint              `gorm:"primaryKey" json:"id"`
+    SiteID        uint              `gorm:"not null;default:1;index" json:"site_id"`
+    Title         string            `gorm:"size:255;not null" json:"title" binding:"required"`
+    Content       string            `gorm:"type:text;not null" json:"content"`
+    Author        string            `gorm:"size:100" json:"author"`
+    CreatedAt     time.Time         `json:"created_at"`
+    UpdatedAt     time.Time         `json:"updated_at"`
+    Media         []Media           `gorm:"many2many:post_media" json:"media"`
+    Contributors  []PostContributor `gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE" json:"contributors"`
+    Fields        JSON              `gorm:"type:jsonb;index:idx_posts_fields,type:gin" json:"fields,omitempty"`
+    Blocks        JSON              `gorm:"type:jsonb" json:"blocks,omitempty"`
+    ContentFormat string            `gorm:"size:20;not null;default:plain" json:"content_format"`
+    ContentHTML   string            `gorm:"type:text" json:"content_html,omitempty"`
+    Status        string            `gorm:"size:20;not null;default:published;index" json:"status"`
+    OwnerID       *uint             `gorm:"index" json:"owner_id"`
+    Version       uint              `gorm:"not null;default:1" json:"version"`
+    Locale        string            `gorm:"-" json:"locale,omitempty"`
+    Lock          *EditLock         `gorm:"-" json:"lock,omitempty"`
+}
+
+// BeforeCreate keeps Author and Contributors in sync: a post created with
+// only an author gets a single author credit, otherwise Author is derived
+// from the ordered contributor list.
+func (p *Post) BeforeCreate(tx *gorm.DB) error {
+    if len(p.Contributors) == 0 {
+        if p.Author != "" {
+            p.Contributors = []PostContributor{{Name: p.Author, Role: RoleAuthor}}
+        }
+        return nil
+    }
+    for i := range p.Contributors {
+        p.Contributors[i].Position = i
+    }
+    p.Author = PrimaryAuthor(p.Contributors)
+    return nil
 }
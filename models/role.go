@@ -0,0 +1,74 @@
+package models
+
+// User roles, from most to least privileged.
+const (
+	UserRoleAdmin  = "admin"
+	UserRoleEditor = "editor"
+	UserRoleAuthor = "author"
+	UserRoleViewer = "viewer"
+)
+
+var UserRoles = []string{UserRoleAdmin, UserRoleEditor, UserRoleAuthor, UserRoleViewer}
+
+type Permission string
+
+const (
+	PermWritePages         Permission = "pages:write"
+	PermWritePosts         Permission = "posts:write"
+	PermWriteOwnPosts      Permission = "posts:write:own"
+	PermPublishPosts       Permission = "posts:publish"
+	PermViewDrafts         Permission = "posts:drafts"
+	PermWriteMedia         Permission = "media:write"
+	PermDeleteMedia        Permission = "media:delete"
+	PermWriteTranslations  Permission = "translations:write"
+	PermWriteContent       Permission = "content:write"
+	PermManageContentTypes Permission = "content-types:manage"
+	PermManageUsers        Permission = "users:manage"
+	PermViewAudit          Permission = "audit:view"
+	PermManageSites        Permission = "sites:manage"
+	PermManageWebhooks     Permission = "webhooks:manage"
+	PermBreakLocks         Permission = "locks:break"
+)
+
+// RolePermissions is the permission model: what each role may do. Authors
+// may only write posts they own and cannot publish them.
+var RolePermissions = map[string][]Permission{
+	UserRoleAdmin: {
+		PermWritePages, PermWritePosts, PermWriteOwnPosts, PermPublishPosts, PermViewDrafts,
+		PermWriteMedia, PermDeleteMedia, PermWriteTranslations, PermWriteContent,
+		PermManageContentTypes, PermManageUsers, PermViewAudit, PermManageSites,
+		PermManageWebhooks, PermBreakLocks,
+	},
+	UserRoleEditor: {
+		PermWritePages, PermWritePosts, PermWriteOwnPosts, PermPublishPosts, PermViewDrafts,
+		PermWriteMedia, PermWriteTranslations, PermWriteContent,
+	},
+	UserRoleAuthor: {
+		PermWriteOwnPosts, PermViewDrafts, PermWriteMedia,
+	},
+	UserRoleViewer: {},
+}
+
+type RoleInfo struct {
+	Role        string       `json:"role"`
+	Permissions []Permission `json:"permissions"`
+}
+
+func IsValidUserRole(role string) bool {
+	_, ok := RolePermissions[role]
+	return ok
+}
+
+// Can reports whether the user's role grants the permission and, for API
+// key requests, one of the key's scopes covers it.
+func (u User) Can(permission Permission) bool {
+	if u.Scopes != nil && !scopesCover(u.Scopes, permission) {
+		return false
+	}
+	for _, p := range RolePermissions[u.Role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+type SearchResult struct {
+	Type      string    `json:"type"`
+	ID        uint      `json:"id"`
+	Title     string    `json:"title"`
+	Snippet   string    `json:"snippet"`
+	Rank      float64   `json:"rank"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type FacetBucket struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+type SearchResponse struct {
+	Query      string                   `json:"query"`
+	Results    []SearchResult           `json:"results"`
+	Total      int64                    `json:"total"`
+	Page       int                      `json:"page"`
+	PerPage    int                      `json:"per_page"`
+	Facets     map[string][]FacetBucket `json:"facets"`
+	DidYouMean string                   `json:"did_you_mean,omitempty"`
+}
+
+type SearchSuggestion struct {
+	Type  string  `json:"type"`
+	ID    *uint   `json:"id,omitempty"`
+	Text  string  `json:"text"`
+	Score float64 `json:"score"`
+}
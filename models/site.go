@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// DefaultSiteID is the site created with the schema. It serves requests
+// that name no site and owns everything written before multi-tenancy.
+const DefaultSiteID = 1
+
+const ResourceSite = "site"
+
+// Site is a tenant: an isolated brand site sharing the deployment. Requests
+// reach a site through its Domain, the /sites/:slug path prefix or an API
+// key bound to it.
+type Site struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Slug      string    `gorm:"size:50;not null;uniqueIndex" json:"slug" binding:"required"`
+	Name      string    `gorm:"size:100;not null" json:"name" binding:"required"`
+	Domain    *string   `gorm:"size:255;uniqueIndex" json:"domain"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+const (
+	ResourcePage        = "page"
+	ResourcePost        = "post"
+	ResourceTranslation = "translation"
+)
+
+type Translation struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	SiteID          uint      `gorm:"not null;default:1;index" json:"site_id"`
+	ResourceType    string    `gorm:"size:20;not null;uniqueIndex:idx_translations_resource_locale" json:"resource_type"`
+	ResourceID      uint      `gorm:"not null;uniqueIndex:idx_translations_resource_locale" json:"resource_id"`
+	Locale          string    `gorm:"size:20;not null;uniqueIndex:idx_translations_resource_locale" json:"locale"`
+	Title           string    `gorm:"size:255;not null" json:"title" binding:"required"`
+	Content         string    `gorm:"type:text;not null" json:"content" binding:"required"`
+	SourceUpdatedAt time.Time `gorm:"not null" json:"source_updated_at"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// Outdated reports whether the source item changed after this translation
+// was last written.
+func (t Translation) Outdated(sourceUpdatedAt time.Time) bool {
+	return t.SourceUpdatedAt.Before(sourceUpdatedAt)
+}
+
+type TranslationStatus struct {
+	ResourceType    string     `json:"resource_type"`
+	ResourceID      uint       `json:"resource_id"`
+	Title           string     `json:"title"`
+	Locale          string     `json:"locale"`
+	Status          string     `json:"status"`
+	SourceUpdatedAt time.Time  `json:"source_updated_at"`
+	TranslatedAt    *time.Time `json:"translated_at,omitempty"`
+}
@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+// User is a CMS account. OIDCSubject links users who sign in through
+// single sign-on to their identity provider account.
+type User struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	Email        string    `gorm:"size:255;not null;uniqueIndex" json:"email"`
+	Name         string    `gorm:"size:100" json:"name"`
+	PasswordHash string    `gorm:"size:255;not null" json:"-"`
+	Role         string    `gorm:"size:20;not null;default:viewer" json:"role"`
+	OIDCSubject  *string   `gorm:"column:oidc_subject;size:255;uniqueIndex" json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+
+	// Scopes limits the user to an API key's scopes. It is nil for password
+	// and token logins.
+	Scopes []string `gorm:"-" json:"-"`
+}
+
+// RefreshToken is a single-use refresh token. Only its SHA-256 hash is
+// stored; each refresh revokes it and issues a successor in the same
+// family, so presenting a revoked token reveals reuse and revokes the family.
+type RefreshToken struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	TokenHash string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	FamilyID  string     `gorm:"size:64;not null;index" json:"family_id"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// RevokedToken denies an access token, by its jti, until it would have
+// expired anyway.
+type RevokedToken struct {
+	JTI       string    `gorm:"primaryKey;size:64" json:"jti"`
+	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`
+}
+
+// OIDCState is a pending single sign-on login, keyed by the hash of the
+// state parameter. It is consumed by the callback.
+type OIDCState struct {
+	StateHash    string    `gorm:"primaryKey;size:64" json:"-"`
+	Nonce        string    `gorm:"size:64;not null" json:"-"`
+	CodeVerifier string    `gorm:"size:128;not null" json:"-"`
+	ExpiresAt    time.Time `gorm:"not null;index" json:"expires_at"`
+}
+
+func (OIDCState) TableName() string {
+	return "oidc_states"
+}
+
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+}
@@ -0,0 +1,137 @@
+package models
+
+import (
+	"database/sql/driver"
+	"strings"
+	"time"
+)
+
+// Content events, named <resource>.<action>. Webhooks subscribe to them.
+const (
+	EventPageCreated   = "page.created"
+	EventPageUpdated   = "page.updated"
+	EventPageDeleted   = "page.deleted"
+	EventPostCreated   = "post.created"
+	EventPostUpdated   = "post.updated"
+	EventPostPublished = "post.published"
+	EventPostDeleted   = "post.deleted"
+	EventMediaCreated  = "media.created"
+	EventMediaUpdated  = "media.updated"
+	EventMediaDeleted  = "media.deleted"
+
+	EventTranslationCreated = "translation.created"
+	EventTranslationUpdated = "translation.updated"
+	EventTranslationDeleted = "translation.deleted"
+)
+
+var EventTypes = []string{
+	EventPageCreated, EventPageUpdated, EventPageDeleted,
+	EventPostCreated, EventPostUpdated, EventPostPublished, EventPostDeleted,
+	EventMediaCreated, EventMediaUpdated, EventMediaDeleted,
+	EventTranslationCreated, EventTranslationUpdated, EventTranslationDeleted,
+}
+
+// Event is a content change as delivered to subscribers. Data is the
+// resource after the change, or before it for deletes.
+type Event struct {
+	ID           string    `json:"id"`
+	Type         string    `json:"type"`
+	SiteID       uint      `json:"site_id"`
+	ResourceType string    `json:"resource_type"`
+	ResourceID   uint      `json:"resource_id"`
+	OccurredAt   time.Time `json:"occurred_at"`
+	Data         JSON      `json:"data"`
+}
+
+// Webhook delivery states. Failed deliveries are retried with backoff until
+// they run out of attempts and become dead.
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+	DeliveryDead      = "dead"
+)
+
+// Webhook subscribes a URL to content events. Deliveries are signed with
+// Secret, which is only shown when the webhook is created.
+type Webhook struct {
+	ID        uint        `gorm:"primaryKey" json:"id"`
+	SiteID    uint        `gorm:"not null;default:1;index" json:"site_id"`
+	URL       string      `gorm:"size:2048;not null" json:"url"`
+	Secret    string      `gorm:"size:255;not null" json:"-"`
+	Events    EventFilter `gorm:"type:text;not null" json:"events"`
+	Active    bool        `gorm:"not null" json:"active"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// WebhookWithSecret is returned once, when the webhook is created.
+type WebhookWithSecret struct {
+	Webhook
+	Secret string `json:"secret"`
+}
+
+// WebhookDelivery is one attempt series at delivering an event to a
+// webhook; together they form the delivery log. A redelivery is a new row
+// for the same event.
+type WebhookDelivery struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	SiteID         uint       `gorm:"not null;default:1;index" json:"site_id"`
+	WebhookID      uint       `gorm:"not null;index" json:"webhook_id"`
+	EventID        string     `gorm:"size:64;not null;index" json:"event_id"`
+	Event          string     `gorm:"size:50;not null" json:"event"`
+	Payload        JSON       `gorm:"type:jsonb;not null" json:"payload"`
+	Status         string     `gorm:"size:20;not null;index:idx_webhook_deliveries_due" json:"status"`
+	Attempts       int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  *time.Time `gorm:"index:idx_webhook_deliveries_due" json:"next_attempt_at"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at"`
+	ResponseStatus int        `json:"response_status"`
+	ResponseBody   string     `gorm:"type:text" json:"response_body"`
+	Error          string     `gorm:"type:text" json:"error"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+type WebhookDeliveryResponse struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+	Total      int64             `json:"total"`
+	Page       int               `json:"page"`
+	PerPage    int               `json:"per_page"`
+}
+
+// EventFilter lists the events a webhook receives: event types, "*" for
+// every event or "<resource>.*" for every event of a resource.
+type EventFilter []string
+
+// Matches reports whether the filter selects the event type.
+func (f EventFilter) Matches(eventType string) bool {
+	resource, _, _ := strings.Cut(eventType, ".")
+	for _, pattern := range f {
+		if pattern == "*" || pattern == eventType || pattern == resource+".*" {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidEventPattern reports whether pattern may appear in an EventFilter.
+func IsValidEventPattern(pattern string) bool {
+	if pattern == "*" {
+		return true
+	}
+	for _, eventType := range EventTypes {
+		resource, _, _ := strings.Cut(eventType, ".")
+		if pattern == eventType || pattern == resource+".*" {
+			return true
+		}
+	}
+	return false
+}
+
+func (f EventFilter) Value() (driver.Value, error) {
+	return Scopes(f).Value()
+}
+
+func (f *EventFilter) Scan(value interface{}) error {
+	return (*Scopes)(f).Scan(value)
+}
@@ -2,33 +2,174 @@ package routes
 
 import (
 	"cms-backend/controllers"
+	"cms-backend/middleware"
+	"cms-backend/models"
+	"cms-backend/utils"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-func InitializeRoutes(router *gin.Engine, db *gorm.DB) {
+func InitializeRoutes(router *gin.Engine, db *gorm.DB, system *gorm.DB, search utils.SearchIndex, sso *utils.OIDCClient, webhooks *utils.WebhookDispatcher, outbox *utils.OutboxRelay, events *utils.EventNotifier, sites *utils.LocalCache, cache *utils.ResponseCache) {
+	router.Use(middleware.RequestID())
 	router.Use(func(c *gin.Context) {
 		c.Set("db", db)
+		c.Set("system_db", system)
+		if search != nil {
+			c.Set("search", search)
+		}
+		if sso != nil {
+			c.Set("oidc", sso)
+		}
+		if webhooks != nil {
+			c.Set("webhooks", webhooks)
+		}
+		if outbox != nil {
+			c.Set("outbox", outbox)
+		}
+		if events != nil {
+			c.Set("events", events)
+		}
+		if sites != nil {
+			c.Set("site_cache", sites)
+		}
+		if cache != nil {
+			c.Set("cache", cache)
+		}
 		c.Next()
 	})
 
-	api := router.Group("/api/v1")
+	// API keys are checked ahead of everything else in the group.
+	api := router.Group("/api/v1", middleware.AuthenticateAPIKey())
 
-	api.GET("/pages", controllers.GetPages)
-	api.GET("/pages/:id", controllers.GetPage)
-	api.POST("/pages", controllers.CreatePage)
-	api.PUT("/pages/:id", controllers.UpdatePage)
-	api.DELETE("/pages/:id", controllers.DeletePage)
+	api.POST("/auth/login", controllers.Login)
+	api.POST("/auth/refresh", controllers.Refresh)
+	api.GET("/auth/oidc/login", controllers.OIDCLogin)
+	api.GET("/auth/oidc/callback", controllers.OIDCCallback)
 
-	api.GET("/posts", controllers.GetPosts)
-	api.GET("/posts/:id", controllers.GetPost)
-	api.POST("/posts", controllers.CreatePost)
-	api.PUT("/posts/:id", controllers.UpdatePost)
-	api.DELETE("/posts/:id", controllers.DeletePost)
+	// Users, roles and sites are shared by the whole deployment; every
+	// route in this group requires a valid access token for writes, and
+	// works on the system connection.
+	admin := api.Group("", middleware.SystemDB(), middleware.Authenticate())
+	manageUsers := middleware.RequirePermission(models.PermManageUsers)
+	manageSites := middleware.RequirePermission(models.PermManageSites)
 
-	api.GET("/media", controllers.GetMedia)
-	api.GET("/media/:id", controllers.GetMediaByID)
-	api.POST("/media", controllers.CreateMedia)
-	api.DELETE("/media/:id", controllers.DeleteMedia)
+	admin.POST("/auth/logout", controllers.Logout)
+
+	admin.GET("/roles", controllers.GetRoles)
+	admin.GET("/users", manageUsers, controllers.GetUsers)
+	admin.POST("/users", manageUsers, controllers.CreateUser)
+	admin.PUT("/users/:id/role", manageUsers, controllers.UpdateUserRole)
+
+	admin.GET("/sites", manageSites, controllers.GetSites)
+	admin.GET("/sites/:id", manageSites, controllers.GetSite)
+	admin.POST("/sites", manageSites, controllers.CreateSite)
+	admin.PUT("/sites/:id", manageSites, controllers.UpdateSite)
+	admin.DELETE("/sites/:id", manageSites, controllers.DeleteSite)
+
+	// Content belongs to a site: under /api/v1 the one whose domain the
+	// request was sent to (or its API key's), under /sites/:site/api/v1 the
+	// one with that slug.
+	siteRoutes(api.Group("", middleware.ResolveSite(), middleware.Authenticate()))
+	siteRoutes(router.Group("/sites/:site/api/v1", middleware.AuthenticateAPIKey(), middleware.ResolveSite(), middleware.Authenticate()))
+
+	// Event streams stay open for as long as the client listens, so they
+	// don't hold a connection confined to the site.
+	api.GET("/events", middleware.ResolveSiteUnpinned(), middleware.Authenticate(), controllers.StreamEvents)
+	router.GET("/sites/:site/api/v1/events", middleware.AuthenticateAPIKey(), middleware.ResolveSiteUnpinned(), middleware.Authenticate(), controllers.StreamEvents)
+}
+
+// siteRoutes registers the routes working on a single site's content.
+func siteRoutes(api *gin.RouterGroup) {
+	// Role checks; post ownership and publishing are enforced by the post
+	// controller itself.
+	writePages := middleware.RequirePermission(models.PermWritePages)
+	writePosts := middleware.RequirePermission(models.PermWritePosts, models.PermWriteOwnPosts)
+	writeTranslations := middleware.RequirePermission(models.PermWriteTranslations)
+	writeContent := middleware.RequirePermission(models.PermWriteContent)
+	manageContentTypes := middleware.RequirePermission(models.PermManageContentTypes)
+	manageWebhooks := middleware.RequirePermission(models.PermManageWebhooks)
+
+	// Scope checks for API key requests to the public reads; writes are
+	// covered by the permission checks above.
+	readPages := middleware.RequireScope(models.ScopePagesRead)
+	readPosts := middleware.RequireScope(models.ScopePostsRead)
+	readMedia := middleware.RequireScope(models.ScopeMediaRead)
+	readContent := middleware.RequireScope(models.ScopeContentRead)
+	readPagesOrPosts := middleware.RequireScope(models.ScopePagesRead, models.ScopePostsRead)
+
+	// Cached reads, revalidated with ETag and Last-Modified; posts embed
+	// their media.
+	conditionalPages := middleware.ConditionalGET(utils.CacheControl("pages", "public, max-age=60"))
+	conditionalPosts := middleware.ConditionalGET(utils.CacheControl("posts", "public, max-age=60"))
+	conditionalMedia := middleware.ConditionalGET(utils.CacheControl("media", "public, max-age=3600"))
+	cachePages := middleware.CacheResponse(models.ResourcePage)
+	cachePosts := middleware.CacheResponse(models.ResourcePost, models.ResourceMedia)
+	cacheMedia := middleware.CacheResponse(models.ResourceMedia)
+
+	api.GET("/site", controllers.GetCurrentSite)
+
+	api.GET("/api-keys", controllers.GetAPIKeys)
+	api.POST("/api-keys", controllers.CreateAPIKey)
+	api.DELETE("/api-keys/:id", controllers.DeleteAPIKey)
+
+	api.GET("/audit", middleware.RequirePermission(models.PermViewAudit), controllers.GetAuditLogs)
+
+	api.GET("/webhooks", manageWebhooks, controllers.GetWebhooks)
+	api.GET("/webhooks/:id", manageWebhooks, controllers.GetWebhook)
+	api.POST("/webhooks", manageWebhooks, controllers.CreateWebhook)
+	api.PUT("/webhooks/:id", manageWebhooks, controllers.UpdateWebhook)
+	api.DELETE("/webhooks/:id", manageWebhooks, controllers.DeleteWebhook)
+	api.GET("/webhooks/:id/deliveries", manageWebhooks, controllers.GetWebhookDeliveries)
+	api.POST("/webhooks/:id/deliveries/:delivery_id/redeliver", manageWebhooks, controllers.RedeliverWebhookDelivery)
+
+	api.GET("/pages", readPages, conditionalPages, cachePages, controllers.GetPages)
+	api.GET("/pages/:id", readPages, conditionalPages, cachePages, controllers.GetPage)
+	api.POST("/pages", writePages, controllers.CreatePage)
+	api.POST("/pages/bulk", writePages, controllers.BulkPages)
+	api.PUT("/pages/:id", writePages, controllers.UpdatePage)
+	api.PATCH("/pages/:id", writePages, controllers.PatchPage)
+	api.DELETE("/pages/:id", writePages, controllers.DeletePage)
+	api.POST("/pages/:id/lock", writePages, controllers.LockPage)
+	api.DELETE("/pages/:id/lock", writePages, controllers.UnlockPage)
+	api.GET("/pages/:id/translations", readPages, controllers.GetPageTranslations)
+	api.PUT("/pages/:id/translations/:locale", writeTranslations, controllers.UpsertPageTranslation)
+	api.DELETE("/pages/:id/translations/:locale", writeTranslations, controllers.DeletePageTranslation)
+
+	api.GET("/posts", readPosts, conditionalPosts, cachePosts, controllers.GetPosts)
+	api.GET("/posts/:id", readPosts, conditionalPosts, cachePosts, controllers.GetPost)
+	api.POST("/posts", writePosts, controllers.CreatePost)
+	api.POST("/posts/bulk", writePosts, controllers.BulkPosts)
+	api.PUT("/posts/:id", writePosts, controllers.UpdatePost)
+	api.PATCH("/posts/:id", writePosts, controllers.PatchPost)
+	api.DELETE("/posts/:id", writePosts, controllers.DeletePost)
+	api.POST("/posts/:id/lock", writePosts, controllers.LockPost)
+	api.DELETE("/posts/:id/lock", writePosts, controllers.UnlockPost)
+	api.GET("/posts/:id/translations", readPosts, controllers.GetPostTranslations)
+	api.PUT("/posts/:id/translations/:locale", writeTranslations, controllers.UpsertPostTranslation)
+	api.DELETE("/posts/:id/translations/:locale", writeTranslations, controllers.DeletePostTranslation)
+
+	api.GET("/translations/status", readPagesOrPosts, controllers.GetTranslationStatus)
+
+	api.GET("/media", readMedia, conditionalMedia, cacheMedia, controllers.GetMedia)
+	api.GET("/media/:id", readMedia, conditionalMedia, cacheMedia, controllers.GetMediaByID)
+	api.POST("/media", middleware.RequirePermission(models.PermWriteMedia), controllers.CreateMedia)
+	api.POST("/media/bulk", middleware.RequirePermission(models.PermWriteMedia), controllers.BulkMedia)
+	api.PATCH("/media/:id", middleware.RequirePermission(models.PermWriteMedia), controllers.PatchMedia)
+	api.DELETE("/media/:id", middleware.RequirePermission(models.PermDeleteMedia), controllers.DeleteMedia)
+
+	api.GET("/search", readPagesOrPosts, controllers.Search)
+	api.GET("/search/suggest", readPagesOrPosts, controllers.SearchSuggest)
+
+	api.GET("/content-types", readContent, controllers.GetContentTypes)
+	api.GET("/content-types/:type", readContent, controllers.GetContentType)
+	api.POST("/content-types", manageContentTypes, controllers.CreateContentType)
+	api.PUT("/content-types/:type", manageContentTypes, controllers.UpdateContentType)
+	api.DELETE("/content-types/:type", manageContentTypes, controllers.DeleteContentType)
+
+	api.GET("/content/:type", readContent, controllers.GetContentEntries)
+	api.GET("/content/:type/:id", readContent, controllers.GetContentEntry)
+	api.POST("/content/:type", writeContent, controllers.CreateContentEntry)
+	api.PUT("/content/:type/:id", writeContent, controllers.UpdateContentEntry)
+	api.DELETE("/content/:type/:id", writeContent, controllers.DeleteContentEntry)
 }
@@ -5,6 +5,7 @@ import (
 	"cms-backend/routes"
 	"cms-backend/utils"
 	"log"
+	"net/http"
 	"os"
 	"testing"
 
@@ -15,8 +16,9 @@ import (
 
 
 var (
-	testDB *gorm.DB
-	router *gin.Engine
+	testDB      *gorm.DB
+	router      *gin.Engine
+	accessToken string
 )
 
 
@@ -51,13 +53,38 @@ func setup() {
 	}
 
 	
-	if err := testDB.AutoMigrate(&models.Media{}, &models.Page{}, &models.Post{}); err != nil {
+	if err := testDB.AutoMigrate(&models.Site{}, &models.Media{}, &models.Page{}, &models.Post{}, &models.PostContributor{}, &models.Translation{}, &models.ContentType{}, &models.ContentEntry{}, &models.User{}, &models.RefreshToken{}, &models.RevokedToken{}, &models.APIKey{}, &models.OIDCState{}, &models.AuditLog{}, &models.Webhook{}, &models.WebhookDelivery{}, &models.OutboxEvent{}, &models.OutboxCursor{}, &models.EditLock{}); err != nil {
 		log.Fatalf("Failed to migrate test database: %v", err)
 	}
 
+	if err := utils.EnsureSearchIndex(testDB); err != nil {
+		log.Fatalf("Failed to set up search index: %v", err)
+	}
+
+	if err := utils.EnsureAuditLog(testDB); err != nil {
+		log.Fatalf("Failed to set up audit log: %v", err)
+	}
+
+	if err := utils.EnsureTenantIsolation(testDB); err != nil {
+		log.Fatalf("Failed to set up site isolation: %v", err)
+	}
+
 	
 	router = gin.New()
-	routes.InitializeRoutes(router, testDB)
+	routes.InitializeRoutes(router, testDB, testDB, nil, nil, nil, nil, nil, nil, nil)
+
+	user := models.User{Email: "integration@example.com", Name: "Integration", PasswordHash: "-", Role: models.UserRoleAdmin}
+	if err := testDB.Where(models.User{Email: user.Email}).FirstOrCreate(&user).Error; err != nil {
+		log.Fatalf("Failed to create test user: %v", err)
+	}
+	if accessToken, _, err = utils.IssueAccessToken(user.ID); err != nil {
+		log.Fatalf("Failed to issue test access token: %v", err)
+	}
+}
+
+// authorize signs a request as the integration test user.
+func authorize(req *http.Request) {
+	req.Header.Set("Authorization", "Bearer "+accessToken)
 }
 
 func cleanup() {
@@ -80,9 +107,13 @@ func clearTables() {
 	if testDB != nil {
 		
 		testDB.Exec("DELETE FROM post_media")
+		testDB.Exec("DELETE FROM post_contributors")
 		testDB.Exec("DELETE FROM posts")
 		testDB.Exec("DELETE FROM media")
 		testDB.Exec("DELETE FROM pages")
+		testDB.Exec("DELETE FROM translations")
+		testDB.Exec("DELETE FROM content_entries")
+		testDB.Exec("DELETE FROM content_types")
 	}
 }
 
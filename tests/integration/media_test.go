@@ -25,6 +25,7 @@ func TestMediaIntegration(t *testing.T) {
 		
 		req := httptest.NewRequest("POST", "/api/v1/media", strings.NewReader(body))
 		req.Header.Set("Content-Type", "application/json")
+		authorize(req)
 
 		
 		w := httptest.NewRecorder()
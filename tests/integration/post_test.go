@@ -28,6 +28,7 @@ func TestPostIntegration(t *testing.T) {
 
 		req := httptest.NewRequest("POST", "/api/v1/posts", strings.NewReader(postBody))
 		req.Header.Set("Content-Type", "application/json")
+		authorize(req)
 		w := httptest.NewRecorder()
 
 		router.ServeHTTP(w, req)
@@ -121,6 +122,7 @@ func createTestMedia(t *testing.T) uint {
 
 	req := httptest.NewRequest("POST", "/api/v1/media", strings.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
+	authorize(req)
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
@@ -0,0 +1,28 @@
+package utils
+
+import "gorm.io/gorm"
+
+// EnsureAuditLog makes audit_logs append-only: a trigger rejects every
+// UPDATE, DELETE and TRUNCATE. It is idempotent.
+func EnsureAuditLog(db *gorm.DB) error {
+	statements := []string{
+		`CREATE OR REPLACE FUNCTION cms_audit_logs_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_logs is append-only';
+END
+$$ LANGUAGE plpgsql`,
+		"DROP TRIGGER IF EXISTS audit_logs_append_only ON audit_logs",
+		"CREATE TRIGGER audit_logs_append_only BEFORE UPDATE OR DELETE ON audit_logs FOR EACH ROW EXECUTE FUNCTION cms_audit_logs_append_only()",
+		"DROP TRIGGER IF EXISTS audit_logs_no_truncate ON audit_logs",
+		"CREATE TRIGGER audit_logs_no_truncate BEFORE TRUNCATE ON audit_logs FOR EACH STATEMENT EXECUTE FUNCTION cms_audit_logs_append_only()",
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
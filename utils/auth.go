@@ -0,0 +1,131 @@
+package utils
+
+import (
+	"cms-backend/models"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	defaultAccessTokenTTL  = 15 * time.Minute
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+var (
+	generatedSecret     []byte
+	generatedSecretOnce sync.Once
+)
+
+// AccessClaims are the claims of an access JWT; the subject is the user ID.
+type AccessClaims struct {
+	jwt.RegisteredClaims
+}
+
+// JWTSecret is the HMAC key signing access tokens. Without JWT_SECRET a
+// random key is generated, so tokens do not survive a restart.
+func JWTSecret() []byte {
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		return []byte(secret)
+	}
+	generatedSecretOnce.Do(func() {
+		log.Println("JWT_SECRET is not set; using a random secret")
+		generatedSecret = []byte(RandomToken())
+	})
+	return generatedSecret
+}
+
+// AccessTokenTTL is how long access tokens are valid (ACCESS_TOKEN_TTL).
+func AccessTokenTTL() time.Duration {
+	return durationEnv("ACCESS_TOKEN_TTL", defaultAccessTokenTTL)
+}
+
+// RefreshTokenTTL is how long refresh tokens are valid (REFRESH_TOKEN_TTL).
+func RefreshTokenTTL() time.Duration {
+	return durationEnv("REFRESH_TOKEN_TTL", defaultRefreshTokenTTL)
+}
+
+func durationEnv(name string, fallback time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(name)); err == nil && d > 0 {
+		return d
+	}
+	return fallback
+}
+
+// IssueAccessToken signs a short-lived HS256 access token for a user.
+func IssueAccessToken(userID uint) (string, AccessClaims, error) {
+	now := time.Now()
+	claims := AccessClaims{jwt.RegisteredClaims{
+		ID:        RandomToken(),
+		Subject:   strconv.FormatUint(uint64(userID), 10),
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(AccessTokenTTL())),
+	}}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(JWTSecret())
+	return token, claims, err
+}
+
+// ParseAccessToken verifies an access token's signature and expiry.
+func ParseAccessToken(token string) (*AccessClaims, error) {
+	var claims AccessClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (interface{}, error) {
+		return JWTSecret(), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+// UserID returns the user an access token was issued to.
+func (c AccessClaims) UserID() (uint, error) {
+	id, err := strconv.ParseUint(c.Subject, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid token subject")
+	}
+	return uint(id), nil
+}
+
+// RandomToken returns 32 random bytes, base64url encoded.
+func RandomToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// NewAPIKey returns a random API key and the prefix identifying it.
+func NewAPIKey() (key, prefix string) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	prefix = models.APIKeyPrefix + hex.EncodeToString(b)
+	return prefix + "_" + RandomToken(), prefix
+}
+
+// HashToken is the SHA-256 hex digest under which opaque tokens are stored.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
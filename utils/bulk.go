@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+const defaultBulkMaxOperations = 1000
+
+// BulkMaxOperations is the most operations one bulk request may carry
+// (BULK_MAX_OPERATIONS).
+func BulkMaxOperations() int {
+	if n, err := strconv.Atoi(os.Getenv("BULK_MAX_OPERATIONS")); err == nil && n > 0 {
+		return n
+	}
+	return defaultBulkMaxOperations
+}
+
+// NestTransactions returns a DB running on the transaction tx on which
+// Begin opens a savepoint instead of a transaction. Its Commit releases the
+// savepoint and its Rollback undoes only what was done since, so code that
+// runs its own transactions can run inside tx; nothing is committed until
+// tx is.
+func NestTransactions(tx *gorm.DB) *gorm.DB {
+	nested := tx.Session(&gorm.Session{NewDB: true, Context: tx.Statement.Context})
+	nested.Statement.ConnPool = &savepointPool{ConnPool: tx.Statement.ConnPool}
+	return nested
+}
+
+type savepointPool struct {
+	gorm.ConnPool
+	savepoints int
+}
+
+func (p *savepointPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	p.savepoints++
+	name := "sp" + strconv.Itoa(p.savepoints)
+	if _, err := p.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return nil, err
+	}
+	return &savepoint{ConnPool: p.ConnPool, name: name}, nil
+}
+
+// savepoint is what GORM takes for a transaction begun on a savepointPool.
+// It cannot begin one itself, so GORM runs its statements in it as it
+// would in a transaction.
+type savepoint struct {
+	gorm.ConnPool
+	name string
+}
+
+func (s *savepoint) Commit() error {
+	_, err := s.ExecContext(context.Background(), "RELEASE SAVEPOINT "+s.name)
+	return err
+}
+
+func (s *savepoint) Rollback() error {
+	_, err := s.ExecContext(context.Background(), "ROLLBACK TO SAVEPOINT "+s.name)
+	return err
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	redisCacheKeyPrefix = "cms:cache:"
+	redisConnectTimeout = 5 * time.Second
+)
+
+// RedisCacheStore keeps the cache in Redis, or any server speaking its
+// protocol, shared by every replica.
+type RedisCacheStore struct {
+	client *redis.Client
+}
+
+// OpenRedisCacheStore connects to the Redis server at url, such as
+// redis://localhost:6379/0.
+func OpenRedisCacheStore(url string) (*RedisCacheStore, error) {
+	options, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+	client := redis.NewClient(options)
+	ctx, cancel := context.WithTimeout(context.Background(), redisConnectTimeout)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return NewRedisCacheStore(client), nil
+}
+
+func NewRedisCacheStore(client *redis.Client) *RedisCacheStore {
+	return &RedisCacheStore{client: client}
+}
+
+func (s *RedisCacheStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := s.client.Get(ctx, redisCacheKeyPrefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (s *RedisCacheStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return s.client.Set(ctx, redisCacheKeyPrefix+key, value, ttl).Err()
+}
+
+func (s *RedisCacheStore) Close() error {
+	return s.client.Close()
+}
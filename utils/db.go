@@ -8,22 +8,50 @@ import (
 	"gorm.io/gorm"
 )
 
-func ConnectDB() (*gorm.DB, error) {
-	dbUser := os.Getenv("DB_USER")
-	dbPassword := os.Getenv("DB_PASSWORD")
-	dbName := os.Getenv("DB_NAME")
-	dbHost := os.Getenv("DB_HOST")
-	dbPort := os.Getenv("DB_PORT")
+// DSN is the Postgres connection string built from the DB_* variables.
+func DSN() string {
+	return dsn(os.Getenv("DB_USER"), os.Getenv("DB_PASSWORD"))
+}
 
-	dsn := fmt.Sprintf(
+// SystemDSN is the connection string for work spanning every site, as
+// DB_SYSTEM_USER with DB_SYSTEM_PASSWORD, by default the same as DSN.
+// Row-level security shows a connection nothing until it is confined to a
+// site, so that role needs BYPASSRLS when DB_USER is a plain role.
+func SystemDSN() string {
+	user := os.Getenv("DB_SYSTEM_USER")
+	if user == "" {
+		return DSN()
+	}
+	return dsn(user, os.Getenv("DB_SYSTEM_PASSWORD"))
+}
+
+func dsn(user, password string) string {
+	return fmt.Sprintf(
 		"host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=UTC",
-		dbHost, dbUser, dbPassword, dbName, dbPort,
+		os.Getenv("DB_HOST"), user, password, os.Getenv("DB_NAME"), os.Getenv("DB_PORT"),
 	)
+}
 
+// ConnectDB connects as DB_USER, for requests working on a single site.
+func ConnectDB() (*gorm.DB, error) {
+	return connect(DSN())
+}
+
+// ConnectSystemDB connects as DB_SYSTEM_USER, for migrations, the reindex
+// command, the outbox relay and webhook deliveries, and requests working on
+// the whole deployment, such as site and user management.
+func ConnectSystemDB() (*gorm.DB, error) {
+	return connect(SystemDSN())
+}
+
+func connect(dsn string) (*gorm.DB, error) {
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
 	if err != nil {
 		return nil, err
 	}
+	if err := RegisterTenantScoping(db); err != nil {
+		return nil, err
+	}
 
 	return db, nil
 }
@@ -0,0 +1,11 @@
+package utils
+
+import "time"
+
+const defaultEditLockTTL = 2 * time.Minute
+
+// EditLockTTL is how long an edit lock lasts without being renewed
+// (EDIT_LOCK_TTL).
+func EditLockTTL() time.Duration {
+	return durationEnv("EDIT_LOCK_TTL", defaultEditLockTTL)
+}
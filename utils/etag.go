@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// BodyETag is a strong ETag hashing a response body.
+func BodyETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// VersionETag is the strong ETag of a representation of a resource at a
+// version. It names the version, which If-Match preconditions are checked
+// against, and hashes the body, which tells the resource's translations and
+// renderings apart. Without a body it only names the version, for
+// ConditionalGET to complete.
+func VersionETag(version uint, body []byte) string {
+	tag := `"v` + strconv.FormatUint(uint64(version), 10)
+	if body != nil {
+		tag += "-" + strings.Trim(BodyETag(body), `"`)
+	}
+	return tag + `"`
+}
+
+// ETagVersion is the version named by an ETag from VersionETag.
+func ETagVersion(etag string) (uint, bool) {
+	etag = strings.Trim(strings.TrimPrefix(strings.TrimSpace(etag), "W/"), `"`)
+	value, ok := strings.CutPrefix(etag, "v")
+	if !ok {
+		return 0, false
+	}
+	value, _, _ = strings.Cut(value, "-")
+	version, err := strconv.ParseUint(value, 10, 32)
+	return uint(version), err == nil
+}
+
+// RequireIfMatch reports whether updates and deletes of versioned resources
+// must name the version they apply to (REQUIRE_IF_MATCH=true).
+func RequireIfMatch() bool {
+	required, _ := strconv.ParseBool(os.Getenv("REQUIRE_IF_MATCH"))
+	return required
+}
@@ -0,0 +1,40 @@
+package utils
+
+import "sync"
+
+// EventNotifier tells event streams in this process that new events were
+// committed, so they read the event log now rather than at their next poll.
+type EventNotifier struct {
+	mu          sync.Mutex
+	subscribers map[chan struct{}]struct{}
+}
+
+func NewEventNotifier() *EventNotifier {
+	return &EventNotifier{subscribers: map[chan struct{}]struct{}{}}
+}
+
+// Subscribe returns a channel signalled after new events are committed and
+// a function ending the subscription. Signals are coalesced.
+func (n *EventNotifier) Subscribe() (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	n.mu.Lock()
+	n.subscribers[ch] = struct{}{}
+	n.mu.Unlock()
+	return ch, func() {
+		n.mu.Lock()
+		delete(n.subscribers, ch)
+		n.mu.Unlock()
+	}
+}
+
+// Notify signals every subscriber.
+func (n *EventNotifier) Notify() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for ch := range n.subscribers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"gorm.io/gorm"
+)
+
+const (
+	// InvalidationChannel is the Postgres channel cache invalidations are
+	// sent on.
+	InvalidationChannel = "cms_invalidate"
+
+	// InvalidateAll is the resource of an invalidation dropping every
+	// cached entry, sent to subscribers when notifications may have been
+	// missed.
+	InvalidateAll = "*"
+
+	defaultCacheTTL         = 5 * time.Minute
+	defaultCacheFallbackTTL = 5 * time.Second
+	listenRetryBase         = time.Second
+	listenRetryMax          = 30 * time.Second
+)
+
+// Invalidation names data that changed: a resource of a site, or one
+// resource when ID is set.
+type Invalidation struct {
+	SiteID   uint   `json:"site_id,omitempty"`
+	Resource string `json:"resource"`
+	ID       uint   `json:"id,omitempty"`
+}
+
+// NotifyInvalidation sends the invalidation to every replica. Postgres
+// delivers it when tx commits, and drops it if tx rolls back.
+func NotifyInvalidation(tx *gorm.DB, invalidation Invalidation) error {
+	payload, err := json.Marshal(invalidation)
+	if err != nil {
+		return err
+	}
+	return tx.Exec("SELECT pg_notify(?, ?)", InvalidationChannel, string(payload)).Error
+}
+
+// InvalidationListener listens for invalidations on a dedicated connection
+// and passes them to its subscribers. While the connection is down,
+// notifications are lost, so MaxAge falls back to a short TTL, and every
+// cache is dropped when it goes down and again once it is back.
+type InvalidationListener struct {
+	dsn         string
+	ttl         time.Duration
+	fallbackTTL time.Duration
+	listening   atomic.Bool
+
+	mu          sync.RWMutex
+	subscribers []func(Invalidation)
+}
+
+// NewInvalidationListener listens on the database at dsn. Cached entries
+// live for CACHE_TTL while it listens and CACHE_FALLBACK_TTL otherwise.
+func NewInvalidationListener(dsn string) *InvalidationListener {
+	return &InvalidationListener{
+		dsn:         dsn,
+		ttl:         durationEnv("CACHE_TTL", defaultCacheTTL),
+		fallbackTTL: durationEnv("CACHE_FALLBACK_TTL", defaultCacheFallbackTTL),
+	}
+}
+
+// Subscribe registers fn to be called with every invalidation.
+func (l *InvalidationListener) Subscribe(fn func(Invalidation)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.subscribers = append(l.subscribers, fn)
+}
+
+// Listening reports whether invalidations are currently being received.
+func (l *InvalidationListener) Listening() bool {
+	return l.listening.Load()
+}
+
+// MaxAge is how long a cached entry may be served.
+func (l *InvalidationListener) MaxAge() time.Duration {
+	if l.Listening() {
+		return l.ttl
+	}
+	return l.fallbackTTL
+}
+
+// Run listens until ctx is done, reconnecting with backoff whenever the
+// connection is lost.
+func (l *InvalidationListener) Run(ctx context.Context) {
+	delay := listenRetryBase
+	for {
+		started := time.Now()
+		err := l.listen(ctx)
+		if l.listening.Swap(false) {
+			l.publish(Invalidation{Resource: InvalidateAll})
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > listenRetryMax {
+			delay = listenRetryBase
+		}
+		log.Printf("Cache invalidation listener disconnected, retrying in %s: %v", delay, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > listenRetryMax {
+			delay = listenRetryMax
+		}
+	}
+}
+
+func (l *InvalidationListener) listen(ctx context.Context) error {
+	conn, err := pgx.Connect(ctx, l.dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+	if _, err := conn.Exec(ctx, "LISTEN "+InvalidationChannel); err != nil {
+		return err
+	}
+
+	// Anything may have changed while nobody was listening.
+	l.listening.Store(true)
+	l.publish(Invalidation{Resource: InvalidateAll})
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		var invalidation Invalidation
+		if err := json.Unmarshal([]byte(notification.Payload), &invalidation); err != nil {
+			log.Printf("Ignoring malformed cache invalidation %q: %v", notification.Payload, err)
+			continue
+		}
+		l.publish(invalidation)
+	}
+}
+
+func (l *InvalidationListener) publish(invalidation Invalidation) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for _, fn := range l.subscribers {
+		fn(invalidation)
+	}
+}
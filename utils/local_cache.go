@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// LocalCache is an in-process cache whose entries are served for at most
+// maxAge. It is meant to be cleared by an InvalidationListener subscriber,
+// with maxAge bounding staleness when invalidations are not received.
+type LocalCache struct {
+	mu      sync.RWMutex
+	entries map[string]localCacheEntry
+	maxAge  func() time.Duration
+}
+
+type localCacheEntry struct {
+	value    interface{}
+	storedAt time.Time
+}
+
+func NewLocalCache(maxAge func() time.Duration) *LocalCache {
+	return &LocalCache{entries: map[string]localCacheEntry{}, maxAge: maxAge}
+}
+
+func (c *LocalCache) Get(key string) (interface{}, bool) {
+	c.mu.RLock()
+	entry, ok := c.entries[key]
+	c.mu.RUnlock()
+	if !ok || time.Since(entry.storedAt) > c.maxAge() {
+		return nil, false
+	}
+	return entry.value, true
+}
+
+func (c *LocalCache) Set(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = localCacheEntry{value: value, storedAt: time.Now()}
+}
+
+// Clear drops every entry.
+func (c *LocalCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = map[string]localCacheEntry{}
+}
@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultSourceLocale = "en"
+
+var defaultSupportedLocales = []string{"en", "de", "fr"}
+
+// SourceLocale is the locale canonical pages and posts are written in.
+func SourceLocale() string {
+	if locale := NormalizeLocale(os.Getenv("SOURCE_LOCALE")); locale != "" {
+		return locale
+	}
+	return defaultSourceLocale
+}
+
+// SupportedLocales lists every locale content may be published in,
+// including the source locale.
+func SupportedLocales() []string {
+	locales := splitLocales(os.Getenv("SUPPORTED_LOCALES"))
+	if len(locales) == 0 {
+		locales = defaultSupportedLocales
+	}
+	return appendLocale(locales, SourceLocale())
+}
+
+func IsSupportedLocale(locale string) bool {
+	for _, l := range SupportedLocales() {
+		if l == locale {
+			return true
+		}
+	}
+	return false
+}
+
+// FallbackChain returns the locales to try, in order, when serving the
+// requested locale: the locale itself, its base language, the configured
+// LOCALE_FALLBACKS and finally the source locale.
+func FallbackChain(locale string) []string {
+	var chain []string
+	chain = appendLocale(chain, locale)
+	if i := strings.Index(locale, "-"); i > 0 {
+		chain = appendLocale(chain, locale[:i])
+	}
+	for _, l := range splitLocales(os.Getenv("LOCALE_FALLBACKS")) {
+		chain = appendLocale(chain, l)
+	}
+	return appendLocale(chain, SourceLocale())
+}
+
+// RequestedLocale picks the locale for a request from ?locale= or, failing
+// that, the best supported match in Accept-Language. It returns "" when the
+// client expressed no preference.
+func RequestedLocale(c *gin.Context) string {
+	if locale := NormalizeLocale(c.Query("locale")); locale != "" {
+		return locale
+	}
+	for _, locale := range parseAcceptLanguage(c.GetHeader("Accept-Language")) {
+		for _, candidate := range []string{locale, baseLanguage(locale)} {
+			if IsSupportedLocale(candidate) {
+				return candidate
+			}
+		}
+	}
+	return ""
+}
+
+func NormalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		locale string
+		q      float64
+	}
+	var entries []weighted
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		locale := NormalizeLocale(fields[0])
+		if locale == "" || locale == "*" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > 0 {
+			entries = append(entries, weighted{locale, q})
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].q > entries[j].q })
+
+	locales := make([]string, len(entries))
+	for i, e := range entries {
+		locales[i] = e.locale
+	}
+	return locales
+}
+
+func baseLanguage(locale string) string {
+	if i := strings.Index(locale, "-"); i > 0 {
+		return locale[:i]
+	}
+	return locale
+}
+
+func splitLocales(value string) []string {
+	var locales []string
+	for _, l := range strings.Split(value, ",") {
+		if l = NormalizeLocale(l); l != "" {
+			locales = appendLocale(locales, l)
+		}
+	}
+	return locales
+}
+
+func appendLocale(locales []string, locale string) []string {
+	for _, l := range locales {
+		if l == locale {
+			return locales
+		}
+	}
+	return append(locales, locale)
+}
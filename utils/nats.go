@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"cms-backend/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	defaultNATSStream        = "CMS_EVENTS"
+	defaultNATSSubjectPrefix = "cms.events"
+)
+
+type NATSConfig struct {
+	URL           string
+	Stream        string
+	SubjectPrefix string
+}
+
+// NATSConfigFromEnv reads NATS_URL, NATS_STREAM and NATS_SUBJECT_PREFIX.
+func NATSConfigFromEnv() NATSConfig {
+	return NATSConfig{
+		URL:           os.Getenv("NATS_URL"),
+		Stream:        os.Getenv("NATS_STREAM"),
+		SubjectPrefix: os.Getenv("NATS_SUBJECT_PREFIX"),
+	}
+}
+
+// NATSSink publishes events to a JetStream stream as
+// <prefix>.<site_id>.<event type>. Publishes wait for the stream's
+// acknowledgement, and the event ID is sent as Nats-Msg-Id so the stream
+// drops events the relay sends twice within its duplicate window.
+type NATSSink struct {
+	conn   *nats.Conn
+	js     nats.JetStreamContext
+	prefix string
+}
+
+// OpenNATSSink connects to the server and creates the stream when it does
+// not exist yet.
+func OpenNATSSink(config NATSConfig) (*NATSSink, error) {
+	if config.URL == "" {
+		return nil, errors.New("NATS_URL must be set for the nats outbox sink")
+	}
+	if config.Stream == "" {
+		config.Stream = defaultNATSStream
+	}
+	if config.SubjectPrefix == "" {
+		config.SubjectPrefix = defaultNATSSubjectPrefix
+	}
+
+	conn, err := nats.Connect(config.URL, nats.Name("cms-backend"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, err
+	}
+	js, err := conn.JetStream()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if _, err := js.StreamInfo(config.Stream); errors.Is(err, nats.ErrStreamNotFound) {
+		_, err = js.AddStream(&nats.StreamConfig{
+			Name:     config.Stream,
+			Subjects: []string{config.SubjectPrefix + ".>"},
+		})
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("creating stream %s: %w", config.Stream, err)
+		}
+	} else if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &NATSSink{conn: conn, js: js, prefix: config.SubjectPrefix}, nil
+}
+
+func (s *NATSSink) Name() string {
+	return SinkNATS
+}
+
+// Subject is the subject the event is published on.
+func (s *NATSSink) Subject(event models.Event) string {
+	return s.prefix + "." + strconv.FormatUint(uint64(event.SiteID), 10) + "." + event.Type
+}
+
+func (s *NATSSink) Publish(ctx context.Context, event models.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = s.js.Publish(s.Subject(event), data, nats.MsgId(event.ID))
+	return err
+}
+
+func (s *NATSSink) Close() {
+	s.conn.Close()
+}
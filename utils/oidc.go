@@ -0,0 +1,215 @@
+package utils
+
+import (
+	"cms-backend/models"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+const defaultOIDCGroupsClaim = "groups"
+
+// OIDCConfig configures single sign-on against an OpenID Connect provider.
+type OIDCConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	// GroupsClaim names the ID token claim listing the user's groups; dots
+	// reach into nested objects (realm_access.roles).
+	GroupsClaim string
+	// RoleMapping maps IdP groups to CMS roles.
+	RoleMapping map[string]string
+	// DefaultRole is given to users none of whose groups are mapped.
+	DefaultRole string
+}
+
+// OIDCIdentity is the verified identity from an ID token.
+type OIDCIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Groups        []string
+}
+
+// OIDCClient runs the authorization code flow with PKCE against a
+// provider found through discovery, verifying ID tokens with its JWKS.
+type OIDCClient struct {
+	config   OIDCConfig
+	oauth2   oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+// OIDCConfigFromEnv reads the OIDC_* variables. ok is false when
+// OIDC_ISSUER is unset and single sign-on is disabled.
+func OIDCConfigFromEnv() (config OIDCConfig, ok bool, err error) {
+	issuer := os.Getenv("OIDC_ISSUER")
+	if issuer == "" {
+		return config, false, nil
+	}
+
+	config = OIDCConfig{
+		Issuer:       issuer,
+		ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+		Scopes:       strings.Fields(os.Getenv("OIDC_SCOPES")),
+		GroupsClaim:  os.Getenv("OIDC_GROUPS_CLAIM"),
+		RoleMapping:  map[string]string{},
+		DefaultRole:  os.Getenv("OIDC_DEFAULT_ROLE"),
+	}
+	if config.ClientID == "" || config.RedirectURL == "" {
+		return config, false, errors.New("OIDC_CLIENT_ID and OIDC_REDIRECT_URL are required with OIDC_ISSUER")
+	}
+	for _, pair := range strings.Split(os.Getenv("OIDC_ROLE_MAPPING"), ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		group, role, found := strings.Cut(pair, "=")
+		if !found {
+			return config, false, fmt.Errorf("invalid OIDC_ROLE_MAPPING entry %q", pair)
+		}
+		config.RoleMapping[strings.TrimSpace(group)] = strings.TrimSpace(role)
+	}
+	return config, true, nil
+}
+
+// OpenOIDCClient connects to the provider configured in the environment.
+// It returns nil when single sign-on is not configured.
+func OpenOIDCClient(ctx context.Context) (*OIDCClient, error) {
+	config, ok, err := OIDCConfigFromEnv()
+	if !ok || err != nil {
+		return nil, err
+	}
+	return NewOIDCClient(ctx, config)
+}
+
+// NewOIDCClient fetches the provider's discovery document and sets up the
+// client.
+func NewOIDCClient(ctx context.Context, config OIDCConfig) (*OIDCClient, error) {
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{oidc.ScopeOpenID, "email", "profile"}
+	}
+	if config.GroupsClaim == "" {
+		config.GroupsClaim = defaultOIDCGroupsClaim
+	}
+	if config.DefaultRole == "" {
+		config.DefaultRole = models.UserRoleViewer
+	}
+	if !models.IsValidUserRole(config.DefaultRole) {
+		return nil, fmt.Errorf("invalid OIDC default role %q", config.DefaultRole)
+	}
+	for group, role := range config.RoleMapping {
+		if !models.IsValidUserRole(role) {
+			return nil, fmt.Errorf("invalid role %q mapped from group %q", role, group)
+		}
+	}
+
+	provider, err := oidc.NewProvider(ctx, config.Issuer)
+	if err != nil {
+		return nil, err
+	}
+	return &OIDCClient{
+		config: config,
+		oauth2: oauth2.Config{
+			ClientID:     config.ClientID,
+			ClientSecret: config.ClientSecret,
+			RedirectURL:  config.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       config.Scopes,
+		},
+		verifier: provider.Verifier(&oidc.Config{ClientID: config.ClientID}),
+	}, nil
+}
+
+// AuthCodeURL is the provider URL the user is sent to, carrying the state,
+// the nonce and the S256 challenge of the PKCE verifier.
+func (o *OIDCClient) AuthCodeURL(state, nonce, verifier string) string {
+	return o.oauth2.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier))
+}
+
+// Exchange redeems an authorization code and verifies the returned ID
+// token's signature, issuer, audience, expiry and nonce.
+func (o *OIDCClient) Exchange(ctx context.Context, code, verifier, nonce string) (OIDCIdentity, error) {
+	token, err := o.oauth2.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return OIDCIdentity{}, err
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return OIDCIdentity{}, errors.New("token response has no id_token")
+	}
+	idToken, err := o.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return OIDCIdentity{}, err
+	}
+	if idToken.Nonce != nonce {
+		return OIDCIdentity{}, errors.New("id_token nonce does not match")
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return OIDCIdentity{}, err
+	}
+	identity := OIDCIdentity{
+		Subject: idToken.Subject,
+		Groups:  claimStrings(claims, o.config.GroupsClaim),
+	}
+	identity.Email, _ = claims["email"].(string)
+	identity.EmailVerified, _ = claims["email_verified"].(bool)
+	identity.Name, _ = claims["name"].(string)
+	if identity.Email == "" {
+		return OIDCIdentity{}, errors.New("id_token has no email claim")
+	}
+	return identity, nil
+}
+
+// Role maps the user's groups to the most privileged CMS role any of them
+// grants, or the default role.
+func (o *OIDCClient) Role(groups []string) string {
+	granted := map[string]bool{}
+	for _, group := range groups {
+		if role, ok := o.config.RoleMapping[group]; ok {
+			granted[role] = true
+		}
+	}
+	for _, role := range models.UserRoles {
+		if granted[role] {
+			return role
+		}
+	}
+	return o.config.DefaultRole
+}
+
+// claimStrings reads a string or list of strings at a dotted claim path.
+func claimStrings(claims map[string]interface{}, path string) []string {
+	var value interface{} = claims
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var values []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+package utils
+
+import (
+	"cms-backend/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	SinkWebhooks = "webhooks"
+	SinkNATS     = "nats"
+	SinkStdout   = "stdout"
+
+	outboxPollInterval     = 2 * time.Second
+	outboxPruneInterval    = time.Hour
+	outboxBatchSize        = 100
+	defaultOutboxRetention = 7 * 24 * time.Hour
+)
+
+// outboxCommitted limits the outbox to events of transactions older than
+// every transaction still running. No more such events can appear, so the
+// relay can move its cursor past them without taking a lock on writes.
+const outboxCommitted = `txid < pg_snapshot_xmin(pg_current_snapshot())::text::bigint`
+
+const initOutboxCursor = `INSERT INTO outbox_cursors (sink, last_event_id, updated_at) VALUES (?, 0, ?) ON CONFLICT (sink) DO NOTHING`
+
+// claimOutboxCursor locks a sink's cursor for the rest of the transaction;
+// it returns nothing while another replica is relaying to the sink.
+const claimOutboxCursor = `SELECT * FROM outbox_cursors WHERE sink = ? FOR UPDATE SKIP LOCKED`
+
+// pruneOutbox deletes events older than the retention that every sink has
+// been sent.
+const pruneOutbox = `DELETE FROM outbox WHERE created_at < ? AND (txid, id) <= (
+SELECT last_txid, last_event_id FROM outbox_cursors WHERE sink IN ? ORDER BY last_txid, last_event_id LIMIT 1
+)`
+
+// EventSink is a destination the outbox relay publishes events to. Publish
+// must not return before the event is stored by the sink; the relay retries
+// an event until it does, so sinks may see an event more than once.
+type EventSink interface {
+	Name() string
+	Publish(ctx context.Context, event models.Event) error
+}
+
+// AppendOutbox writes events to the outbox as part of tx, which must be
+// the transaction making the change they describe.
+func AppendOutbox(tx *gorm.DB, events []models.OutboxEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	return tx.Create(&events).Error
+}
+
+// OutboxAfter scopes a query to the committed outbox events after the one
+// at position (txID, id), in the order they are published. Events are
+// positioned by their transaction first, so one committed late is not
+// skipped by a reader that has moved past a higher ID.
+func OutboxAfter(txID int64, id uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(outboxCommitted).Where("(txid, id) > (?, ?)", txID, id).Order("txid, id")
+	}
+}
+
+// OutboxLatest scopes a query to the last committed outbox event.
+func OutboxLatest(db *gorm.DB) *gorm.DB {
+	return db.Where(outboxCommitted).Order("txid DESC, id DESC").Limit(1)
+}
+
+// OutboxSinks are the sink names listed in OUTBOX_SINKS, by default only
+// webhooks.
+func OutboxSinks() []string {
+	value := os.Getenv("OUTBOX_SINKS")
+	if value == "" {
+		return []string{SinkWebhooks}
+	}
+	var sinks []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			sinks = append(sinks, name)
+		}
+	}
+	return sinks
+}
+
+// OpenEventSinks sets up the sinks listed in OUTBOX_SINKS. The webhooks
+// sink queues deliveries on the given dispatcher.
+func OpenEventSinks(webhooks *WebhookDispatcher) ([]EventSink, error) {
+	var sinks []EventSink
+	for _, name := range OutboxSinks() {
+		switch name {
+		case SinkWebhooks:
+			sinks = append(sinks, webhooks)
+		case SinkNATS:
+			sink, err := OpenNATSSink(NATSConfigFromEnv())
+			if err != nil {
+				return nil, err
+			}
+			sinks = append(sinks, sink)
+		case SinkStdout:
+			sinks = append(sinks, NewWriterSink(SinkStdout, os.Stdout))
+		default:
+			return nil, fmt.Errorf("unknown outbox sink %q", name)
+		}
+	}
+	return sinks, nil
+}
+
+// WriterSink writes each event as a line of JSON.
+type WriterSink struct {
+	name string
+	mu   sync.Mutex
+	w    io.Writer
+}
+
+func NewWriterSink(name string, w io.Writer) *WriterSink {
+	return &WriterSink{name: name, w: w}
+}
+
+func (s *WriterSink) Name() string {
+	return s.name
+}
+
+func (s *WriterSink) Publish(ctx context.Context, event models.Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(append(line, '\n'))
+	return err
+}
+
+// OutboxRelay publishes outbox events to its sinks with at-least-once
+// semantics. Each sink has its own cursor, so it receives every event in
+// order and a failing sink holds up only itself.
+type OutboxRelay struct {
+	db        *gorm.DB
+	sinks     []EventSink
+	retention time.Duration
+	wake      chan struct{}
+}
+
+// NewOutboxRelay relays to the sinks, keeping published events for
+// OUTBOX_RETENTION.
+func NewOutboxRelay(db *gorm.DB, sinks ...EventSink) *OutboxRelay {
+	return &OutboxRelay{
+		db:        db,
+		sinks:     sinks,
+		retention: durationEnv("OUTBOX_RETENTION", defaultOutboxRetention),
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+// Wake makes Run relay now instead of at its next tick.
+func (r *OutboxRelay) Wake() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run relays events until ctx is done.
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+	lastPrune := time.Time{}
+	for {
+		for _, sink := range r.sinks {
+			for {
+				sent, err := r.Relay(ctx, sink)
+				if err != nil {
+					log.Printf("Failed to relay events to %s: %v", sink.Name(), err)
+				}
+				if err != nil || sent < outboxBatchSize {
+					break
+				}
+			}
+		}
+		if time.Since(lastPrune) > outboxPruneInterval {
+			if err := r.Prune(ctx); err != nil {
+				log.Printf("Failed to prune outbox: %v", err)
+			}
+			lastPrune = time.Now()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-r.wake:
+		}
+	}
+}
+
+// Relay publishes the next batch of events the sink has not been sent and
+// advances its cursor past those it accepted. It stops at the first event
+// the sink rejects, returning the number published and the sink's error.
+func (r *OutboxRelay) Relay(ctx context.Context, sink EventSink) (int, error) {
+	db := r.db.WithContext(ctx)
+	if err := db.Exec(initOutboxCursor, sink.Name(), time.Now()).Error; err != nil {
+		return 0, err
+	}
+
+	tx := db.Begin()
+	if tx.Error != nil {
+		return 0, tx.Error
+	}
+	defer tx.Rollback()
+
+	var cursors []models.OutboxCursor
+	if err := tx.Raw(claimOutboxCursor, sink.Name()).Scan(&cursors).Error; err != nil {
+		return 0, err
+	}
+	if len(cursors) == 0 {
+		return 0, nil
+	}
+	cursor := cursors[0]
+
+	var events []models.OutboxEvent
+	if err := tx.Scopes(OutboxAfter(cursor.LastTxID, cursor.LastEventID)).Limit(outboxBatchSize).Find(&events).Error; err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	var publishErr error
+	for _, event := range events {
+		if publishErr = sink.Publish(ctx, event.Event()); publishErr != nil {
+			break
+		}
+		cursor.LastTxID, cursor.LastEventID = event.TxID, event.ID
+		sent++
+	}
+	if sent > 0 {
+		if err := tx.Model(&cursor).Updates(map[string]interface{}{
+			"last_txid":     cursor.LastTxID,
+			"last_event_id": cursor.LastEventID,
+		}).Error; err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		return 0, err
+	}
+	return sent, publishErr
+}
+
+// Prune deletes events past the retention that every sink has been sent.
+func (r *OutboxRelay) Prune(ctx context.Context) error {
+	names := make([]string, 0, len(r.sinks))
+	for _, sink := range r.sinks {
+		names = append(names, sink.Name())
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Exec(pruneOutbox, time.Now().Add(-r.retention), names).Error
+}
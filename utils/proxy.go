@@ -0,0 +1,19 @@
+package utils
+
+import (
+	"os"
+	"strings"
+)
+
+// TrustedProxies are the addresses and CIDR ranges listed in
+// TRUSTED_PROXIES whose X-Forwarded-For and X-Real-IP headers are believed.
+// It is nil by default, so the client IP is the peer's address.
+func TrustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
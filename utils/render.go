@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	goldmarkhtml "github.com/yuin/goldmark/renderer/html"
+)
+
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+	FormatPlain    = "plain"
+)
+
+var (
+	// Raw HTML in Markdown is passed through to the sanitizer rather than
+	// dropped, so authors can still use allowed inline tags.
+	markdown = goldmark.New(
+		goldmark.WithExtensions(extension.GFM),
+		goldmark.WithRendererOptions(goldmarkhtml.WithUnsafe()),
+	)
+	// htmlPolicy is the allow-list every rendered document passes through,
+	// whatever its source format.
+	htmlPolicy = bluemonday.UGCPolicy()
+)
+
+func IsValidContentFormat(format string) bool {
+	return format == FormatMarkdown || format == FormatHTML || format == FormatPlain
+}
+
+// RenderHTML converts content in the given format to sanitized HTML.
+func RenderHTML(format, content string) (string, error) {
+	var unsafe string
+	switch format {
+	case FormatMarkdown:
+		var buf bytes.Buffer
+		if err := markdown.Convert([]byte(content), &buf); err != nil {
+			return "", err
+		}
+		unsafe = buf.String()
+	case FormatHTML:
+		unsafe = content
+	case FormatPlain, "":
+		unsafe = plainToHTML(content)
+	default:
+		return "", fmt.Errorf("unknown content format %q", format)
+	}
+	return htmlPolicy.Sanitize(unsafe), nil
+}
+
+// plainToHTML escapes text and keeps its paragraph and line breaks.
+func plainToHTML(content string) string {
+	var b strings.Builder
+	for _, paragraph := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n\n") {
+		if strings.TrimSpace(paragraph) == "" {
+			continue
+		}
+		b.WriteString("<p>")
+		b.WriteString(strings.ReplaceAll(html.EscapeString(paragraph), "\n", "<br>"))
+		b.WriteString("</p>\n")
+	}
+	return b.String()
+}
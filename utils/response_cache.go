@@ -0,0 +1,179 @@
+package utils
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	CacheBackendMemory = "memory"
+	CacheBackendRedis  = "redis"
+	CacheBackendNone   = "none"
+
+	defaultCacheMaxBytes = 64 << 20
+
+	// Generations outlive the entries stored under them; one that expires
+	// anyway is replaced, which only costs misses.
+	cacheGenerationTTL = 24 * time.Hour
+
+	// cacheEpochScope is a scope every entry depends on, so invalidating it
+	// drops the whole cache.
+	cacheEpochScope = "epoch"
+)
+
+// CacheStore is where a ResponseCache keeps its entries. Stores may drop
+// entries at any time.
+type CacheStore interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// CachedResponse is a response as kept in the cache.
+type CachedResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+// ResponseCache caches read responses. Every entry depends on scopes, such
+// as a site's posts or a single post, and is stored under the current
+// generation of each. Invalidating a scope gives it a new generation, so
+// the entries depending on it are never read again and age out of the
+// store. A response computed from data read before an invalidation is
+// stored under the old generation, so it cannot be served afterwards.
+type ResponseCache struct {
+	store  CacheStore
+	maxAge func() time.Duration
+}
+
+// NewResponseCache caches responses in store, each for maxAge at the time
+// it is stored.
+func NewResponseCache(store CacheStore, maxAge func() time.Duration) *ResponseCache {
+	return &ResponseCache{store: store, maxAge: maxAge}
+}
+
+// CacheBackend is the configured response cache backend, memory (the
+// default), redis or none.
+func CacheBackend() string {
+	if backend := os.Getenv("CACHE_BACKEND"); backend != "" {
+		return backend
+	}
+	return CacheBackendMemory
+}
+
+// CacheMaxBytes bounds the size of the memory cache backend.
+func CacheMaxBytes() int64 {
+	if n, err := strconv.ParseInt(os.Getenv("CACHE_MAX_BYTES"), 10, 64); err == nil && n > 0 {
+		return n
+	}
+	return defaultCacheMaxBytes
+}
+
+// OpenResponseCache opens the configured cache backend. It returns nil for
+// none.
+func OpenResponseCache(maxAge func() time.Duration) (*ResponseCache, error) {
+	switch backend := CacheBackend(); backend {
+	case CacheBackendNone:
+		return nil, nil
+	case CacheBackendMemory:
+		return NewResponseCache(NewMemoryCacheStore(CacheMaxBytes()), maxAge), nil
+	case CacheBackendRedis:
+		store, err := OpenRedisCacheStore(os.Getenv("REDIS_URL"))
+		if err != nil {
+			return nil, err
+		}
+		return NewResponseCache(store, maxAge), nil
+	default:
+		return nil, fmt.Errorf("unknown cache backend %q", backend)
+	}
+}
+
+// CacheControl is the Cache-Control of anonymous responses of a route,
+// CACHE_CONTROL_<ROUTE> when set and fallback otherwise.
+func CacheControl(route, fallback string) string {
+	if value, ok := os.LookupEnv("CACHE_CONTROL_" + strings.ToUpper(route)); ok {
+		return value
+	}
+	return fallback
+}
+
+// CacheScope is the scope of a site's resources of a type, or of a single
+// resource when id is not 0.
+func CacheScope(siteID uint, resource string, id uint) string {
+	scope := "site:" + strconv.FormatUint(uint64(siteID), 10) + ":" + resource
+	if id != 0 {
+		scope += ":" + strconv.FormatUint(uint64(id), 10)
+	}
+	return scope
+}
+
+// Lookup returns the response cached for key under the current generations
+// of scopes, if any, and the entry key to Store a fresh response under.
+func (r *ResponseCache) Lookup(ctx context.Context, key string, scopes []string) (string, *CachedResponse, error) {
+	hash := sha256.New()
+	hash.Write([]byte(key))
+	for _, scope := range append([]string{cacheEpochScope}, scopes...) {
+		generation, err := r.generation(ctx, scope)
+		if err != nil {
+			return "", nil, err
+		}
+		fmt.Fprintf(hash, "\x00%s=%s", scope, generation)
+	}
+	entryKey := "response:" + hex.EncodeToString(hash.Sum(nil))
+
+	value, ok, err := r.store.Get(ctx, entryKey)
+	if err != nil || !ok {
+		return entryKey, nil, err
+	}
+	var response CachedResponse
+	if err := json.Unmarshal(value, &response); err != nil {
+		return entryKey, nil, err
+	}
+	return entryKey, &response, nil
+}
+
+// Store caches response under an entry key returned by Lookup.
+func (r *ResponseCache) Store(ctx context.Context, entryKey string, response CachedResponse) error {
+	value, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	return r.store.Set(ctx, entryKey, value, r.maxAge())
+}
+
+// Invalidate drops the responses depending on what changed: the list of
+// the resource type and the resource itself, or everything for
+// InvalidateAll.
+func (r *ResponseCache) Invalidate(ctx context.Context, invalidation Invalidation) error {
+	if invalidation.Resource == InvalidateAll {
+		return r.renew(ctx, cacheEpochScope)
+	}
+	if err := r.renew(ctx, CacheScope(invalidation.SiteID, invalidation.Resource, 0)); err != nil {
+		return err
+	}
+	if invalidation.ID != 0 {
+		return r.renew(ctx, CacheScope(invalidation.SiteID, invalidation.Resource, invalidation.ID))
+	}
+	return nil
+}
+
+func (r *ResponseCache) generation(ctx context.Context, scope string) (string, error) {
+	value, ok, err := r.store.Get(ctx, "generation:"+scope)
+	if err != nil || ok {
+		return string(value), err
+	}
+	generation := RandomToken()
+	return generation, r.store.Set(ctx, "generation:"+scope, []byte(generation), cacheGenerationTTL)
+}
+
+func (r *ResponseCache) renew(ctx context.Context, scope string) error {
+	return r.store.Set(ctx, "generation:"+scope, []byte(RandomToken()), cacheGenerationTTL)
+}
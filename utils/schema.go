@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+const schemaURL = "schema.json"
+
+// CompileSchema parses a JSON Schema document (draft 2020-12 unless the
+// document declares otherwise).
+func CompileSchema(schema []byte) (*jsonschema.Schema, error) {
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft2020
+	compiler.AssertFormat = true
+	if err := compiler.AddResource(schemaURL, bytes.NewReader(schema)); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	compiled, err := compiler.Compile(schemaURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	return compiled, nil
+}
+
+// ValidateJSON checks a JSON document against a JSON Schema document.
+func ValidateJSON(schema, document []byte) error {
+	compiled, err := CompileSchema(schema)
+	if err != nil {
+		return err
+	}
+	var value interface{}
+	if err := json.Unmarshal(document, &value); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	if err := compiled.Validate(value); err != nil {
+		if verr, ok := err.(*jsonschema.ValidationError); ok {
+			return fmt.Errorf("validation failed: %s", validationMessage(verr))
+		}
+		return err
+	}
+	return nil
+}
+
+// validationMessage flattens the leaf causes of a validation error into a
+// single line such as "/date: missing properties: 'start'".
+func validationMessage(err *jsonschema.ValidationError) string {
+	if len(err.Causes) == 0 {
+		location := err.InstanceLocation
+		if location == "" {
+			location = "/"
+		}
+		return location + ": " + err.Message
+	}
+	var buf bytes.Buffer
+	for i, cause := range err.Causes {
+		if i > 0 {
+			buf.WriteString("; ")
+		}
+		buf.WriteString(validationMessage(cause))
+	}
+	return buf.String()
+}
+
+// FieldSchema returns the JSON Schema for a resource's custom fields, read
+// from <FIELD_SCHEMA_DIR>/<resource>.json, or nil when none is configured.
+func FieldSchema(resourceType string) ([]byte, error) {
+	dir := os.Getenv("FIELD_SCHEMA_DIR")
+	if dir == "" {
+		return nil, nil
+	}
+	schema, err := os.ReadFile(filepath.Join(dir, resourceType+".json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return schema, err
+}
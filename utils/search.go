@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"gorm.io/gorm"
+)
+
+const defaultSearchLanguage = "english"
+
+var searchLanguagePattern = regexp.MustCompile(`^[a-z_]+$`)
+
+// SearchLanguage is the Postgres text search configuration used to build
+// and query the search vectors (english, german, french, simple, ...).
+func SearchLanguage() string {
+	if language := os.Getenv("SEARCH_LANGUAGE"); searchLanguagePattern.MatchString(language) {
+		return language
+	}
+	return defaultSearchLanguage
+}
+
+// EnsureSearchIndex installs the weighted search_vector columns, their GIN
+// indexes and the triggers maintaining them on posts and pages, plus the
+// pg_trgm indexes behind suggestions. It is idempotent; when SEARCH_LANGUAGE
+// changed since the last run the existing rows are re-indexed with the new
+// configuration.
+func EnsureSearchIndex(db *gorm.DB) error {
+	language := SearchLanguage()
+
+	var current string
+	db.Raw("SELECT COALESCE(obj_description(to_regprocedure('cms_search_vector_update()'), 'pg_proc'), '')").Scan(&current)
+
+	statements := []string{
+		"ALTER TABLE posts ADD COLUMN IF NOT EXISTS search_vector tsvector",
+		"ALTER TABLE pages ADD COLUMN IF NOT EXISTS search_vector tsvector",
+		fmt.Sprintf(`CREATE OR REPLACE FUNCTION cms_search_vector_update() RETURNS trigger AS $$
+BEGIN
+    NEW.search_vector :=
+        setweight(to_tsvector('%[1]s', COALESCE(NEW.title, '')), 'A') ||
+        setweight(to_tsvector('%[1]s', COALESCE(NEW.content, '')), 'B');
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql`, language),
+		fmt.Sprintf("COMMENT ON FUNCTION cms_search_vector_update() IS '%s'", language),
+		"DROP TRIGGER IF EXISTS posts_search_vector_update ON posts",
+		"CREATE TRIGGER posts_search_vector_update BEFORE INSERT OR UPDATE OF title, content ON posts FOR EACH ROW EXECUTE FUNCTION cms_search_vector_update()",
+		"DROP TRIGGER IF EXISTS pages_search_vector_update ON pages",
+		"CREATE TRIGGER pages_search_vector_update BEFORE INSERT OR UPDATE OF title, content ON pages FOR EACH ROW EXECUTE FUNCTION cms_search_vector_update()",
+		"CREATE INDEX IF NOT EXISTS idx_posts_search_vector ON posts USING GIN (search_vector)",
+		"CREATE INDEX IF NOT EXISTS idx_pages_search_vector ON pages USING GIN (search_vector)",
+		"CREATE EXTENSION IF NOT EXISTS pg_trgm",
+		"CREATE INDEX IF NOT EXISTS idx_posts_title_trgm ON posts USING GIN (title gin_trgm_ops)",
+		"CREATE INDEX IF NOT EXISTS idx_pages_title_trgm ON pages USING GIN (title gin_trgm_ops)",
+		"CREATE INDEX IF NOT EXISTS idx_post_contributors_name_trgm ON post_contributors USING GIN (name gin_trgm_ops)",
+	}
+	if current != language {
+		statements = append(statements,
+			"UPDATE posts SET title = title",
+			"UPDATE pages SET title = title",
+		)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
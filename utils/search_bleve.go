@@ -0,0 +1,253 @@
+package utils
+
+import (
+	"cms-backend/models"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	_ "github.com/blevesearch/bleve/v2/analysis/lang/de"
+	_ "github.com/blevesearch/bleve/v2/analysis/lang/en"
+	_ "github.com/blevesearch/bleve/v2/analysis/lang/es"
+	_ "github.com/blevesearch/bleve/v2/analysis/lang/fr"
+	_ "github.com/blevesearch/bleve/v2/analysis/lang/it"
+	_ "github.com/blevesearch/bleve/v2/analysis/lang/nl"
+	_ "github.com/blevesearch/bleve/v2/analysis/lang/pt"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+// bleveAnalyzers maps Postgres text search configurations to the Bleve
+// analyzers used for the same SEARCH_LANGUAGE.
+var bleveAnalyzers = map[string]string{
+	"english":    "en",
+	"german":     "de",
+	"spanish":    "es",
+	"french":     "fr",
+	"italian":    "it",
+	"dutch":      "nl",
+	"portuguese": "pt",
+}
+
+// BleveSearchIndex is an embedded, file-based search index for deployments
+// that keep search load off the primary database.
+type BleveSearchIndex struct {
+	index bleve.Index
+}
+
+// OpenBleveSearchIndex opens the index at path, creating it when missing.
+func OpenBleveSearchIndex(path string) (*BleveSearchIndex, error) {
+	index, err := bleve.Open(path)
+	if err == bleve.ErrorIndexPathDoesNotExist {
+		index, err = bleve.New(path, bleveMapping())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &BleveSearchIndex{index: index}, nil
+}
+
+// NewMemoryBleveSearchIndex creates a throwaway in-memory index.
+func NewMemoryBleveSearchIndex() (*BleveSearchIndex, error) {
+	index, err := bleve.NewMemOnly(bleveMapping())
+	if err != nil {
+		return nil, err
+	}
+	return &BleveSearchIndex{index: index}, nil
+}
+
+func bleveMapping() mapping.IndexMapping {
+	analyzer, ok := bleveAnalyzers[SearchLanguage()]
+	if !ok {
+		analyzer = "standard"
+	}
+
+	text := bleve.NewTextFieldMapping()
+	text.Analyzer = analyzer
+	text.IncludeTermVectors = true
+
+	keyword := bleve.NewKeywordFieldMapping()
+	keyword.IncludeInAll = false
+
+	date := bleve.NewDateTimeFieldMapping()
+	date.IncludeInAll = false
+
+	doc := bleve.NewDocumentStaticMapping()
+	doc.AddFieldMappingsAt("site", keyword)
+	doc.AddFieldMappingsAt("type", keyword)
+	doc.AddFieldMappingsAt("title", text)
+	doc.AddFieldMappingsAt("content", text)
+	doc.AddFieldMappingsAt("authors", keyword)
+	doc.AddFieldMappingsAt("category", keyword)
+	doc.AddFieldMappingsAt("year", keyword)
+	doc.AddFieldMappingsAt("created_at", date)
+	doc.AddFieldMappingsAt("updated_at", date)
+
+	indexMapping := bleve.NewIndexMapping()
+	indexMapping.DefaultMapping = doc
+	indexMapping.DefaultAnalyzer = analyzer
+	return indexMapping
+}
+
+func bleveDocumentID(resourceType string, id uint) string {
+	return resourceType + ":" + strconv.FormatUint(uint64(id), 10)
+}
+
+func (b *BleveSearchIndex) Index(doc SearchDocument) error {
+	return b.index.Index(bleveDocumentID(doc.Type, doc.ID), map[string]interface{}{
+		"site":       strconv.FormatUint(uint64(doc.SiteID), 10),
+		"type":       doc.Type,
+		"title":      doc.Title,
+		"content":    doc.Content,
+		"authors":    doc.Authors,
+		"category":   doc.Category,
+		"year":       strconv.Itoa(doc.CreatedAt.Year()),
+		"created_at": doc.CreatedAt,
+		"updated_at": doc.UpdatedAt,
+	})
+}
+
+func (b *BleveSearchIndex) Delete(resourceType string, id uint) error {
+	return b.index.Delete(bleveDocumentID(resourceType, id))
+}
+
+func (b *BleveSearchIndex) Close() error {
+	return b.index.Close()
+}
+
+// Search matches every query term across title and content, boosting title
+// matches, with the same facets and <mark> highlighting as Postgres.
+func (b *BleveSearchIndex) Search(q SearchQuery) (models.SearchResponse, error) {
+	response := models.SearchResponse{
+		Query:   q.Text,
+		Results: []models.SearchResult{},
+		Page:    q.Page,
+		PerPage: q.PerPage,
+		Facets:  map[string][]models.FacetBucket{},
+	}
+
+	must, should, mustNot := bleveTextQueries(q.Text)
+	var types []query.Query
+	for _, resourceType := range q.Types {
+		types = append(types, bleveTerm("type", resourceType))
+	}
+	must = append(must, bleve.NewDisjunctionQuery(types...))
+	if q.SiteID != 0 {
+		must = append(must, bleveTerm("site", strconv.FormatUint(uint64(q.SiteID), 10)))
+	}
+	if q.Author != "" {
+		must = append(must, bleveTerm("authors", q.Author))
+	}
+	if q.Category != "" {
+		must = append(must, bleveTerm("category", q.Category))
+	}
+	if q.Year != 0 {
+		must = append(must, bleveTerm("year", strconv.Itoa(q.Year)))
+	}
+
+	request := bleve.NewSearchRequestOptions(query.NewBooleanQuery(must, should, mustNot), q.PerPage, (q.Page-1)*q.PerPage, false)
+	request.Fields = []string{"type", "title", "created_at", "updated_at"}
+	request.Highlight = bleve.NewHighlightWithStyle("html")
+	request.Highlight.AddField("title")
+	request.Highlight.AddField("content")
+	request.SortBy([]string{"-_score", "-updated_at", "_id"})
+	request.AddFacet("type", bleve.NewFacetRequest("type", len(searchTables)))
+	request.AddFacet("year", bleve.NewFacetRequest("year", 100))
+	request.AddFacet("category", bleve.NewFacetRequest("category", 100))
+	request.AddFacet("author", bleve.NewFacetRequest("authors", 100))
+
+	result, err := b.index.Search(request)
+	if err != nil {
+		return response, err
+	}
+
+	response.Total = int64(result.Total)
+	for name, facet := range result.Facets {
+		buckets := []models.FacetBucket{}
+		for _, term := range facet.Terms.Terms() {
+			if term.Term != "" {
+				buckets = append(buckets, models.FacetBucket{Value: term.Term, Count: int64(term.Count)})
+			}
+		}
+		response.Facets[name] = buckets
+	}
+
+	for _, hit := range result.Hits {
+		resourceType, id, err := parseBleveDocumentID(hit.ID)
+		if err != nil {
+			return response, err
+		}
+		// Fragments are escaped by the html formatter; a title without a
+		// match is escaped here so stored markup never reaches clients.
+		title, _ := hit.Fields["title"].(string)
+		title = html.EscapeString(title)
+		if fragments := hit.Fragments["title"]; len(fragments) > 0 {
+			title = fragments[0]
+		}
+		createdAt, _ := time.Parse(time.RFC3339, fmt.Sprint(hit.Fields["created_at"]))
+		updatedAt, _ := time.Parse(time.RFC3339, fmt.Sprint(hit.Fields["updated_at"]))
+		response.Results = append(response.Results, models.SearchResult{
+			Type:      resourceType,
+			ID:        id,
+			Title:     title,
+			Snippet:   strings.Join(hit.Fragments["content"], " ... "),
+			Rank:      hit.Score,
+			CreatedAt: createdAt,
+			UpdatedAt: updatedAt,
+		})
+	}
+	return response, nil
+}
+
+// bleveTextQueries translates the web search syntax accepted by Postgres:
+// "quoted phrases" and plain words must all match, -words must not. Title
+// matches are optional and only boost the score; or is ignored.
+func bleveTextQueries(text string) (must, should, mustNot []query.Query) {
+	for i, part := range strings.Split(text, `"`) {
+		if i%2 == 1 {
+			if phrase := strings.TrimSpace(part); phrase != "" {
+				must = append(must, bleve.NewMatchPhraseQuery(phrase))
+				title := bleve.NewMatchPhraseQuery(phrase)
+				title.SetField("title")
+				title.SetBoost(2)
+				should = append(should, title)
+			}
+			continue
+		}
+		for _, word := range strings.Fields(part) {
+			if strings.HasPrefix(word, "-") {
+				if word = strings.TrimPrefix(word, "-"); word != "" {
+					mustNot = append(mustNot, bleve.NewMatchQuery(word))
+				}
+				continue
+			}
+			if strings.EqualFold(word, "or") {
+				continue
+			}
+			must = append(must, bleve.NewMatchQuery(word))
+			title := bleve.NewMatchQuery(word)
+			title.SetField("title")
+			title.SetBoost(2)
+			should = append(should, title)
+		}
+	}
+	return must, should, mustNot
+}
+
+func bleveTerm(field, value string) query.Query {
+	term := bleve.NewTermQuery(value)
+	term.SetField(field)
+	return term
+}
+
+func parseBleveDocumentID(docID string) (string, uint, error) {
+	resourceType, value, _ := strings.Cut(docID, ":")
+	id, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid search document id %q", docID)
+	}
+	return resourceType, uint(id), nil
+}
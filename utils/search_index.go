@@ -0,0 +1,182 @@
+package utils
+
+import (
+	"cms-backend/models"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	SearchBackendPostgres = "postgres"
+	SearchBackendBleve    = "bleve"
+
+	defaultSearchIndexPath = "data/search.bleve"
+)
+
+// SearchDocument is the searchable projection of a post or page.
+type SearchDocument struct {
+	SiteID    uint
+	Type      string
+	ID        uint
+	Title     string
+	Content   string
+	Authors   []string
+	Category  string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// SearchQuery is a parsed /search request. A zero SiteID searches every
+// site.
+type SearchQuery struct {
+	SiteID   uint
+	Text     string
+	Types    []string
+	Author   string
+	Category string
+	Year     int
+	Page     int
+	PerPage  int
+}
+
+// SearchIndex is a search backend. Controllers feed it every post and page
+// write; Index and Delete are no-ops for backends that index the tables
+// themselves.
+type SearchIndex interface {
+	Index(doc SearchDocument) error
+	Delete(resourceType string, id uint) error
+	Search(query SearchQuery) (models.SearchResponse, error)
+	Close() error
+}
+
+// SearchBackend is the configured search backend, postgres (the default)
+// or bleve.
+func SearchBackend() string {
+	if backend := os.Getenv("SEARCH_BACKEND"); backend != "" {
+		return backend
+	}
+	return SearchBackendPostgres
+}
+
+// SearchIndexPath is the directory holding the Bleve index.
+func SearchIndexPath() string {
+	if path := os.Getenv("SEARCH_INDEX_PATH"); path != "" {
+		return path
+	}
+	return defaultSearchIndexPath
+}
+
+// OpenSearchIndex opens the configured search backend. It returns nil for
+// postgres, which searches through each request's own connection.
+func OpenSearchIndex() (SearchIndex, error) {
+	switch backend := SearchBackend(); backend {
+	case SearchBackendPostgres:
+		return nil, nil
+	case SearchBackendBleve:
+		return OpenBleveSearchIndex(SearchIndexPath())
+	default:
+		return nil, fmt.Errorf("unknown SEARCH_BACKEND %q", backend)
+	}
+}
+
+// PostSearchDocument projects a post for indexing. Contributors must be
+// loaded for co-authors to be searchable.
+func PostSearchDocument(post models.Post) SearchDocument {
+	var authors []string
+	for _, contributor := range post.Contributors {
+		if contributor.Role == models.RoleAuthor {
+			authors = append(authors, contributor.Name)
+		}
+	}
+	if len(authors) == 0 && post.Author != "" {
+		authors = []string{post.Author}
+	}
+	return SearchDocument{
+		SiteID:    post.SiteID,
+		Type:      models.ResourcePost,
+		ID:        post.ID,
+		Title:     post.Title,
+		Content:   post.Content,
+		Authors:   authors,
+		Category:  fieldsCategory(post.Fields),
+		CreatedAt: post.CreatedAt,
+		UpdatedAt: post.UpdatedAt,
+	}
+}
+
+// PageSearchDocument projects a page for indexing.
+func PageSearchDocument(page models.Page) SearchDocument {
+	return SearchDocument{
+		SiteID:    page.SiteID,
+		Type:      models.ResourcePage,
+		ID:        page.ID,
+		Title:     page.Title,
+		Content:   page.Content,
+		Category:  fieldsCategory(page.Fields),
+		CreatedAt: page.CreatedAt,
+		UpdatedAt: page.UpdatedAt,
+	}
+}
+
+func fieldsCategory(fields models.JSON) string {
+	var object struct {
+		Category string `json:"category"`
+	}
+	if fields.IsNull() {
+		return ""
+	}
+	json.Unmarshal(fields, &object)
+	return object.Category
+}
+
+// ReindexSearch rebuilds the search index from the posts and pages tables
+// and returns the number of documents indexed. A nil index re-indexes the
+// Postgres search vectors.
+func ReindexSearch(db *gorm.DB, index SearchIndex) (int, error) {
+	if index == nil {
+		// Touching the rows re-runs the trigger maintaining search_vector.
+		count := 0
+		err := db.Transaction(func(tx *gorm.DB) error {
+			for _, table := range []string{"posts", "pages"} {
+				result := tx.Exec("UPDATE " + table + " SET title = title")
+				if result.Error != nil {
+					return result.Error
+				}
+				count += int(result.RowsAffected)
+			}
+			return nil
+		})
+		return count, err
+	}
+
+	count := 0
+	var posts []models.Post
+	err := db.Preload("Contributors").Where("status = ?", models.PostStatusPublished).FindInBatches(&posts, 500, func(tx *gorm.DB, batch int) error {
+		for _, post := range posts {
+			if err := index.Index(PostSearchDocument(post)); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return count, err
+	}
+
+	var pages []models.Page
+	err = db.FindInBatches(&pages, 500, func(tx *gorm.DB, batch int) error {
+		for _, page := range pages {
+			if err := index.Index(PageSearchDocument(page)); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	}).Error
+	return count, err
+}
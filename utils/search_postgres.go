@@ -0,0 +1,197 @@
+package utils
+
+import (
+	"cms-backend/models"
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// ts_headline returns stored HTML as is, so it marks matches with control
+// characters that highlightHTML turns into <mark> once the rest is escaped.
+const (
+	highlightStart = "\x02"
+	highlightStop  = "\x03"
+
+	searchTitleOptions    = "HighlightAll=true, StartSel=" + highlightStart + ", StopSel=" + highlightStop
+	searchHeadlineOptions = "StartSel=" + highlightStart + ", StopSel=" + highlightStop + ", MaxFragments=2, MaxWords=30, MinWords=10"
+)
+
+var highlightMarks = strings.NewReplacer(highlightStart, "<mark>", highlightStop, "</mark>")
+
+// highlightHTML escapes a ts_headline result and marks its matches, like
+// the Bleve backend's html highlighter.
+func highlightHTML(headline string) string {
+	return highlightMarks.Replace(html.EscapeString(headline))
+}
+
+// searchTables maps the searchable content types to their tables.
+var searchTables = map[string]string{
+	models.ResourcePost: "posts",
+	models.ResourcePage: "pages",
+}
+
+// searchFacetQuery buckets the matches CTE by content type, year, the
+// category custom field and credited author in a single round trip.
+const searchFacetQuery = `SELECT 'type' AS facet, type AS value, count(*) AS count FROM m GROUP BY type
+UNION ALL SELECT 'year', extract(year FROM created_at)::int::text, count(*) FROM m GROUP BY 2
+UNION ALL SELECT 'category', category, count(*) FROM m WHERE category <> '' GROUP BY category
+UNION ALL SELECT 'author', pc.name, count(DISTINCT m.id) FROM m JOIN post_contributors pc ON m.type = 'post' AND pc.post_id = m.id AND pc.role = 'author' GROUP BY pc.name
+ORDER BY facet, count DESC, value`
+
+// searchCorrectionQuery finds the closest word to a misspelt query term
+// among the words of titles and contributor names. Each %s takes the site
+// condition of its table.
+const searchCorrectionQuery = `SELECT word FROM (
+SELECT lower(regexp_split_to_table(title, '\W+')) AS word FROM posts WHERE status = 'published'%s
+UNION SELECT lower(regexp_split_to_table(title, '\W+')) FROM pages WHERE true%s
+UNION SELECT lower(regexp_split_to_table(pc.name, '\W+')) FROM post_contributors pc JOIN posts p ON p.id = pc.post_id WHERE p.status = 'published'%s
+) words WHERE length(word) > 2 AND word %% ? ORDER BY similarity(word, ?) DESC, word LIMIT 1`
+
+// PostgresSearchIndex searches the weighted search_vector columns (title A,
+// content B) kept current by triggers, so it has nothing to index itself.
+type PostgresSearchIndex struct {
+	db *gorm.DB
+}
+
+func NewPostgresSearchIndex(db *gorm.DB) *PostgresSearchIndex {
+	return &PostgresSearchIndex{db: db}
+}
+
+func (p *PostgresSearchIndex) Index(doc SearchDocument) error { return nil }
+
+func (p *PostgresSearchIndex) Delete(resourceType string, id uint) error { return nil }
+
+func (p *PostgresSearchIndex) Close() error { return nil }
+
+// Search runs a ranked full-text query with facet counts and a spelling
+// suggestion when nothing matches.
+func (p *PostgresSearchIndex) Search(query SearchQuery) (models.SearchResponse, error) {
+	response := models.SearchResponse{
+		Query:   query.Text,
+		Results: []models.SearchResult{},
+		Page:    query.Page,
+		PerPage: query.PerPage,
+		Facets:  map[string][]models.FacetBucket{},
+	}
+
+	language := SearchLanguage()
+	matches, matchArgs := p.matches(query, language)
+	if matches == "" {
+		return response, nil
+	}
+
+	var buckets []struct {
+		Facet string
+		Value string
+		Count int64
+	}
+	if err := p.db.Raw("WITH m AS ("+matches+") "+searchFacetQuery, matchArgs...).Scan(&buckets).Error; err != nil {
+		return response, err
+	}
+
+	for _, facet := range []string{"type", "year", "category", "author"} {
+		response.Facets[facet] = []models.FacetBucket{}
+	}
+	for _, b := range buckets {
+		response.Facets[b.Facet] = append(response.Facets[b.Facet], models.FacetBucket{Value: b.Value, Count: b.Count})
+		if b.Facet == "type" {
+			response.Total += b.Count
+		}
+	}
+
+	if response.Total == 0 {
+		suggestion, err := p.correct(query.Text, query.SiteID)
+		response.DidYouMean = suggestion
+		return response, err
+	}
+
+	args := append(matchArgs, language, searchTitleOptions, language, searchHeadlineOptions, query.PerPage, (query.Page-1)*query.PerPage)
+	err := p.db.Raw("WITH m AS ("+matches+") SELECT type, id, "+
+		"ts_headline(?::regconfig, title, query, ?) AS title, "+
+		"ts_headline(?::regconfig, content, query, ?) AS snippet, "+
+		"ts_rank_cd(search_vector, query) AS rank, created_at, updated_at "+
+		"FROM m ORDER BY rank DESC, updated_at DESC, id LIMIT ? OFFSET ?", args...).Scan(&response.Results).Error
+	for i := range response.Results {
+		response.Results[i].Title = highlightHTML(response.Results[i].Title)
+		response.Results[i].Snippet = highlightHTML(response.Results[i].Snippet)
+	}
+	return response, err
+}
+
+// matches builds the UNION of matching posts and pages that the facet and
+// result queries run over.
+func (p *PostgresSearchIndex) matches(query SearchQuery, language string) (string, []interface{}) {
+	var selects []string
+	var args []interface{}
+	for _, resourceType := range query.Types {
+		// Pages have no authors, so an author filter rules them out.
+		if query.Author != "" && resourceType == models.ResourcePage {
+			continue
+		}
+
+		sql := "SELECT '" + resourceType + "' AS type, t.id, t.title, t.content, t.search_vector, t.created_at, t.updated_at, " +
+			"COALESCE(t.fields->>'category', '') AS category, query " +
+			"FROM " + searchTables[resourceType] + " t, websearch_to_tsquery(?::regconfig, ?) query WHERE t.search_vector @@ query"
+		args = append(args, language, query.Text)
+
+		// Search is public, so drafts never match.
+		if resourceType == models.ResourcePost {
+			sql += " AND t.status = 'published'"
+		}
+		siteCondition, siteArgs := SiteCondition("t.site_id", query.SiteID)
+		sql += siteCondition
+		args = append(args, siteArgs...)
+		if query.Author != "" {
+			sql += " AND EXISTS (SELECT 1 FROM post_contributors pc WHERE pc.post_id = t.id AND pc.name = ? AND pc.role = 'author')"
+			args = append(args, query.Author)
+		}
+		if query.Category != "" {
+			sql += " AND t.fields->>'category' = ?"
+			args = append(args, query.Category)
+		}
+		if query.Year != 0 {
+			sql += " AND extract(year FROM t.created_at) = ?"
+			args = append(args, query.Year)
+		}
+		selects = append(selects, sql)
+	}
+	return strings.Join(selects, " UNION ALL "), args
+}
+
+// correct replaces each query word with its closest known word on the site
+// and returns the corrected query, or "" when no word needed correcting.
+func (p *PostgresSearchIndex) correct(q string, siteID uint) (string, error) {
+	condition, conditionArgs := SiteCondition("site_id", siteID)
+	joinedCondition, _ := SiteCondition("p.site_id", siteID)
+	correctionQuery := fmt.Sprintf(searchCorrectionQuery, condition, condition, joinedCondition)
+	var siteArgs []interface{}
+	for i := 0; i < 3; i++ {
+		siteArgs = append(siteArgs, conditionArgs...)
+	}
+
+	words := strings.Fields(strings.ToLower(q))
+	changed := false
+	for i, word := range words {
+		term := strings.TrimFunc(word, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+		if len(term) < 3 || term == "or" {
+			continue
+		}
+		var correction string
+		args := append(append([]interface{}{}, siteArgs...), term, term)
+		if err := p.db.Raw(correctionQuery, args...).Scan(&correction).Error; err != nil {
+			return "", err
+		}
+		if correction != "" && correction != term {
+			words[i] = strings.Replace(word, term, correction, 1)
+			changed = true
+		}
+	}
+	if !changed {
+		return "", nil
+	}
+	return strings.Join(words, " "), nil
+}
@@ -0,0 +1,198 @@
+package utils
+
+import (
+	"context"
+	"database/sql/driver"
+	"log"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TenantTables are the tables holding a site_id. Statements on them are
+// scoped to the site of their context, and row-level security scopes them
+// to the app.site_id setting of the connection.
+var TenantTables = map[string]bool{
+	"pages":              true,
+	"posts":              true,
+	"media":              true,
+	"translations":       true,
+	"content_types":      true,
+	"content_entries":    true,
+	"api_keys":           true,
+	"audit_logs":         true,
+	"webhooks":           true,
+	"webhook_deliveries": true,
+	"outbox":             true,
+	"edit_locks":         true,
+}
+
+type siteKey struct{}
+
+// WithSite returns a context whose statements are scoped to the site.
+func WithSite(ctx context.Context, siteID uint) context.Context {
+	return context.WithValue(ctx, siteKey{}, siteID)
+}
+
+// SiteFromContext is the site statements with this context are scoped to,
+// or 0 when they are not scoped.
+func SiteFromContext(ctx context.Context) uint {
+	if ctx == nil {
+		return 0
+	}
+	siteID, _ := ctx.Value(siteKey{}).(uint)
+	return siteID
+}
+
+// RegisterTenantScoping installs the GORM callbacks that confine queries,
+// updates and deletes on tenant tables to the context's site and stamp
+// creates and saves with it, so a request can neither read nor move another
+// site's rows. Raw SQL is not covered and must filter site_id itself.
+func RegisterTenantScoping(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if err := callbacks.Create().Before("gorm:create").Register("tenant:create", stampSite); err != nil {
+		return err
+	}
+	if err := callbacks.Query().Before("gorm:query").Register("tenant:query", scopeSite); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("tenant:update", func(db *gorm.DB) {
+		scopeSite(db)
+		stampSite(db)
+	}); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().Before("gorm:delete").Register("tenant:delete", scopeSite); err != nil {
+		return err
+	}
+	return callbacks.Row().Before("gorm:row").Register("tenant:row", scopeSite)
+}
+
+func scopeSite(db *gorm.DB) {
+	siteID := SiteFromContext(db.Statement.Context)
+	if siteID == 0 || !TenantTables[db.Statement.Table] {
+		return
+	}
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: "site_id"}, Value: siteID},
+	}})
+}
+
+func stampSite(db *gorm.DB) {
+	siteID := SiteFromContext(db.Statement.Context)
+	if siteID == 0 || db.Statement.Schema == nil || !TenantTables[db.Statement.Table] {
+		return
+	}
+	field := db.Statement.Schema.LookUpField("SiteID")
+	if field == nil {
+		return
+	}
+
+	value := db.Statement.ReflectValue
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if err := field.Set(db.Statement.Context, reflect.Indirect(value.Index(i)), siteID); err != nil {
+				db.AddError(err)
+			}
+		}
+	case reflect.Struct:
+		if err := field.Set(db.Statement.Context, value, siteID); err != nil {
+			db.AddError(err)
+		}
+	}
+}
+
+// PinSite checks out a connection for one request and sets app.site_id on
+// it, so row-level security confines it to the site. The returned DB uses
+// that connection and scopes statements to the site; release resets the
+// setting and returns the connection to the pool.
+func PinSite(ctx context.Context, db *gorm.DB, siteID uint) (*gorm.DB, func(), error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, nil, err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := conn.ExecContext(ctx, "SELECT set_config('app.site_id', $1, false)", strconv.FormatUint(uint64(siteID), 10)); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	pinned := db.Session(&gorm.Session{NewDB: true, Context: WithSite(ctx, siteID)})
+	pinned.Statement.ConnPool = conn
+	release := func() {
+		if _, err := conn.ExecContext(context.Background(), "RESET app.site_id"); err != nil {
+			// Never hand a connection scoped to a site back to the pool.
+			log.Printf("Failed to reset app.site_id, discarding connection: %v", err)
+			conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+		}
+		conn.Close()
+	}
+	return pinned, release, nil
+}
+
+// EnsureTenantIsolation creates the default site and installs the
+// row-level security policies confining every tenant table to the site in
+// app.site_id. Connections without the setting see no rows at all, so work
+// spanning every site connects as the system role (see SystemDSN).
+// Superusers and roles with BYPASSRLS are not subject to the policies, so
+// the API must connect as a plain role. It is idempotent.
+func EnsureTenantIsolation(db *gorm.DB) error {
+	statements := []string{
+		`INSERT INTO sites (id, slug, name, created_at, updated_at) VALUES (1, 'default', 'Default', now(), now()) ON CONFLICT (id) DO NOTHING`,
+		`SELECT setval(pg_get_serial_sequence('sites', 'id'), GREATEST((SELECT max(id) FROM sites), 1))`,
+		// Content type slugs became unique per site.
+		"DROP INDEX IF EXISTS idx_content_types_slug",
+		`CREATE OR REPLACE FUNCTION cms_current_site() RETURNS integer AS $$
+    SELECT NULLIF(current_setting('app.site_id', true), '')::integer
+$$ LANGUAGE sql STABLE`,
+	}
+	tables := make([]string, 0, len(TenantTables))
+	for table := range TenantTables {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	for _, table := range tables {
+		statements = append(statements,
+			"ALTER TABLE "+table+" ENABLE ROW LEVEL SECURITY",
+			"ALTER TABLE "+table+" FORCE ROW LEVEL SECURITY",
+			"DROP POLICY IF EXISTS site_isolation ON "+table,
+			"CREATE POLICY site_isolation ON "+table+" USING (site_id = cms_current_site())",
+		)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// InSite runs fn in a transaction confined to the site by row-level
+// security, for connections not pinned to a site with PinSite.
+func InSite(db *gorm.DB, siteID uint, fn func(tx *gorm.DB) error) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT set_config('app.site_id', ?, true)", strconv.FormatUint(uint64(siteID), 10)).Error; err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
+// SiteCondition is the " AND column = ?" clause confining raw SQL to a
+// site, which the tenant callbacks cannot do. It is empty for siteID 0.
+func SiteCondition(column string, siteID uint) (string, []interface{}) {
+	if siteID == 0 {
+		return "", nil
+	}
+	return " AND " + column + " = ?", []interface{}{siteID}
+}
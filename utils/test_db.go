@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"cms-backend/models"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
@@ -10,7 +11,15 @@ import (
 	"gorm.io/gorm/logger"
 )
 
+// SetupRouterAndMockDB returns a router whose requests run as an admin
+// against a mocked database.
 func SetupRouterAndMockDB(t *testing.T) (*gin.Engine, *gorm.DB, sqlmock.Sqlmock) {
+	return SetupRouterAndMockDBAs(t, &models.User{ID: 1, Email: "admin@example.com", Role: models.UserRoleAdmin})
+}
+
+// SetupRouterAndMockDBAs is SetupRouterAndMockDB acting as the given user,
+// or anonymously when user is nil.
+func SetupRouterAndMockDBAs(t *testing.T, user *models.User) (*gin.Engine, *gorm.DB, sqlmock.Sqlmock) {
 	sqldb, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatal(err)
@@ -27,10 +36,16 @@ func SetupRouterAndMockDB(t *testing.T) (*gin.Engine, *gorm.DB, sqlmock.Sqlmock)
 	if err != nil {
 		t.Fatal(err)
 	}
+	if err := RegisterTenantScoping(db); err != nil {
+		t.Fatal(err)
+	}
 
 	router := gin.Default()
 	router.Use(func(c *gin.Context) {
 		c.Set("db", db)
+		if user != nil {
+			c.Set("user", *user)
+		}
 	})
 	return router, db, mock
 }
@@ -0,0 +1,257 @@
+package utils
+
+import (
+	"bytes"
+	"cms-backend/models"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+
+	defaultWebhookMaxAttempts = 8
+	defaultWebhookTimeout     = 10 * time.Second
+	webhookRetryBase          = 30 * time.Second
+	webhookRetryMax           = 6 * time.Hour
+	webhookPollInterval       = 5 * time.Second
+	webhookBatchSize          = 20
+	webhookResponseLimit      = 1024
+)
+
+// claimDueDeliveries leases up to a batch of due deliveries by moving their
+// next attempt past the time it takes to send the batch, so no other
+// replica sends them meanwhile and a batch whose sender died is retried.
+const claimDueDeliveries = `UPDATE webhook_deliveries SET next_attempt_at = ? WHERE id IN (
+SELECT id FROM webhook_deliveries WHERE status IN ('pending', 'failed') AND next_attempt_at <= ?
+ORDER BY next_attempt_at, id LIMIT ? FOR UPDATE SKIP LOCKED
+) RETURNING *`
+
+// WebhookDispatcher is the outbox sink turning content events into webhook
+// deliveries. It sends them in the background, retrying failures with
+// exponential backoff until MaxAttempts is reached and the delivery is dead.
+type WebhookDispatcher struct {
+	db          *gorm.DB
+	client      *http.Client
+	MaxAttempts int
+	wake        chan struct{}
+}
+
+// NewWebhookDispatcher configures a dispatcher from WEBHOOK_MAX_ATTEMPTS
+// and WEBHOOK_TIMEOUT.
+func NewWebhookDispatcher(db *gorm.DB) *WebhookDispatcher {
+	maxAttempts, err := strconv.Atoi(os.Getenv("WEBHOOK_MAX_ATTEMPTS"))
+	if err != nil || maxAttempts < 1 {
+		maxAttempts = defaultWebhookMaxAttempts
+	}
+	return &WebhookDispatcher{
+		db:          db,
+		client:      &http.Client{Timeout: durationEnv("WEBHOOK_TIMEOUT", defaultWebhookTimeout)},
+		MaxAttempts: maxAttempts,
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// WebhookSignature signs a delivery: the hex HMAC-SHA256, keyed with the
+// webhook secret, of the timestamp, a dot and the body.
+func WebhookSignature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookBackoff is the delay before retrying after the given number of
+// failed attempts: 30s doubling per attempt, capped at 6h.
+func WebhookBackoff(attempts int) time.Duration {
+	delay := webhookRetryBase
+	for i := 1; i < attempts && delay < webhookRetryMax; i++ {
+		delay *= 2
+	}
+	if delay > webhookRetryMax {
+		delay = webhookRetryMax
+	}
+	return delay
+}
+
+func (d *WebhookDispatcher) Name() string {
+	return SinkWebhooks
+}
+
+// Publish queues a delivery of the event to every active webhook of its
+// site subscribed to it, skipping webhooks it was already queued for.
+func (d *WebhookDispatcher) Publish(ctx context.Context, event models.Event) error {
+	db := d.db.WithContext(WithSite(ctx, event.SiteID))
+	var webhooks []models.Webhook
+	if err := db.Where("active = ?", true).Find(&webhooks).Error; err != nil {
+		return err
+	}
+	var queued []uint
+	if err := db.Model(&models.WebhookDelivery{}).Where("event_id = ?", event.ID).
+		Pluck("webhook_id", &queued).Error; err != nil {
+		return err
+	}
+	skip := map[uint]bool{}
+	for _, id := range queued {
+		skip[id] = true
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	var deliveries []models.WebhookDelivery
+	for _, webhook := range webhooks {
+		if webhook.Events.Matches(event.Type) && !skip[webhook.ID] {
+			deliveries = append(deliveries, models.WebhookDelivery{
+				WebhookID:     webhook.ID,
+				EventID:       event.ID,
+				Event:         event.Type,
+				Payload:       models.JSON(payload),
+				Status:        models.DeliveryPending,
+				NextAttemptAt: &now,
+			})
+		}
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	if err := db.Create(&deliveries).Error; err != nil {
+		return err
+	}
+	d.Wake()
+	return nil
+}
+
+// Wake makes Run look for due deliveries now instead of at its next tick.
+func (d *WebhookDispatcher) Wake() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run sends due deliveries until ctx is done.
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+	for {
+		for {
+			sent, err := d.DeliverDue(ctx)
+			if err != nil {
+				log.Printf("Failed to deliver webhooks: %v", err)
+			}
+			if err != nil || sent < webhookBatchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// DeliverDue claims a batch of due deliveries, sends them and records the
+// outcome of each. It returns the number of deliveries attempted.
+func (d *WebhookDispatcher) DeliverDue(ctx context.Context) (int, error) {
+	now := time.Now()
+	var deliveries []models.WebhookDelivery
+	lease := now.Add(webhookBatchSize*d.client.Timeout + time.Minute)
+	if err := d.db.WithContext(ctx).Raw(claimDueDeliveries, lease, now, webhookBatchSize).
+		Scan(&deliveries).Error; err != nil {
+		return 0, err
+	}
+
+	for _, delivery := range deliveries {
+		if err := d.deliver(ctx, delivery); err != nil {
+			return 0, err
+		}
+	}
+	return len(deliveries), nil
+}
+
+func (d *WebhookDispatcher) deliver(ctx context.Context, delivery models.WebhookDelivery) error {
+	var webhook models.Webhook
+	result := d.db.WithContext(ctx).Limit(1).Find(&webhook, delivery.WebhookID)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"attempts":        delivery.Attempts + 1,
+		"last_attempt_at": now,
+		"response_status": 0,
+		"response_body":   "",
+		"error":           "",
+	}
+	if result.RowsAffected == 0 || !webhook.Active {
+		updates["status"] = models.DeliveryDead
+		updates["next_attempt_at"] = nil
+		updates["error"] = "Webhook is disabled"
+	} else {
+		status, body, err := d.send(ctx, webhook, delivery)
+		updates["response_status"] = status
+		updates["response_body"] = body
+		switch {
+		case err == nil:
+			updates["status"] = models.DeliverySucceeded
+			updates["next_attempt_at"] = nil
+		case delivery.Attempts+1 >= d.MaxAttempts:
+			updates["status"] = models.DeliveryDead
+			updates["next_attempt_at"] = nil
+			updates["error"] = err.Error()
+		default:
+			updates["status"] = models.DeliveryFailed
+			updates["next_attempt_at"] = now.Add(WebhookBackoff(delivery.Attempts + 1))
+			updates["error"] = err.Error()
+		}
+	}
+	return d.db.WithContext(ctx).Model(&delivery).Updates(updates).Error
+}
+
+// send posts the payload and returns the response status and the start of
+// its body. Anything but a 2xx response is an error.
+func (d *WebhookDispatcher) send(ctx context.Context, webhook models.Webhook, delivery models.WebhookDelivery) (int, string, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader([]byte(delivery.Payload)))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "cms-backend-webhooks/1.0")
+	req.Header.Set(WebhookEventHeader, delivery.Event)
+	req.Header.Set(WebhookDeliveryHeader, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, WebhookSignature(webhook.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseLimit))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, string(body), fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return resp.StatusCode, string(body), nil
+}